@@ -20,15 +20,31 @@ package commands
 import (
 	"bytes"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/cmd/shared/utils"
 	"github.com/jroimartin/gocui"
 )
 
 var (
 	curSearchResultsNotes quicknote.Notes
+
+	// searchCompletions are the texts the search box can be
+	// completed to, Tab uses the first one
+	searchCompletions []string
 )
 
+// Number of notes shown in the related notes panel
+const relatedNotesLimit = 10
+
+// Number of "did you mean" suggestions shown when nothing matches
+const suggestionsLimit = 3
+
+// Number of completions shown in the title of the search box
+const completionsLimit = 5
+
 func init() {
 	curSearchResultsNotes = make(quicknote.Notes, 0, 0)
 }
@@ -63,6 +79,29 @@ func mainLayout(g *gocui.Gui) error {
 }
 
 func searchBoxViewEvent(g *gocui.Gui, v *gocui.View) error {
+	err := withConns(g, searchLockTimeout, func() error {
+		return searchNotes(g, v)
+	})
+
+	rV, vErr := g.View("results_list")
+	if vErr != nil {
+		return vErr
+	}
+	return showLockedError(rV, err)
+}
+
+// showLockedError shows in v that another qnote process holds the
+// data directory, any other error is returned
+func showLockedError(v *gocui.View, err error) error {
+	if _, ok := err.(*utils.LockedError); ok {
+		v.Clear()
+		fmt.Fprintln(v, err.Error())
+		return nil
+	}
+	return err
+}
+
+func searchNotes(g *gocui.Gui, v *gocui.View) error {
 	var query string
 	var err error
 
@@ -74,30 +113,57 @@ func searchBoxViewEvent(g *gocui.Gui, v *gocui.View) error {
 	// There is a null character at the end we must remove
 	query = string(bytes.Trim([]byte(query), "\x00"))
 
+	if err = setSearchCompletions(v, query); err != nil {
+		return err
+	}
+
 	rV, err := g.View("results_list")
 	if err != nil {
 		return err
 	}
 
 	_, sy := rV.Size()
-	ids, _, err := idxConn.SearchNotePhrase(query, workingNotebook, "desc", sy, 0)
+	var opts *quicknote.SearchOptions
+	if workingQuery != nil {
+		opts = &quicknote.SearchOptions{Query: workingQuery}
+	}
+
+	res, err := idxConn.SearchNotePhrase(query, workingNotebook, sy, opts)
 	if err != nil {
 		return err
 	}
 
+	// Try again allowing for typos when nothing matched
+	if res.Total == 0 && len(strings.TrimSpace(query)) > 0 {
+		fuzzyOpts := &quicknote.SearchOptions{Query: workingQuery, Fuzzy: true}
+		if res, err = idxConn.SearchNotePhrase(query, workingNotebook, sy, fuzzyOpts); err != nil {
+			return err
+		}
+	}
+
 	var highestID int64
-	for _, id := range ids {
-		if id > highestID {
-			highestID = id
+	for _, h := range res.Hits {
+		if h.ID > highestID {
+			highestID = h.ID
 		}
 	}
 
 	idLen := len(fmt.Sprintf("%d", highestID))
 
 	rV.Clear()
-	curSearchResultsNotes = make(quicknote.Notes, 0, len(ids))
-	for _, noteID := range ids {
-		n, err := dbConn.GetNoteByID(noteID)
+	if res.Total == 0 && len(strings.TrimSpace(query)) > 0 {
+		suggestions, err := idxConn.SuggestQuery(query, suggestionsLimit)
+		if err != nil {
+			return err
+		}
+		if len(suggestions) > 0 {
+			fmt.Fprintf(rV, "Did you mean: %s\n", strings.Join(suggestions, ", "))
+		}
+	}
+
+	curSearchResultsNotes = make(quicknote.Notes, 0, len(res.Hits))
+	for _, h := range res.Hits {
+		n, err := dbConn.GetNoteByID(h.ID)
 		if err != nil {
 			return err
 		}
@@ -106,15 +172,105 @@ func searchBoxViewEvent(g *gocui.Gui, v *gocui.View) error {
 			curSearchResultsNotes = append(curSearchResultsNotes, n)
 			idStr := fmt.Sprintf(fmt.Sprintf("%%%dd", idLen), n.ID)
 			lines := fmt.Sprintf("\x1b[38;5;50m%s\x1b[0m: %s %s", idStr, n.Book.Name, n.Title)
+			for _, f := range h.Fragments["body"] {
+				if strings.Contains(f, quicknote.HighlightStart) {
+					lines += " - " + highlightMatches(f)
+					break
+				}
+			}
 			fmt.Fprintln(rV, lines)
 		} else {
-			fmt.Fprintln(rV, "Got Null note for", noteID)
+			fmt.Fprintln(rV, "Got Null note for", h.ID)
+		}
+	}
+
+	return nil
+}
+
+// setSearchCompletions completes the last word of the query when it is a
+// tag (#tag or tag:tag), otherwise the titles starting with the query. The
+// completions are shown in the title of the search box.
+func setSearchCompletions(v *gocui.View, query string) error {
+	searchCompletions = nil
+	v.Title = ""
+	if len(strings.TrimSpace(query)) == 0 || strings.HasSuffix(query, " ") {
+		return nil
+	}
+
+	start := strings.LastIndex(query, " ") + 1
+	word := query[start:]
+
+	var labels []string
+	if marker := tagMarker(word); marker != "" {
+		completions, err := idxConn.CompleteTags(word[len(marker):], completionsLimit)
+		if err != nil {
+			return err
+		}
+		for _, c := range completions {
+			searchCompletions = append(searchCompletions, query[:start]+marker+c.Text)
+			labels = append(labels, fmt.Sprintf("%s%s (%d)", marker, c.Text, c.Count))
+		}
+	} else {
+		completions, err := idxConn.CompleteTitles(query, completionsLimit)
+		if err != nil {
+			return err
+		}
+		for _, c := range completions {
+			if !strings.EqualFold(c.Text, query) {
+				searchCompletions = append(searchCompletions, c.Text)
+				labels = append(labels, c.Text)
+			}
 		}
 	}
 
+	if len(labels) > 0 {
+		v.Title = "Tab: " + strings.Join(labels, " | ")
+	}
 	return nil
 }
 
+// tagMarker returns how word is marked as a tag, "" if it is not
+func tagMarker(word string) string {
+	for _, marker := range []string{"#", "tag:"} {
+		if strings.HasPrefix(word, marker) {
+			return marker
+		}
+	}
+	return ""
+}
+
+// completeSearchBox replaces the search box text with the first completion
+func completeSearchBox(g *gocui.Gui, v *gocui.View) error {
+	if len(searchCompletions) == 0 {
+		return nil
+	}
+
+	text := searchCompletions[0]
+	v.Clear()
+	fmt.Fprint(v, text)
+
+	cx := utf8.RuneCountInString(text)
+	if maxX, _ := v.Size(); cx >= maxX {
+		if err := v.SetOrigin(cx-maxX+1, 0); err != nil {
+			return err
+		}
+		cx = maxX - 1
+	}
+	if err := v.SetCursor(cx, 0); err != nil {
+		return err
+	}
+
+	return searchBoxViewEvent(g, v)
+}
+
+// highlightMatches puts the fragment on one line with
+// the matched terms in color
+func highlightMatches(frag string) string {
+	frag = strings.Replace(frag, quicknote.HighlightStart, "\x1b[1;33m", -1)
+	frag = strings.Replace(frag, quicknote.HighlightEnd, "\x1b[0m", -1)
+	return strings.Join(strings.Fields(frag), " ")
+}
+
 func searchBoxKeyUpEvent(g *gocui.Gui, v *gocui.View) error {
 	rV, err := g.View("results_list")
 	if err != nil {
@@ -171,7 +327,8 @@ func displayNote(g *gocui.Gui, v *gocui.View) error {
 	n := curSearchResultsNotes[cy]
 
 	maxX, maxY := g.Size()
-	if nv, err := g.SetView("note_display", -1, -1, maxX, maxY); err != nil {
+	splitX := maxX * 2 / 3
+	if nv, err := g.SetView("note_display", -1, -1, splitX, maxY); err != nil {
 		if err != gocui.ErrUnknownView {
 			return err
 		}
@@ -185,6 +342,43 @@ func displayNote(g *gocui.Gui, v *gocui.View) error {
 			return err
 		}
 	}
+	if rnv, err := g.SetView("related_notes", splitX+1, -1, maxX, maxY); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+
+		rnv.Title = "Related"
+		rnv.Wrap = true
+		err := withConns(g, searchLockTimeout, func() error {
+			return printRelatedNotes(rnv, n)
+		})
+		if err = showLockedError(rnv, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printRelatedNotes writes the notes similar to n to the view
+func printRelatedNotes(v *gocui.View, n *quicknote.Note) error {
+	res, err := idxConn.SimilarNotes(n, relatedNotesLimit)
+	if err != nil {
+		return err
+	}
+
+	notes, err := dbConn.GetNotesByIDs(res.IDs())
+	if err != nil {
+		return err
+	}
+
+	if len(notes) == 0 {
+		fmt.Fprintln(v, "No related notes")
+		return nil
+	}
+
+	for _, rn := range res.SortNotes(notes) {
+		fmt.Fprintf(v, "\x1b[38;5;50m%d\x1b[0m: %s %s\n", rn.ID, rn.Book.Name, rn.Title)
+	}
 	return nil
 }
 
@@ -194,6 +388,9 @@ func delDisplayNote(g *gocui.Gui, v *gocui.View) error {
 	if err := g.DeleteView("note_display"); err != nil {
 		return err
 	}
+	if err := g.DeleteView("related_notes"); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
 	if _, err := g.SetCurrentView("search_box"); err != nil {
 		return err
 	}
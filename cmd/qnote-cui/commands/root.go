@@ -22,9 +22,12 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/anmil/quicknote"
 	"github.com/anmil/quicknote/cmd/shared/config"
+	"github.com/anmil/quicknote/cmd/shared/utils"
+	"github.com/anmil/quicknote/query"
 	"github.com/jroimartin/gocui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -41,6 +44,23 @@ var (
 	dbConn          quicknote.DB
 	idxConn         quicknote.Index
 	workingNotebook *quicknote.Book
+	workingQuery    query.Node
+)
+
+// How long a search waits for a qnote process holding the data directory
+const searchLockTimeout = 2 * time.Second
+
+// How long the connections stay open after the last search, so typing
+// in the search box does not open them again for every key
+const connsIdleTimeout = time.Second
+
+var (
+	// connsLock is the lock on the data directory while
+	// the connections are open, see withConns
+	connsLock *utils.FileLock
+
+	// connsTimer releases the connections once they are idle
+	connsTimer *time.Timer
 )
 
 var (
@@ -84,11 +104,22 @@ func rootCmdRun(cmd *cobra.Command, args []string) {
 // PreseistentPreRunRoot runs before the Root Command and any child
 // commands that do not override it.
 func PreseistentPreRunRoot(cmd *cobra.Command, args []string) {
-	var err error
-	dbConn, err = config.GetDBConn()
+	lock, err := config.LockDataDirectory()
 	exitOnError(err)
-	idxConn, err = config.GetIndexConn()
+	defer lock.Unlock()
+
+	dbConn, err = config.GetDBConn()
 	exitOnError(err)
+	defer closeConns()
+
+	// A saved search limits the results to the notes matching its query
+	if quicknote.IsSavedSearchName(workingNotebookName) {
+		s, err := config.GetWorkingSavedSearch(dbConn, workingNotebookName)
+		exitOnError(err)
+		workingQuery, err = query.Parse(s.Query)
+		exitOnError(err)
+		return
+	}
 
 	workingNotebook, err = config.GetWorkingBook(dbConn, workingNotebookName)
 	exitOnError(err)
@@ -101,8 +132,78 @@ func PreseistentPreRunRoot(cmd *cobra.Command, args []string) {
 // PreseistentPostRunRoot runs after the Root Command and any child
 // commands that do not override it.
 func PreseistentPostRunRoot(cmd *cobra.Command, args []string) {
+	if connsTimer != nil {
+		connsTimer.Stop()
+	}
+	releaseConns()
+}
+
+// withConns runs fn with the database and index open, holding the lock on
+// the data directory. The connections stay open while the search box is
+// edited and are closed, releasing the lock, once they are not used for
+// connsIdleTimeout, so qnote can be used while qnote-cui is open.
+func withConns(g *gocui.Gui, timeout time.Duration, fn func() error) error {
+	if err := openConns(timeout); err != nil {
+		return err
+	}
+
+	// The connections are released in the main loop,
+	// which runs the searches, never during one
+	if connsTimer != nil {
+		connsTimer.Stop()
+	}
+	connsTimer = time.AfterFunc(connsIdleTimeout, func() {
+		g.Update(func(g *gocui.Gui) error {
+			releaseConns()
+			return nil
+		})
+	})
+
+	return fn()
+}
+
+// openConns takes the lock on the data directory and opens the
+// database and index, if they are not open already
+func openConns(timeout time.Duration) error {
+	if connsLock != nil {
+		return nil
+	}
+
+	lock, err := config.LockDataDirectoryWait(timeout)
+	if err != nil {
+		return err
+	}
+	connsLock = lock
+
+	if dbConn, err = config.GetDBConn(); err != nil {
+		releaseConns()
+		return err
+	}
+	if idxConn, err = config.GetIndexConn(); err != nil {
+		releaseConns()
+		return err
+	}
+	return nil
+}
+
+// releaseConns closes the connections and releases the lock
+func releaseConns() {
+	closeConns()
+	if connsLock != nil {
+		connsLock.Unlock()
+		connsLock = nil
+	}
+}
+
+// closeConns closes the database and index connections
+func closeConns() {
+	if idxConn != nil {
+		config.CloseIndexConn(idxConn)
+		idxConn = nil
+	}
 	if dbConn != nil {
 		dbConn.Close()
+		dbConn = nil
 	}
 }
 
@@ -134,6 +235,9 @@ func setKeybindings(g *gocui.Gui) {
 	if err := g.SetKeybinding("search_box", gocui.KeyArrowDown, gocui.ModNone, searchBoxKeyDownEvent); err != nil {
 		log.Panicln(err)
 	}
+	if err := g.SetKeybinding("search_box", gocui.KeyTab, gocui.ModNone, completeSearchBox); err != nil {
+		log.Panicln(err)
+	}
 	if err := g.SetKeybinding("note_display", gocui.KeyEsc, gocui.ModNone, delDisplayNote); err != nil {
 		log.Panicln(err)
 	}
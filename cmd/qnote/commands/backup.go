@@ -0,0 +1,287 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/cmd/shared/backup"
+	"github.com/anmil/quicknote/cmd/shared/config"
+	"github.com/anmil/quicknote/cmd/shared/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	backupDirectory  string
+	backupKeepDaily  int
+	backupKeepWeekly int
+)
+
+// snapshotter is implemented by database providers
+// that can take a consistent snapshot on their own.
+type snapshotter interface {
+	Backup(destPath string) error
+}
+
+func init() {
+	RootCmd.AddCommand(BackupCmd)
+	BackupCmd.AddCommand(BackupRestoreCmd)
+	BackupCmd.AddCommand(BackupListCmd)
+
+	viper.SetDefault("backup_directory", path.Join(config.DataDirectory, "backups"))
+	viper.SetDefault("backup_keep_daily", "7")
+	viper.SetDefault("backup_keep_weekly", "4")
+
+	BackupCmd.PersistentFlags().StringVarP(&backupDirectory, "dir", "", viper.GetString("backup_directory"),
+		"Directory the snapshots are stored in")
+	BackupCmd.Flags().IntVarP(&backupKeepDaily, "keep-daily", "", viper.GetInt("backup_keep_daily"),
+		"Number of days to keep the newest snapshot of")
+	BackupCmd.Flags().IntVarP(&backupKeepWeekly, "keep-weekly", "", viper.GetInt("backup_keep_weekly"),
+		"Number of weeks to keep the newest snapshot of")
+}
+
+// BackupCmd Take a snapshot of all Notes, Books, and Tags
+var BackupCmd = &cobra.Command{
+	Use:   "backup [flags]",
+	Short: "Take a snapshot of all Notes, Books, and Tags",
+	Long: `Take a consistent snapshot of all Notes, Books, and Tags
+
+With the sqlite database provider the snapshot is a copy of the database taken
+with SQLite's online backup API, so it is safe to run while qnote is in use.
+For all other providers the snapshot is a gzip compressed QNOT export (see
+'qnote help export').
+
+Snapshots are written to the backup directory (see '--dir') with a timestamped
+name. After the snapshot is taken, old snapshots are removed using the
+retention rules. The newest snapshot of each day is kept for '--keep-daily'
+days and the newest snapshot of each week for '--keep-weekly' weeks.
+
+Use 'qnote backup restore <file>' to restore a snapshot.`,
+	Run: backupCmdRun,
+}
+
+func backupCmdRun(cmd *cobra.Command, args []string) {
+	dir, err := getBackupDirectory()
+	exitOnError(err)
+
+	fp, err := takeSnapshot(dir)
+	exitOnError(err)
+	fmt.Printf("Snapshot written to %s\n", fp)
+
+	removed, err := backup.Prune(dir, backupKeepDaily, backupKeepWeekly)
+	exitOnError(err)
+
+	for _, s := range removed {
+		fmt.Printf("Removed expired snapshot %s\n", s.Path)
+	}
+}
+
+// BackupListCmd List all snapshots
+var BackupListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List all snapshots in the backup directory",
+	Run:     backupListCmdRun,
+}
+
+func backupListCmdRun(cmd *cobra.Command, args []string) {
+	dir, err := getBackupDirectory()
+	exitOnError(err)
+
+	snapshots, err := backup.ListSnapshots(dir)
+	exitOnError(err)
+
+	for _, s := range snapshots {
+		fmt.Print(utils.FgCyan("Created: "))
+		fmt.Print(s.Created.Format("2006-01-02 03:04:05 PM"))
+		fmt.Print(utils.FgCyan(" File: "))
+		fmt.Println(s.Path)
+	}
+}
+
+// BackupRestoreCmd Restore a snapshot
+var BackupRestoreCmd = &cobra.Command{
+	Use:   "restore [flags] <file>",
+	Short: "Restore all Notes, Books, and Tags from a snapshot",
+	Long: `Restore all Notes, Books, and Tags from a snapshot
+
+All existing Notes and Books are replaced with the ones in the snapshot and
+the index is rebuilt. A snapshot of the current database is taken before
+restoring, in case you need to go back.
+
+SQLite snapshots (.db) can only be restored when the sqlite database provider
+is used. QNOT snapshots (.qnot.gz) can be restored with any provider, Note IDs
+are not preserved in this case (see 'qnote help import').`,
+	Run: backupRestoreCmdRun,
+}
+
+func backupRestoreCmdRun(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		exitValidationError("No snapshot file given", cmd)
+	}
+
+	fp, err := utils.ExpandFilePath(args[0])
+	exitOnError(err)
+
+	_, err = os.Stat(fp)
+	exitOnError(err)
+
+	isSQLite := backup.IsSQLiteSnapshot(fp)
+	if isSQLite && viper.GetString("db_provider") != "sqlite" {
+		exitValidationError("SQLite snapshots can only be restored with the sqlite database provider", cmd)
+	}
+
+	cMsg := "This will replace all Notes, Books, and Tags with the snapshot %s, are you sure?"
+	if !skipConfirm && !utils.AskForConfirmationMust(fmt.Sprintf(cMsg, fp)) {
+		return
+	}
+
+	dir, err := getBackupDirectory()
+	exitOnError(err)
+
+	safety, err := takeSnapshot(dir)
+	exitOnError(err)
+	fmt.Printf("Current database saved to %s\n", safety)
+
+	// The index is rebuilt from the restored database
+	books, err := dbConn.GetAllBooks()
+	exitOnError(err)
+	for _, bk := range books {
+		err = idxConn.DeleteBook(bk)
+		exitOnError(err)
+	}
+
+	var restored int
+	if isSQLite {
+		restored = restoreSQLiteSnapshot(fp)
+	} else {
+		restored = restoreQNOTSnapshot(fp, books)
+	}
+
+	fmt.Printf("Restored %d notes from %s\n", restored, fp)
+}
+
+func restoreSQLiteSnapshot(fp string) int {
+	err := dbConn.Close()
+	exitOnError(err)
+
+	// Copy next to the database first so the
+	// database file is replaced in one step
+	dbPath := config.GetSqliteDBPath()
+	tmpPath := dbPath + ".restore"
+	err = utils.CopyFile(fp, tmpPath)
+	exitOnError(err)
+
+	// The database runs in WAL mode, a WAL file left behind
+	// must never be applied to the restored database
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err = os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			exitOnError(err)
+		}
+	}
+
+	err = os.Rename(tmpPath, dbPath)
+	exitOnError(err)
+
+	dbConn, err = config.GetDBConn()
+	exitOnError(err)
+
+	notes, err := dbConn.GetAllNotes("created", "asc")
+	exitOnError(err)
+
+	err = idxConn.IndexNotes(notes)
+	exitOnError(err)
+
+	return len(notes)
+}
+
+func restoreQNOTSnapshot(fp string, books quicknote.Books) int {
+	file, err := os.Open(fp)
+	exitOnError(err)
+	defer file.Close()
+
+	zip, err := gzip.NewReader(file)
+	exitOnError(err)
+	defer zip.Close()
+
+	for _, bk := range books {
+		err = dbConn.DeleteBook(bk)
+		exitOnError(err)
+	}
+
+	// The database is empty, nothing can be a duplicate
+	skipDupCheck = true
+	preserveModified = true
+
+	return importNotes(zip, false)
+}
+
+// takeSnapshot writes a new snapshot to dir and returns its path. The snapshot
+// is written to a temporary file first so a failed backup never leaves a
+// partial snapshot behind.
+func takeSnapshot(dir string) (string, error) {
+	now := time.Now()
+
+	var fp string
+	var err error
+	if db, ok := dbConn.(snapshotter); ok {
+		fp = path.Join(dir, backup.SnapshotName(now, backup.SQLiteExt))
+		err = db.Backup(fp + ".tmp")
+	} else {
+		fp = path.Join(dir, backup.SnapshotName(now, backup.QNOTExt))
+		err = exportSnapshot(fp + ".tmp")
+	}
+
+	if err != nil {
+		os.Remove(fp + ".tmp")
+		return "", err
+	}
+
+	return fp, os.Rename(fp+".tmp", fp)
+}
+
+func exportSnapshot(fp string) error {
+	notes, err := dbConn.GetAllNotes("created", "asc")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(fp)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fn := strings.TrimSuffix(path.Base(fp), ".gz.tmp")
+	return exportNotes(notes, fn, file, true)
+}
+
+func getBackupDirectory() (string, error) {
+	dir, err := utils.ExpandFilePath(backupDirectory)
+	if err != nil {
+		return "", err
+	}
+	return dir, utils.EnsureDirectoryExists(dir)
+}
@@ -64,6 +64,7 @@ func init() {
 	RootCmd.AddCommand(SplitCmd)
 	RootCmd.AddCommand(ExportCmd)
 	RootCmd.AddCommand(ImportCmd)
+	RootCmd.AddCommand(DBCmd)
 
 	viper.SetDefault("display_order", "asc")
 	viper.SetDefault("order_by", "modified")
@@ -136,3 +137,9 @@ var SplitCmd = &cobra.Command{
 	Use:   "split",
 	Short: "Split Book",
 }
+
+// DBCmd manage the database
+var DBCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the database",
+}
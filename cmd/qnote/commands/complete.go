@@ -0,0 +1,123 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/cmd/shared/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Command line variables
+var (
+	completeLimit  int
+	completeFormat string
+)
+
+var completeFormatOptions = []string{
+	"text",
+	"json",
+}
+
+func init() {
+	RootCmd.AddCommand(CompleteCmd)
+	CompleteCmd.AddCommand(CompleteTagsCmd)
+	CompleteCmd.AddCommand(CompleteTitlesCmd)
+
+	viper.SetDefault("complete_limit", "10")
+
+	CompleteCmd.PersistentFlags().IntVarP(&completeLimit, "limit", "l", viper.GetInt("complete_limit"),
+		"Number of completions to return")
+	CompleteCmd.PersistentFlags().StringVarP(&completeFormat, "format", "f", "text",
+		fmt.Sprintf("Format to display completions in [%s]", strings.Join(completeFormatOptions, ", ")))
+}
+
+// CompleteCmd Complete tags and note titles
+var CompleteCmd = &cobra.Command{
+	Use:   "complete",
+	Short: "Complete tags and note titles for editor integrations",
+	Long: `Prints the tags or note titles from the index that start with a prefix.
+
+The text format prints one completion a line, a tag and the number of notes
+with it or a note ID and its title separated by a tab.`,
+}
+
+// CompleteTagsCmd Complete tags
+var CompleteTagsCmd = &cobra.Command{
+	Use:   "tags [flags] [prefix]",
+	Short: "Tags starting with the prefix, the most used first",
+	Run:   completeTagsCmdRun,
+}
+
+func completeTagsCmdRun(cmd *cobra.Command, args []string) {
+	prefix := completePrefix(cmd, args)
+	completions, err := idxConn.CompleteTags(strings.TrimPrefix(prefix, "#"), completeLimit)
+	exitOnError(err)
+
+	printCompletions(completions, func(c *quicknote.Completion) string {
+		return fmt.Sprintf("%s\t%d", c.Text, c.Count)
+	})
+}
+
+// CompleteTitlesCmd Complete note titles
+var CompleteTitlesCmd = &cobra.Command{
+	Use:   "titles [flags] [prefix]",
+	Short: "Note titles starting with the prefix",
+	Run:   completeTitlesCmdRun,
+}
+
+func completeTitlesCmdRun(cmd *cobra.Command, args []string) {
+	completions, err := idxConn.CompleteTitles(completePrefix(cmd, args), completeLimit)
+	exitOnError(err)
+
+	printCompletions(completions, func(c *quicknote.Completion) string {
+		return fmt.Sprintf("%d\t%s", c.ID, c.Text)
+	})
+}
+
+// completePrefix returns the prefix argument, which can be left out
+func completePrefix(cmd *cobra.Command, args []string) string {
+	if len(args) > 1 {
+		exitValidationError("Only one prefix can be given", cmd)
+	} else if !utils.InSliceString(completeFormat, completeFormatOptions) {
+		exitValidationError("invalid format", cmd)
+	}
+
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+func printCompletions(completions []*quicknote.Completion, line func(c *quicknote.Completion) string) {
+	if completeFormat == "json" {
+		err := json.NewEncoder(os.Stdout).Encode(completions)
+		exitOnError(err)
+		return
+	}
+
+	for _, c := range completions {
+		fmt.Println(line(c))
+	}
+}
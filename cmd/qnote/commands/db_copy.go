@@ -0,0 +1,117 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/db"
+	"github.com/anmil/quicknote/index"
+	"github.com/spf13/cobra"
+)
+
+var (
+	copyToProvider      string
+	copyToOptions       []string
+	copyRebuildIndex    bool
+	copyToIndexProvider string
+	copyToIndexOptions  []string
+)
+
+func init() {
+	DBCmd.AddCommand(DBCopyCmd)
+
+	DBCopyCmd.Flags().StringVarP(&copyToProvider, "to-provider", "", "",
+		"Database provider to copy to [sqlite, postgres]")
+	DBCopyCmd.Flags().StringSliceVarP(&copyToOptions, "to-options", "", []string{},
+		"Options for the database provider, sqlite: <path> postgres: <name>,<host>,<port>,<user>,<pass>,<sslmode>")
+	DBCopyCmd.Flags().BoolVarP(&copyRebuildIndex, "rebuild-index", "", false,
+		"Index all copied notes")
+	DBCopyCmd.Flags().StringVarP(&copyToIndexProvider, "to-index-provider", "", "",
+		"Index provider to rebuild, the configured index is used if not given [bleve, elastic]")
+	DBCopyCmd.Flags().StringSliceVarP(&copyToIndexOptions, "to-index-options", "", []string{},
+		"Options for the index provider, bleve: <directory>,<shards> elastic: <url>,<index name>")
+}
+
+// DBCopyCmd Copy all Books, Tags, and Notes to another database
+var DBCopyCmd = &cobra.Command{
+	Use:   "copy [flags]",
+	Short: "Copy all Books, Tags, and Notes to another database",
+	Long: `Copy all Books, Tags, and Notes from the configured database to another database
+
+Unlike export and import, the copy keeps the IDs and the Created and Modified
+dates of everything copied. The target database must be empty. After the copy
+the number of Books, Tags, and Notes in both databases are compared.
+
+Use '--rebuild-index' to index the copied notes. The configured index is used
+unless '--to-index-provider' is given. Update your config file to use the new
+providers once the copy is finished.
+
+Example:
+
+    qnote db copy --to-provider postgres \
+        --to-options quicknote,localhost,5432,qnote,secret,disable --rebuild-index`,
+	Run: dbCopyCmdRun,
+}
+
+func dbCopyCmdRun(cmd *cobra.Command, args []string) {
+	if copyToProvider == "" {
+		exitValidationError("No target database provider given", cmd)
+	} else if len(copyToOptions) == 0 {
+		exitValidationError("No target database options given", cmd)
+	} else if copyToIndexProvider != "" && !copyRebuildIndex {
+		exitValidationError("--to-index-provider requires --rebuild-index", cmd)
+	} else if copyToIndexProvider != "" && len(copyToIndexOptions) != 2 {
+		exitValidationError("--to-index-options requires two options", cmd)
+	}
+
+	dst, err := db.NewDatabase(copyToProvider, copyToOptions...)
+	exitOnError(err)
+	defer dst.Close()
+
+	err = db.Copy(dbConn, dst, func(bk *quicknote.Book, copied int64) {
+		fmt.Printf("Copied Book %s (%d notes total)\n", bk.Name, copied)
+	})
+	exitOnError(err)
+
+	err = db.Verify(dbConn, dst)
+	exitOnError(err)
+
+	counts, err := db.GetCounts(dst)
+	exitOnError(err)
+	fmt.Printf("Copied %d books, %d tags, and %d notes\n", counts.Books, counts.Tags, counts.Notes)
+
+	if !copyRebuildIndex {
+		return
+	}
+
+	idx := idxConn
+	if copyToIndexProvider != "" {
+		idx, err = index.NewIndex(copyToIndexProvider, copyToIndexOptions...)
+		exitOnError(err)
+	}
+
+	notes, err := dst.GetAllNotes("id", "asc")
+	exitOnError(err)
+
+	err = idx.IndexNotes(notes)
+	exitOnError(err)
+
+	fmt.Printf("Finished indexing notes (%d)\n", len(notes))
+}
@@ -19,7 +19,9 @@ package commands
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/anmil/quicknote"
@@ -28,27 +30,87 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Command line variables
+var (
+	editQuery string
+)
+
 func init() {
 	EditCmd.AddCommand(EditNoteCmd)
 	EditNoteCmd.AddCommand(MoveNotesIDsCmd)
+
+	EditNoteCmd.Flags().StringVarP(&editQuery, "query", "q", "", "Edit all Notes matching the query")
 }
 
 // EditNoteCmd Edit note
 var EditNoteCmd = &cobra.Command{
-	Use:   "note <note id>",
+	Use:   "note <note id...>",
 	Short: "Edit note",
-	Long:  `Opens an editor (default vim) to allow you to edit a Note`,
-	Run:   editNoteCmdRun,
+	Long: `Opens an editor (default vim) to allow you to edit a Note
+
+The header at the top of the editor sets the note's Book, type, extra tags
+and due date. Changing the Book moves the note.
+
+Given more than one note id, or --query, all of the notes are opened in one
+editor. Each note starts with a line holding its id and Book, change the Book
+to move the note. When the editor is closed the notes that changed are saved
+together.`,
+	Run: editNoteCmdRun,
 }
 
 func editNoteCmdRun(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
+	if editQuery != "" {
+		if len(args) != 0 {
+			exitValidationError("Note IDs can not be used with --query", cmd)
+		}
+
+		notes, err := getQueryNotes(editQuery, "id", "asc")
+		if err != nil {
+			exitValidationError(err.Error(), cmd)
+		}
+		if len(notes) == 0 {
+			fmt.Println("There were no notes that matched you query")
+			return
+		}
+
+		editNotes(notes)
+		return
+	}
+
+	if len(args) == 0 {
 		exitValidationError("No Note ID given", cmd)
 	}
 
-	noteID, err := strconv.ParseInt(args[0], 10, 64)
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		noteID, err := strconv.ParseInt(arg, 10, 64)
+		exitOnError(err)
+		ids = append(ids, noteID)
+	}
+
+	if len(ids) == 1 {
+		editNote(ids[0])
+		return
+	}
+
+	notes, err := dbConn.GetNotesByIDs(ids)
 	exitOnError(err)
 
+	found := make(map[int64]bool)
+	for _, n := range notes {
+		found[n.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			fmt.Printf("Note %d does not exists\n", id)
+			return
+		}
+	}
+
+	editNotes(notes)
+}
+
+func editNote(noteID int64) {
 	oldNote, err := dbConn.GetNoteByID(noteID)
 	exitOnError(err)
 
@@ -57,34 +119,41 @@ func editNoteCmdRun(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	editor, err := utils.NewEditor()
-	exitOnError(err)
-	defer editor.Close()
-
-	editor.SetText(fmt.Sprintf("%s\n%s", oldNote.Title, oldNote.Body))
-	err = editor.Open()
-	exitOnError(err)
+	p := getParser(oldNote.Book.Name, oldNote.Type)
+	text := p.Text(oldNote.Title, oldNote.Body)
 
-	p, err := parser.NewParser(oldNote.Type)
-	exitOnError(err)
-	p.Parse(editor.Text())
+	h := &utils.NoteHeader{
+		Book: oldNote.Book.Name,
+		Type: oldNote.Type,
+		Tags: getExtraTags(oldNote, p),
+		Due:  oldNote.Due,
+	}
+	h, noteText := openNoteEditor(h, text)
+	if len(noteText) == 0 {
+		fmt.Println("No text entered.. aborting")
+		return
+	}
 
-	tags := make(quicknote.Tags, 0, len(p.Tags()))
-	for _, t := range p.Tags() {
-		tag, err := dbConn.GetOrCreateTagByName(t)
+	bk := oldNote.Book
+	if h.Book != bk.Name {
+		bk, err = dbConn.GetOrCreateBookByName(h.Book)
 		exitOnError(err)
-		tags = append(tags, tag)
 	}
 
+	p = getParser(bk.Name, h.Type)
+	p.Parse(noteText)
+
 	newNote := &quicknote.Note{
 		ID:       oldNote.ID,
 		Created:  oldNote.Created,
 		Modified: time.Now(),
-		Book:     oldNote.Book,
-		Type:     oldNote.Type,
+		Book:     bk,
+		Type:     h.Type,
 		Title:    p.Title(),
 		Body:     p.Body(),
-		Tags:     tags,
+		Tags:     getOrCreateTags(getNoteTags(p, h.Tags)),
+		People:   getMentions(p),
+		Due:      h.Due,
 	}
 
 	err = dbConn.EditNote(newNote)
@@ -96,6 +165,168 @@ func editNoteCmdRun(cmd *cobra.Command, args []string) {
 	utils.PrintNoteColored(newNote, false)
 }
 
+// editNotes opens all of the notes in one editor and saves
+// the notes that were changed
+func editNotes(notes quicknote.Notes) {
+	byID := make(map[int64]*quicknote.Note)
+	texts := make(map[int64]string)
+	sections := make([]*utils.NoteSection, 0, len(notes))
+	for _, n := range notes {
+		p := getParser(n.Book.Name, n.Type)
+		s := &utils.NoteSection{ID: n.ID, Book: n.Book.Name, Text: p.Text(n.Title, n.Body)}
+		sections = append(sections, s)
+		byID[n.ID] = n
+		texts[n.ID] = strings.TrimRight(s.Text, "\n")
+	}
+
+	editor, err := utils.NewEditor()
+	exitOnError(err)
+	defer editor.Close()
+
+	editor.SetText(utils.FormatNoteSections(sections))
+	err = editor.Open()
+	exitOnError(err)
+
+	edited, err := utils.ParseNoteSections(editor.Text())
+	exitOnError(err)
+
+	// Check all of the sections before changing anything
+	changed := make(quicknote.Notes, 0)
+	changedTags := make([][]string, 0)
+	changedBooks := make([]string, 0)
+	for _, s := range edited {
+		oldNote, ok := byID[s.ID]
+		if !ok {
+			exitOnError(fmt.Errorf("Note %d was not opened for editing", s.ID))
+		}
+		if s.Book == oldNote.Book.Name && s.Text == texts[s.ID] {
+			continue
+		}
+		if strings.TrimSpace(s.Book) == "" {
+			exitOnError(fmt.Errorf("Note %d has no Book", s.ID))
+		}
+
+		// The extra tags are found with the parser of the old Book,
+		// the text is parsed with the parser of the Book it is in
+		extra := getExtraTags(oldNote, getParser(oldNote.Book.Name, oldNote.Type))
+		p := getParser(s.Book, oldNote.Type)
+		p.Parse(s.Text)
+		if p.Title() == "" {
+			exitOnError(fmt.Errorf("Note %d has no title", s.ID))
+		}
+
+		oldTags := oldNote.GetTagStringArray()
+		newTags := getNoteTags(p, extra)
+		sort.Strings(oldTags)
+		sort.Strings(newTags)
+
+		if s.Book == oldNote.Book.Name && p.Title() == oldNote.Title &&
+			p.Body() == oldNote.Body && sameSortedStrings(oldTags, newTags) {
+			continue
+		}
+
+		changed = append(changed, &quicknote.Note{
+			ID:       oldNote.ID,
+			Created:  oldNote.Created,
+			Modified: time.Now(),
+			Book:     oldNote.Book,
+			Type:     oldNote.Type,
+			Title:    p.Title(),
+			Body:     p.Body(),
+			People:   getMentions(p),
+			Due:      oldNote.Due,
+		})
+		changedTags = append(changedTags, newTags)
+		changedBooks = append(changedBooks, s.Book)
+	}
+
+	if len(changed) == 0 {
+		fmt.Println("No notes changed")
+		return
+	}
+
+	// New Books and tags are created by EditNotes in the
+	// same transaction as the notes
+	books := make(map[string]*quicknote.Book)
+	tags := make(map[string]*quicknote.Tag)
+	ids := make([]string, 0, len(changed))
+	for i, n := range changed {
+		if changedBooks[i] != n.Book.Name {
+			n.Book = getBookForEdit(changedBooks[i], books)
+		}
+		n.Tags = getTagsForEdit(changedTags[i], tags)
+		ids = append(ids, strconv.FormatInt(n.ID, 10))
+	}
+
+	err = dbConn.EditNotes(changed)
+	exitOnError(err)
+
+	err = idxConn.IndexNotes(changed)
+	exitOnError(err)
+
+	fmt.Printf("Updated %d notes: %s\n", len(changed), strings.Join(ids, ", "))
+}
+
+// getExtraTags returns the tags of the note that are not in its
+// text, added in the editor header. p is parsed with the note's text.
+func getExtraTags(n *quicknote.Note, p parser.Parser) []string {
+	return parser.ExtraTags(p, p.Text(n.Title, n.Body), n.GetTagStringArray())
+}
+
+// getBookForEdit returns the Book with the name, or a new Book without
+// an ID if it does not exist. books holds the Books already returned.
+func getBookForEdit(name string, books map[string]*quicknote.Book) *quicknote.Book {
+	if bk, ok := books[name]; ok {
+		return bk
+	}
+
+	bk, err := dbConn.GetBookByName(name)
+	exitOnError(err)
+	if bk == nil {
+		bk = quicknote.NewBook()
+		bk.Created = time.Now()
+		bk.Modified = time.Now()
+		bk.Name = name
+	}
+	books[name] = bk
+	return bk
+}
+
+// getTagsForEdit returns the Tags with the names, with new Tags without
+// an ID for the ones that do not exist. tags holds the Tags already returned.
+func getTagsForEdit(names []string, tags map[string]*quicknote.Tag) quicknote.Tags {
+	noteTags := make(quicknote.Tags, 0, len(names))
+	for _, name := range names {
+		t, ok := tags[name]
+		if !ok {
+			var err error
+			t, err = dbConn.GetTagByName(name)
+			exitOnError(err)
+			if t == nil {
+				t = quicknote.NewTag()
+				t.Created = time.Now()
+				t.Modified = time.Now()
+				t.Name = name
+			}
+			tags[name] = t
+		}
+		noteTags = append(noteTags, t)
+	}
+	return noteTags
+}
+
+// getOrCreateTags returns the Tags with the names, creating
+// the ones that do not exist
+func getOrCreateTags(names []string) quicknote.Tags {
+	tags := make(quicknote.Tags, 0, len(names))
+	for _, t := range names {
+		tag, err := dbConn.GetOrCreateTagByName(t)
+		exitOnError(err)
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
 // MoveNotesIDsCmd See SplitBookIDsCmd
 var MoveNotesIDsCmd = &cobra.Command{
 	Use:   "move [flags] <book_name> <note_id...>",
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/cmd/shared/config"
 	"github.com/anmil/quicknote/cmd/shared/encoding"
 	"github.com/anmil/quicknote/cmd/shared/utils"
 
@@ -82,6 +83,7 @@ var ExportBookCmd = &cobra.Command{
 	Use:   "book [flags] <book>...",
 	Short: "Export all Notes, Tags in book(s)",
 	Long: `Export all Notes, Tags in book(s) using the QNOT file format.
+Saved searches can be given as books by starting their name with '@'.
 
 See the help documentation for the export command for details
 
@@ -97,7 +99,15 @@ func exportBookCmdRun(cmd *cobra.Command, args []string) {
 	}
 
 	var books quicknote.Books
+	var searches quicknote.SavedSearches
 	for _, bkName := range args {
+		if quicknote.IsSavedSearchName(bkName) {
+			s, err := config.GetWorkingSavedSearch(dbConn, bkName)
+			exitOnError(err)
+			searches = append(searches, s)
+			continue
+		}
+
 		bk, err := dbConn.GetBookByName(bkName)
 		exitOnError(err)
 		if bk == nil {
@@ -120,6 +130,11 @@ func exportBookCmdRun(cmd *cobra.Command, args []string) {
 		exitOnError(err)
 		notes = append(notes, ns...)
 	}
+	for _, s := range searches {
+		ns, err := getSavedSearchNotes(s, "created", "asc")
+		exitOnError(err)
+		notes = append(notes, ns...)
+	}
 
 	err = exportNotes(notes, fn, out, compressOutput)
 	exitOnError(err)
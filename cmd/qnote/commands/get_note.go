@@ -18,18 +18,29 @@
 package commands
 
 import (
+	"errors"
 	"strconv"
+	"strings"
 
+	"github.com/anmil/quicknote"
 	"github.com/anmil/quicknote/cmd/shared/utils"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+// Command line variables
+var (
+	getMention string
+)
+
 func init() {
 	GetCmd.AddCommand(GetNoteCmd)
 	GetNoteCmd.AddCommand(GetNoteAllCmd)
 
+	GetNoteCmd.PersistentFlags().StringVarP(&getMention, "mention", "m", "",
+		"Only Notes mentioning the person (@name)")
+
 	viper.SetDefault("titles_only", "false")
 }
 
@@ -69,7 +80,15 @@ func getNoteCmdRun(cmd *cobra.Command, args []string) {
 }
 
 func getAllBookNotes() {
-	notes, err := dbConn.GetAllBookNotes(workingNotebook, sortBy, displayOrder)
+	var notes quicknote.Notes
+	var err error
+	if getMention != "" {
+		notes, err = getMentionNotes(workingNotebook)
+	} else if workingSearch != nil {
+		notes, err = getSavedSearchNotes(workingSearch, sortBy, displayOrder)
+	} else {
+		notes, err = dbConn.GetAllBookNotes(workingNotebook, sortBy, displayOrder)
+	}
 	exitOnError(err)
 	err = utils.PrintNotes(notes, displayFormat)
 	exitOnError(err)
@@ -81,12 +100,21 @@ var GetNoteAllCmd = &cobra.Command{
 	Short: "List all Notes for all Books",
 	Long: `List all notes in all Books
 
-This is the same as 'gnote ls notes' except it returns all Notes in all Books`,
+This is the same as 'gnote ls notes' except it returns all Notes in all Books.
+When '-n' is a saved search (-n @name), all Notes matching it are returned.`,
 	Run: getNoteAllCmdRun,
 }
 
 func getNoteAllCmdRun(cmd *cobra.Command, args []string) {
-	notes, err := dbConn.GetAllNotes(sortBy, displayOrder)
+	var notes quicknote.Notes
+	var err error
+	if getMention != "" {
+		notes, err = getMentionNotes(nil)
+	} else if workingSearch != nil {
+		notes, err = getSavedSearchNotes(workingSearch, sortBy, displayOrder)
+	} else {
+		notes, err = dbConn.GetAllNotes(sortBy, displayOrder)
+	}
 	exitOnError(err)
 
 	if displayFormat == "short" && displayTextOneResult && len(notes) == 1 {
@@ -96,3 +124,13 @@ func getNoteAllCmdRun(cmd *cobra.Command, args []string) {
 	err = utils.PrintNotes(notes, displayFormat)
 	exitOnError(err)
 }
+
+// getMentionNotes returns the notes in the Book bk, or all Books
+// if bk is nil, mentioning the person given with --mention
+func getMentionNotes(bk *quicknote.Book) (quicknote.Notes, error) {
+	if workingSearch != nil {
+		return nil, errors.New("--mention can not be used with a saved search")
+	}
+	name := strings.ToLower(strings.TrimPrefix(getMention, "@"))
+	return dbConn.GetAllMentionNotes(bk, name, sortBy, displayOrder)
+}
@@ -0,0 +1,63 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"github.com/anmil/quicknote/cmd/shared/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	GetCmd.AddCommand(GetPeopleCmd)
+	GetPeopleCmd.AddCommand(GetPeopleAllCmd)
+}
+
+// GetPeopleCmd lists the people mentioned in the working Book
+var GetPeopleCmd = &cobra.Command{
+	Use:     "people",
+	Aliases: []string{"person", "mentions"},
+	Short:   "lists the people mentioned in the working Book",
+	Long: `Lists the people mentioned as @name in the notes of the working Book, with
+the number of notes mentioning them, the most mentioned first.
+
+List the notes mentioning a person with 'qnote get note --mention <name>'.`,
+	Run: getPeopleCmdRun,
+}
+
+func getPeopleCmdRun(cmd *cobra.Command, args []string) {
+	people, err := dbConn.GetPeople(workingNotebook)
+	exitOnError(err)
+
+	err = utils.PrintCountStats(people, displayFormat)
+	exitOnError(err)
+}
+
+// GetPeopleAllCmd lists the people mentioned in all Books
+var GetPeopleAllCmd = &cobra.Command{
+	Use:   "all",
+	Short: "lists the people mentioned in all Books",
+	Run:   getPeopleAllCmdRun,
+}
+
+func getPeopleAllCmdRun(cmd *cobra.Command, args []string) {
+	people, err := dbConn.GetPeople(nil)
+	exitOnError(err)
+
+	err = utils.PrintCountStats(people, displayFormat)
+	exitOnError(err)
+}
@@ -101,13 +101,24 @@ func importCmdRun(cmd *cobra.Command, args []string) {
 		in = zip
 	}
 
+	importNotes(in, true)
+}
+
+// importNotes saves all the notes from the QNOT stream in to the database
+// and index, returning the number of notes saved. If verbose is false only
+// skipped duplicates are printed.
+func importNotes(in io.Reader, verbose bool) int {
+	var saved int
+
 	r := bufio.NewReader(in)
 	dec := encoding.NewBinaryDecoder(r)
 	err := dec.ParseHeader()
 	exitOnError(err)
 
-	fmt.Println("Version:", dec.Header.Version)
-	fmt.Println("Created:", dec.Header.Created)
+	if verbose {
+		fmt.Println("Version:", dec.Header.Version)
+		fmt.Println("Created:", dec.Header.Created)
+	}
 
 	notes, err := dec.ParseNotes()
 	exitOnError(err)
@@ -178,9 +189,14 @@ func importCmdRun(cmd *cobra.Command, args []string) {
 
 		err = saveNote(n)
 		exitOnError(err)
+		saved++
 
-		fmt.Print("Saved Note: ")
-		utils.PrintNoteColored(n, true)
+		if verbose {
+			fmt.Print("Saved Note: ")
+			utils.PrintNoteColored(n, true)
+		}
 	}
 	exitOnError(dec.Err)
+
+	return saved
 }
@@ -29,6 +29,7 @@ import (
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/cmd/shared/config"
 	"github.com/anmil/quicknote/cmd/shared/utils"
 	"github.com/anmil/quicknote/parser"
 	"github.com/spf13/cobra"
@@ -38,17 +39,20 @@ var (
 	noteType string
 )
 
-var editorDocMessage = `%s
+var editorDocMessage = `
 
-# Please enter the text for the note you wish to create. Empty notes
-# aborts the creation. All lines below this message are ignored.
+# Please enter the text for the note below the '--' line. Empty notes
+# abort the change. All lines below this message are ignored.
 #
-# First line is used as the title. Any word that starts with '#' is
-# considered a tag
+# The header above the '--' line sets the note's book, type, extra tags
+# and due date (YYYY-MM-DD). Changing the book moves the note. Errors
+# in the header are shown here and the editor is opened again.
 #
-# This note will be saved with the following values:
-#      Notebook: %s
-#          Type: %s
+# With the basic parser the first line is used as the title. Any word
+# that starts with '#' is considered a tag. The markdown parser uses
+# the first heading as the title and reads YAML front matter.
+#
+# Parser: %s
 #`
 
 func init() {
@@ -69,7 +73,11 @@ var NewNoteCmd = &cobra.Command{
 Opens an editor (default vim) to allow you to enter a new Note. The Note text
 is parsed using the first line as the Note's title. All other lines are used
 as the Note's body. Any word starting with '#' character is parsed as a Tag.
-Tags can be in either the title or the body.`,
+Tags can be in either the title or the body.
+
+Books and note types can use the markdown parser instead, see "parser",
+"book_parsers" and "type_parsers" in the config file. It uses the first
+heading as the title, ignores tags in code and reads YAML front matter.`,
 	Run: newNoteCmdRun,
 }
 
@@ -80,8 +88,7 @@ func newNoteCmdRun(cmd *cobra.Command, args []string) {
 	case quicknote.URL:
 		newURLNoteCmdRun(cmd, args)
 	default:
-		editorText := fmt.Sprintf(editorDocMessage, "", workingNotebook.Name, quicknote.Basic)
-		createNewNote(editorText, quicknote.Basic)
+		createNewNote("", quicknote.Basic)
 	}
 }
 
@@ -115,8 +122,7 @@ func newURLNoteCmdRun(cmd *cobra.Command, args []string) {
 
 	url := args[0]
 	text := getURLMetaNote(url)
-	editorText := fmt.Sprintf(editorDocMessage, text, workingNotebook.Name, quicknote.URL)
-	createNewNote(editorText, quicknote.URL)
+	createNewNote(text, quicknote.URL)
 }
 
 func getURLMetaNote(url string) string {
@@ -179,52 +185,134 @@ func parseTagsFromKeywords(keywords string) []string {
 }
 
 func createNewNote(text string, typ string) {
-	editor, err := utils.NewEditor()
-	exitOnError(err)
-	defer editor.Close()
-
-	if len(text) > 0 {
-		editor.SetText(text)
-	}
-
-	err = editor.Open()
-	exitOnError(err)
-
-	noteText := editor.Text()
-	noteText = removeBottomComment(noteText)
-	noteText = strings.TrimSpace(noteText)
+	h := &utils.NoteHeader{Book: workingNotebook.Name, Type: typ}
+	h, noteText := openNoteEditor(h, text)
 	if len(noteText) == 0 {
 		fmt.Println("No text entered.. aborting")
 		return
 	}
 
-	p, err := parser.NewParser(quicknote.Basic)
-	exitOnError(err)
-	p.Parse(noteText)
-
-	tags := make(quicknote.Tags, 0, len(p.Tags()))
-	for _, t := range p.Tags() {
-		tag, err := dbConn.GetOrCreateTagByName(t)
+	bk := workingNotebook
+	if h.Book != bk.Name {
+		var err error
+		bk, err = dbConn.GetOrCreateBookByName(h.Book)
 		exitOnError(err)
-		tags = append(tags, tag)
 	}
 
+	p := getParser(bk.Name, h.Type)
+	p.Parse(noteText)
+
 	n := &quicknote.Note{
 		Created:  time.Now(),
 		Modified: time.Now(),
-		Book:     workingNotebook,
-		Type:     typ,
+		Book:     bk,
+		Type:     h.Type,
 		Title:    p.Title(),
 		Body:     p.Body(),
-		Tags:     tags,
+		Tags:     getOrCreateTags(getNoteTags(p, h.Tags)),
+		People:   getMentions(p),
+		Due:      h.Due,
 	}
 
-	err = saveNote(n)
+	err := saveNote(n)
 	exitOnError(err)
 
 	utils.PrintNoteColored(n, false)
 }
 
+// openNoteEditor opens the editor with the header h above the text. If
+// the header is not valid the editor is opened again showing the error.
+// It returns the new header and the text without the bottom comment.
+func openNoteEditor(h *utils.NoteHeader, text string) (*utils.NoteHeader, string) {
+	buffer := utils.FormatNoteHeader(h, text) +
+		fmt.Sprintf(editorDocMessage, getParserName(h.Book, h.Type))
+
+	var prevErr error
+	for {
+		editor, err := utils.NewEditor()
+		exitOnError(err)
+
+		editor.SetText(buffer)
+		err = editor.Open()
+		edited := editor.Text()
+		editor.Close()
+		exitOnError(err)
+
+		if len(strings.TrimSpace(edited)) == 0 {
+			return h, ""
+		}
+
+		nh, noteText, err := utils.ParseNoteHeader(edited)
+		if err == nil {
+			return nh, strings.TrimSpace(removeBottomComment(noteText))
+		}
+
+		// Closing the editor without fixing the error gives up
+		if prevErr != nil && edited == buffer {
+			exitOnError(err)
+		}
+		prevErr = err
+
+		buffer = removeErrorComment(edited) + "\n" + editorErrorPrefix + err.Error() + "\n"
+	}
+}
+
+const editorErrorPrefix = "# Error: "
+
+// removeErrorComment removes the error lines added by openNoteEditor
+func removeErrorComment(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !strings.HasPrefix(line, editorErrorPrefix) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// getNoteTags returns the tags the parser found with the extra tags
+// of the header, following the tag rules in the config
+func getNoteTags(p parser.Parser, extra []string) []string {
+	s, err := config.GetParserSelector()
+	exitOnError(err)
+	rules := s.Rules
+	if rules == nil {
+		rules = parser.DefaultTagRules()
+	}
+
+	tags := append([]string(nil), p.Tags()...)
+	for _, t := range extra {
+		if tag := rules.Tag(t); tag != "" && !utils.InSliceString(tag, tags) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// getParser returns the config parser for a note in the Book book of type typ
+func getParser(book, typ string) parser.Parser {
+	s, err := config.GetParserSelector()
+	exitOnError(err)
+	p, err := s.Parser(book, typ)
+	exitOnError(err)
+	return p
+}
+
+// getMentions returns the people mentioned in the text p parsed
+func getMentions(p parser.Parser) []string {
+	if e, ok := p.(parser.Extractor); ok && e.Extracted() != nil {
+		return e.Extracted().Mentions
+	}
+	return nil
+}
+
+func getParserName(book, typ string) string {
+	s, err := config.GetParserSelector()
+	exitOnError(err)
+	return s.Name(book, typ)
+}
+
 func removeBottomComment(text string) string {
 	text = strings.TrimRight(text, "\n")
 	lines := strings.Split(text, "\n")
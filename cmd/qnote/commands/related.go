@@ -0,0 +1,84 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anmil/quicknote/cmd/shared/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var relatedLimit int
+
+func init() {
+	RootCmd.AddCommand(RelatedCmd)
+
+	viper.SetDefault("related_limit", "10")
+
+	RelatedCmd.Flags().IntVarP(&relatedLimit, "limit", "l", viper.GetInt("related_limit"),
+		"Number of related notes to return")
+	RelatedCmd.Flags().StringVarP(&displayFormat, "display-format", "f", viper.GetString("display_format"),
+		fmt.Sprintf("Format to display notes in [%s]", strings.Join(displayFormatOptions, ", ")))
+}
+
+// RelatedCmd Show notes related to a note
+var RelatedCmd = &cobra.Command{
+	Use:   "related [flags] <note id>",
+	Short: "Show notes related to a note",
+	Long: `Shows the notes most similar to the given Note from all Books.
+
+Notes are related when they share the less common words and tags of the
+given Note. The notes must be indexed (see 'qnote search reindex').`,
+	Run: relatedCmdRun,
+}
+
+func relatedCmdRun(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		exitValidationError("No Note ID given", cmd)
+	}
+
+	noteID, err := strconv.ParseInt(args[0], 10, 64)
+	exitOnError(err)
+
+	n, err := dbConn.GetNoteByID(noteID)
+	exitOnError(err)
+
+	if n == nil {
+		fmt.Println("Note does not exists")
+		return
+	}
+
+	res, err := idxConn.SimilarNotes(n, relatedLimit)
+	exitOnError(err)
+
+	notes, err := dbConn.GetNotesByIDs(res.IDs())
+	exitOnError(err)
+	notes = res.SortNotes(notes)
+
+	if len(notes) == 0 {
+		fmt.Println("No related notes found")
+		return
+	}
+
+	err = utils.PrintSearchResults(notes, res, displayFormat)
+	exitOnError(err)
+}
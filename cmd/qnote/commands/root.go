@@ -25,6 +25,7 @@ import (
 
 	"github.com/anmil/quicknote"
 	"github.com/anmil/quicknote/cmd/shared/config"
+	"github.com/anmil/quicknote/cmd/shared/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -40,6 +41,8 @@ var (
 	dbConn          quicknote.DB
 	idxConn         quicknote.Index
 	workingNotebook *quicknote.Book
+	workingSearch   *quicknote.SavedSearch
+	dataDirLock     *utils.FileLock
 )
 
 func init() {
@@ -68,11 +71,27 @@ Searching is provided by Bleve by default, or Elasticsearch with some extra setu
 // commands that do not override it.
 func PreseistentPreRunRoot(cmd *cobra.Command, args []string) {
 	var err error
+	dataDirLock, err = config.LockDataDirectory()
+	exitOnError(err)
+
 	dbConn, err = config.GetDBConn()
 	exitOnError(err)
 	idxConn, err = config.GetIndexConn()
 	exitOnError(err)
 
+	if oi, ok := idxConn.(quicknote.OutdatedIndex); ok && oi.Outdated() && cmd != SearchReindexCmd {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", quicknote.ErrIndexOutdated)
+	}
+
+	if quicknote.IsSavedSearchName(workingNotebookName) {
+		if !acceptsSavedSearch(cmd) {
+			exitValidationError("a saved search can not be used as the working Book for this command", cmd)
+		}
+		workingSearch, err = config.GetWorkingSavedSearch(dbConn, workingNotebookName)
+		exitOnError(err)
+		return
+	}
+
 	workingNotebook, err = config.GetWorkingBook(dbConn, workingNotebookName)
 	exitOnError(err)
 
@@ -87,6 +106,9 @@ func PreseistentPostRunRoot(cmd *cobra.Command, args []string) {
 	if dbConn != nil {
 		dbConn.Close()
 	}
+	if dataDirLock != nil {
+		dataDirLock.Unlock()
+	}
 }
 
 func exitOnError(err error) {
@@ -0,0 +1,164 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/query"
+	"github.com/spf13/cobra"
+)
+
+// Number of IDs fetched from the index at a time when
+// getting the notes of a saved search
+const savedSearchPageSize = 1000
+
+func init() {
+	SearchCmd.AddCommand(SearchSaveCmd)
+	GetCmd.AddCommand(GetSavedSearchCmd)
+	DeleteCmd.AddCommand(DeleteSavedSearchCmd)
+}
+
+// SearchSaveCmd saves a query as a saved search
+var SearchSaveCmd = &cobra.Command{
+	Use:   "save <name> <query>",
+	Short: "Save a query as a saved search",
+	Long: `Saves the query (see 'qnote help search' for the syntax) as a saved search.
+Saving a query with the name of an existing saved search replaces its query.
+
+A saved search can be used in place of a Book by starting its name with '@'
+
+	qnote search save todo "tag:todo book:Work"
+	qnote get notes -n @todo
+	qnote export book @todo
+`,
+	Run: searchSaveCmdRun,
+}
+
+func searchSaveCmdRun(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		exitValidationError("invalid arguments given", cmd)
+	}
+
+	name := quicknote.SavedSearchName(args[0])
+	if len(name) == 0 || strings.ContainsAny(name, " \t\r\n") {
+		exitValidationError("invalid saved search name", cmd)
+	}
+
+	if _, err := query.Parse(args[1]); err != nil {
+		exitValidationError(err.Error(), cmd)
+	}
+
+	s := &quicknote.SavedSearch{Name: name, Query: args[1]}
+	err := dbConn.SaveSearch(s)
+	exitOnError(err)
+
+	fmt.Printf("Saved search %s%s\n", quicknote.SavedSearchPrefix, s.Name)
+}
+
+// GetSavedSearchCmd lists all saved searches
+var GetSavedSearchCmd = &cobra.Command{
+	Use:     "search",
+	Aliases: []string{"searches"},
+	Short:   "List all saved searches",
+	Run:     getSavedSearchCmdRun,
+}
+
+func getSavedSearchCmdRun(cmd *cobra.Command, args []string) {
+	searches, err := dbConn.GetAllSavedSearches()
+	exitOnError(err)
+
+	for _, s := range searches {
+		fmt.Printf("%s%s: %s\n", quicknote.SavedSearchPrefix, s.Name, s.Query)
+	}
+}
+
+// DeleteSavedSearchCmd deletes a saved search
+var DeleteSavedSearchCmd = &cobra.Command{
+	Use:   "search <name>",
+	Short: "Delete a saved search",
+	Long:  `Deletes the saved search. The notes it matched are not changed.`,
+	Run:   deleteSavedSearchCmdRun,
+}
+
+func deleteSavedSearchCmdRun(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		exitValidationError("invalid arguments given", cmd)
+	}
+
+	s, err := dbConn.GetSavedSearchByName(quicknote.SavedSearchName(args[0]))
+	exitOnError(err)
+	if s == nil {
+		fmt.Printf("Saved search %s does not exists\n", args[0])
+		return
+	}
+
+	err = dbConn.DeleteSavedSearch(s)
+	exitOnError(err)
+}
+
+// acceptsSavedSearch returns true if cmd can use
+// a saved search as the working Book (-n @name)
+func acceptsSavedSearch(cmd *cobra.Command) bool {
+	for _, c := range []*cobra.Command{GetNoteCmd, GetNoteAllCmd, SearchCmd, ExportBookCmd} {
+		if c == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+// getSavedSearchNotes returns the notes matching the saved search's
+// query in the index, sorted like the notes of a Book
+func getSavedSearchNotes(s *quicknote.SavedSearch, sortBy, order string) (quicknote.Notes, error) {
+	return getQueryNotes(s.Query, sortBy, order)
+}
+
+// getQueryNotes returns all of the notes matching the query
+// in the index, sorted like the notes of a Book
+func getQueryNotes(qs string, sortBy, order string) (quicknote.Notes, error) {
+	q, err := query.Parse(qs)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0)
+	opts := &quicknote.SearchOptions{Sort: []*quicknote.SortField{{Field: quicknote.SortID}}}
+	for {
+		res, err := idxConn.SearchNote(q, savedSearchPageSize, opts)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, res.IDs()...)
+
+		if res.Next == "" {
+			break
+		}
+		opts.After = res.Next
+	}
+
+	notes, err := dbConn.GetNotesByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	quicknote.SortNotes(notes, sortBy, order)
+	return notes, nil
+}
@@ -19,39 +19,89 @@ package commands
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/cmd/shared/config"
 	"github.com/anmil/quicknote/cmd/shared/utils"
+	"github.com/anmil/quicknote/query"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 // Command line variables
 var (
-	resultsLimit     int
-	resultsOffset    int
-	queryStringQuery bool
+	resultsLimit       int
+	resultsAfter       string
+	searchSortBy       string
+	searchDisplayOrder string
+	queryStringQuery   bool
+	reindexIncremental bool
+	reindexBookName    string
+	searchFacets       string
+	searchFacetSize    int
+	facetFilters       []string
+	fuzzySearch        bool
+	explainSearch      bool
+	compareSearch      bool
 )
 
+// Number of "did you mean" suggestions shown when nothing matches
+const suggestionsLimit = 3
+
+// Number of notes sent to the index at a time when re-indexing
+const reindexBatchSize = 500
+
 func init() {
 	RootCmd.AddCommand(SearchCmd)
 	SearchCmd.AddCommand(SearchReindexCmd)
+	SearchCmd.AddCommand(SearchReshardCmd)
 
 	viper.SetDefault("search_results_limit", "15")
 	viper.SetDefault("raw_query", "false")
+	viper.SetDefault("search_facets", strings.Join(quicknote.FacetFields, ","))
+	viper.SetDefault("search_facet_size", "5")
+	viper.SetDefault("search_sort_by", quicknote.SortRelevance)
+	viper.SetDefault("search_display_order", "desc")
 
 	SearchCmd.PersistentFlags().IntVarP(&resultsLimit, "limit", "l",
 		viper.GetInt("search_results_limit"), "Number of results to return")
 
-	SearchCmd.PersistentFlags().IntVarP(&resultsOffset, "offset", "o", 0, "Start point is the result, use for paging")
+	SearchCmd.Flags().StringVarP(&resultsAfter, "after", "", "",
+		"Show the page after the cursor printed under the results, use for paging")
+	SearchCmd.Flags().StringVarP(&searchSortBy, "sort-by", "s", viper.GetString("search_sort_by"),
+		fmt.Sprintf("Comma separated fields to sort by, each can end with :asc or :desc [%s]",
+			strings.Join(quicknote.SortFields, ", ")))
+	SearchCmd.Flags().StringVarP(&searchDisplayOrder, "display-order", "d", viper.GetString("search_display_order"),
+		fmt.Sprintf("The order of sort fields without one [%s]", strings.Join(displayOrderOptions, ", ")))
 	SearchCmd.PersistentFlags().BoolVarP(&queryStringQuery, "query-string-query", "q", viper.GetBool("query_string_query"),
-		"By default qnote will alter the query to include the working notebook tag. Set this to to disable action.")
+		"Parse the input as a qnote query, it searches all Books instead of the working Book")
 
 	SearchCmd.PersistentFlags().StringVarP(&displayFormat, "display-format", "f", viper.GetString("display_format"),
 		fmt.Sprintf("Format to display notes in [%s]", strings.Join(displayFormatOptions, ", ")))
 
 	SearchCmd.PersistentFlags().BoolVarP(&displayTextOneResult, "text-single-result", "", viper.GetBool("display_text_for_one_result"),
 		fmt.Sprintf("Display in text mode when there is only one result"))
+
+	SearchCmd.Flags().StringVarP(&searchFacets, "facets", "", viper.GetString("search_facets"),
+		fmt.Sprintf("Comma separated facets to show under the results [%s]", strings.Join(quicknote.FacetFields, ", ")))
+	SearchCmd.Flags().IntVarP(&searchFacetSize, "facet-size", "", viper.GetInt("search_facet_size"),
+		"Number of terms to show for each facet")
+	SearchCmd.Flags().BoolVarP(&fuzzySearch, "fuzzy", "", false,
+		"Match words with a few typos, can not be used with '-q'")
+	SearchCmd.Flags().BoolVarP(&explainSearch, "explain", "", false,
+		"Show how the score of each result was computed instead of the notes")
+	SearchCmd.Flags().BoolVarP(&compareSearch, "compare", "", false,
+		"Show the results of each index of the multi index provider side by side")
+	SearchCmd.Flags().StringArrayVarP(&facetFilters, "facet-filter", "", nil,
+		"Only show results with the facet term, e.g. tag=x or created=2017-03 (can be repeated)")
+
+	SearchReindexCmd.Flags().BoolVarP(&reindexIncremental, "incremental", "i", false,
+		"Only index notes created or modified since the last re-index")
+	SearchReindexCmd.Flags().StringVarP(&reindexBookName, "book", "", "",
+		"Only re-index the notes in this Book")
 }
 
 // SearchCmd Search notes
@@ -60,16 +110,36 @@ var SearchCmd = &cobra.Command{
 	Short: "Search notes",
 	Long: `Search all notes in the working Book (see '-n').
 
-Query syntax depends on the index provider that is configured. This command
-(when '-q' is not given) uses a Phrase Prefix query. Results match on all
-given words in the query string with the last word used as a prefix. For
-better documentation on how this works. See the index providers docs
+By default this command uses a Phrase Prefix query. Results match on all
+given words in the query string with the last word used as a prefix.
+
+Use '--fuzzy' to also match words with a few typos. When nothing matches,
+"did you mean" suggestions are shown from the words in the index.
+
+To use qnote's query language set the '-q' flag, the query runs on all Books.
+The same query works with every index provider.
 
-Bleve (default): http://www.blevesearch.com/docs/Query/
-ElasticSearch: https://www.elastic.co/guide/en/elasticsearch/guide/current/_query_time_search_as_you_type.html
+	apples                    title, body or tags has the word
+	app*                      words starting with app
+	"apple pie"               the phrase
+	title:apples tag:food     in the field (title, body, tag, book, type)
+	tag:(food OR drinks)      field for a group
+	apples AND NOT tag:food   AND, OR, NOT and (), AND is implied
+	-tag:food                 same as NOT tag:food
+	created:>2017-01-01       created or modified with >, >=, <, <= or a day
+	id:603
 
-To use the QueryStringQuery syntax set the '-q' flag
-	Example (ElasticSearch): title:term1 AND tags:term2 NOT (body:term3 OR body:term4)
+	Example: title:term1 AND tag:term2 NOT (body:term3 OR body:term4)
+
+Results are sorted by relevance, use '--sort-by' to sort by other fields,
+e.g. '--sort-by created:desc,title'. Notes with the same values are sorted
+by ID. Use the '--after' cursor printed under the results for the next page.
+
+Use '--explain' to see how the score of each result was computed, the
+weight of each field and term with its boost and frequency.
+
+With the multi index provider, '--compare' runs the search on each of its
+indexes and shows the results side by side.
 `,
 	Run: searchCmdRun,
 }
@@ -78,29 +148,118 @@ func searchCmdRun(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
 		exitValidationError("invalid query string", cmd)
 	}
-	query := args[0]
+	text := args[0]
 
-	var ids []int64
-	var total uint64
-	var err error
+	if fuzzySearch && queryStringQuery {
+		exitValidationError("--fuzzy can not be used with -q", cmd)
+	}
 
+	sort, err := quicknote.ParseSort(searchSortBy, searchDisplayOrder)
+	if err != nil {
+		exitValidationError(err.Error(), cmd)
+	}
+
+	opts := &quicknote.SearchOptions{
+		FacetSize: searchFacetSize,
+		Fuzzy:     fuzzySearch,
+		Sort:      sort,
+		After:     resultsAfter,
+		Explain:   explainSearch,
+	}
+	for _, field := range strings.Split(searchFacets, ",") {
+		field = strings.TrimSpace(field)
+		if len(field) == 0 {
+			continue
+		} else if !quicknote.IsFacetField(field) {
+			exitValidationError(fmt.Sprintf("invalid facet %s", field), cmd)
+		}
+		opts.Facets = append(opts.Facets, field)
+	}
+	for _, s := range facetFilters {
+		f, err := quicknote.ParseFacetFilter(s)
+		if err != nil {
+			exitValidationError(err.Error(), cmd)
+		}
+		opts.Filters = append(opts.Filters, f)
+	}
+
+	if workingSearch != nil {
+		q, err := query.Parse(workingSearch.Query)
+		exitOnError(err)
+		opts.Query = q
+	}
+
+	// Only the text formats show facets
+	if displayFormat != "text" && displayFormat != "short" {
+		opts.Facets = nil
+	}
+
+	var q query.Node
 	if queryStringQuery {
-		ids, total, err = idxConn.SearchNote(query, resultsLimit, resultsOffset)
-	} else {
-		ids, total, err = idxConn.SearchNotePhrase(query, workingNotebook, "asc", resultsLimit, resultsOffset)
+		var perr error
+		if q, perr = query.Parse(text); perr != nil {
+			exitValidationError(perr.Error(), cmd)
+		}
+	}
+	search := func(idx quicknote.Index) (*quicknote.SearchResult, error) {
+		if q != nil {
+			return idx.SearchNote(q, resultsLimit, opts)
+		}
+		return idx.SearchNotePhrase(text, workingNotebook, resultsLimit, opts)
+	}
+
+	if compareSearch {
+		compareSearchResults(cmd, search)
+		return
+	}
+
+	res, err := search(idxConn)
+	if err == quicknote.ErrInvalidCursor {
+		exitValidationError(err.Error(), cmd)
 	}
 	exitOnError(err)
 
-	notes, err := dbConn.GetNotesByIDs(ids)
+	if res.Total == 0 && !queryStringQuery {
+		suggestions, err := idxConn.SuggestQuery(text, suggestionsLimit)
+		exitOnError(err)
+		utils.PrintSuggestions(suggestions)
+	}
+
+	notes, err := dbConn.GetNotesByIDs(res.IDs())
 	exitOnError(err)
+	notes = res.SortNotes(notes)
 
 	if displayFormat == "short" && displayTextOneResult && len(notes) == 1 {
 		displayFormat = "text"
 	}
 
-	err = utils.PrintNotes(notes, displayFormat)
+	if explainSearch {
+		utils.PrintExplanations(notes, res)
+	} else {
+		err = utils.PrintSearchResults(notes, res, displayFormat)
+		exitOnError(err)
+	}
+
+	utils.PrintFacets(res.Facets)
+	fmt.Printf("\nShowing %d of %d\n", len(res.Hits), res.Total)
+	if res.Next != "" {
+		fmt.Printf("Next page: --after %s\n", res.Next)
+	}
+}
+
+// compareSearchResults runs search on each index of the multi
+// provider and prints the results side by side
+func compareSearchResults(cmd *cobra.Command, search func(idx quicknote.Index) (*quicknote.SearchResult, error)) {
+	comparer, ok := idxConn.(quicknote.Comparer)
+	if !ok {
+		exitValidationError("--compare needs the multi index provider", cmd)
+	} else if resultsAfter != "" {
+		exitValidationError("--after can not be used with --compare", cmd)
+	}
+
+	compared, err := comparer.Compare(search)
 	exitOnError(err)
-	fmt.Printf("\nShowing %d-%d of %d\n", resultsOffset, resultsOffset+len(ids), total)
+	utils.PrintComparison(compared)
 }
 
 // SearchReindexCmd Re-indexes all Notes in all Books
@@ -113,16 +272,163 @@ If you change the index provider, copy or replace the qnote.db. You need to
 call this in order to use the search command.
 
 Re-indexing can take several minutes depending on the number of notes and
-the index provider used.`,
+the index provider used. A full re-index is built as a new index, searches
+use the current index until it is complete. Use '--incremental' to only index the notes created
+or modified since the last re-index, notes that no longer exist are removed
+from the index. Use '--book' to only re-index the notes in one Book.`,
 	Run: searchReindexCmdRun,
 }
 
 func searchReindexCmdRun(cmd *cobra.Command, args []string) {
-	notes, err := dbConn.GetAllNotes(sortBy, displayOrder)
+	var bk *quicknote.Book
+	if reindexBookName != "" {
+		var err error
+		bk, err = dbConn.GetBookByName(reindexBookName)
+		exitOnError(err)
+		if bk == nil {
+			exitValidationError("Book does not exists", cmd)
+		}
+	}
+
+	// An index with an older mapping is built again with all of the notes
+	if oi, ok := idxConn.(quicknote.OutdatedIndex); ok && oi.Outdated() && (reindexIncremental || bk != nil) {
+		fmt.Println("The index was created by an older version of qnote, re-indexing all notes")
+		reindexIncremental = false
+		bk = nil
+	}
+
+	cp, err := config.GetIndexCheckpoint()
 	exitOnError(err)
 
-	err = idxConn.IndexNotes(notes)
+	if reindexIncremental && cp == nil {
+		fmt.Println("No previous re-index found, re-indexing all notes")
+	}
+
+	// Taken before reading the notes so notes modified
+	// while re-indexing are picked up next time
+	started := time.Now()
+
+	var notes quicknote.Notes
+	if reindexIncremental && cp != nil {
+		notes, err = dbConn.GetNotesModifiedSince(bk, cp.Time, cp.MaxID)
+	} else if bk != nil {
+		notes, err = dbConn.GetAllBookNotes(bk, "id", "asc")
+	} else {
+		notes, err = dbConn.GetAllNotes("id", "asc")
+	}
+	exitOnError(err)
+
+	// A full re-index is built next to the current index, which
+	// is still searched until the new one is complete
+	deleted := 0
+	rebuilder, ok := idxConn.(quicknote.Rebuilder)
+	if ok && bk == nil && !(reindexIncremental && cp != nil) {
+		err = rebuilder.Rebuild(func(idx quicknote.Index) error {
+			return indexNotes(idx, notes)
+		})
+	} else {
+		deleted = removeDeletedNotesFromIndex(bk)
+		err = indexNotes(idxConn, notes)
+	}
+	exitOnError(err)
+
+	// The checkpoint covers all Books, re-indexing one
+	// Book does not bring the others up to date
+	if bk == nil {
+		next := &config.IndexCheckpoint{Time: started}
+		if cp != nil && reindexIncremental {
+			next.MaxID = cp.MaxID
+		}
+		for _, n := range notes {
+			if n.ID > next.MaxID {
+				next.MaxID = n.ID
+			}
+		}
+
+		err = config.SaveIndexCheckpoint(next)
+		exitOnError(err)
+	}
+
+	fmt.Printf("Finished indexing notes (%d), removed %d deleted notes\n", len(notes), deleted)
+}
+
+// SearchReshardCmd Changes the number of Bleve shards
+var SearchReshardCmd = &cobra.Command{
+	Use:   "reshard <count>",
+	Short: "Changes the number of shards the index is split across",
+	Long: `Moves the notes between the index shards so they are split across count
+shards. Only the Bleve index provider uses shards.
+
+The 'bleve_shard_count' config option is only used when the index is created,
+an existing index keeps its number of shards until it is resharded.`,
+	Run: searchReshardCmdRun,
+}
+
+func searchReshardCmdRun(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		exitValidationError("No shard count given", cmd)
+	}
+
+	shards, err := strconv.Atoi(args[0])
+	if err != nil || shards < 1 {
+		exitValidationError("Shard count must be a number greater than 0", cmd)
+	}
+
+	resharder, ok := idxConn.(quicknote.Resharder)
+	if !ok {
+		exitValidationError("The index provider does not use shards", cmd)
+	}
+
+	from := resharder.Shards()
+	moved, err := resharder.Reshard(shards)
+	exitOnError(err)
+
+	fmt.Printf("Resharded the index from %d to %d shards, moved %d notes\n", from, shards, moved)
+}
+
+// indexNotes indexes notes in batches showing the progress
+func indexNotes(idx quicknote.Index, notes quicknote.Notes) error {
+	if len(notes) == 0 {
+		return nil
+	}
+
+	bar := utils.NewProgressBar(len(notes))
+	for i := 0; i < len(notes); i += reindexBatchSize {
+		end := i + reindexBatchSize
+		if end > len(notes) {
+			end = len(notes)
+		}
+
+		if err := idx.IndexNotes(notes[i:end]); err != nil {
+			return err
+		}
+		bar.Add(end - i)
+	}
+	bar.Finish()
+	return nil
+}
+
+// removeDeletedNotesFromIndex deletes notes from the index that no longer
+// exist in the database. The number of deleted notes is returned.
+func removeDeletedNotesFromIndex(bk *quicknote.Book) int {
+	dbIDs, err := dbConn.GetAllNoteIDs(bk)
 	exitOnError(err)
 
-	fmt.Printf("Finished indexing notes (%d)\n", len(notes))
+	idxIDs, err := idxConn.NoteIDs(bk)
+	exitOnError(err)
+
+	exists := make(map[int64]bool, len(dbIDs))
+	for _, id := range dbIDs {
+		exists[id] = true
+	}
+
+	deleted := 0
+	for _, id := range idxIDs {
+		if !exists[id] {
+			err = idxConn.DeleteNote(&quicknote.Note{ID: id})
+			exitOnError(err)
+			deleted++
+		}
+	}
+	return deleted
 }
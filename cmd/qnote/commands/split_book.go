@@ -21,8 +21,8 @@ import (
 	"fmt"
 	"strconv"
 
-	"github.com/anmil/quicknote/cmd/shared/config"
 	"github.com/anmil/quicknote/cmd/shared/utils"
+	"github.com/anmil/quicknote/query"
 	"github.com/spf13/cobra"
 )
 
@@ -31,14 +31,14 @@ func init() {
 	SplitCmd.AddCommand(SplitBookIDsCmd)
 }
 
-// SplitBookQueryCmd splits one book into two book using QueryStringQuery
+// SplitBookQueryCmd splits one book into two book using a query
 var SplitBookQueryCmd = &cobra.Command{
-	Use:   "query [flags] <book_name> <query_string_query>",
-	Short: "splits the working Book into two Books using QueryStringQuery",
+	Use:   "query [flags] <book_name> <query>",
+	Short: "splits the working Book into two Books using a query",
 	Long: `Splits the working Book into two Books. All notes matching the query will be
 moved into the Book <book_name>. If <book_name> already exists, the Notes
-matching the query are merged into the exciting Book. For docs on the syntax for
-QueryStringQuery see the docs for 'qnote search'.`,
+matching the query are merged into the exciting Book. For docs on the query
+syntax see the docs for 'qnote search'.`,
 	Run: splitBooksQueryCmdRun,
 }
 
@@ -49,17 +49,19 @@ func splitBooksQueryCmdRun(cmd *cobra.Command, args []string) {
 
 	bk1 := workingNotebook
 
-	var query string
-	switch config.IndexProvider {
-	case "bleve":
-		query = fmt.Sprintf("+book:%s +(%s)", bk1.Name, args[1])
-	case "elastic":
-		query = fmt.Sprintf("book:%s AND (%s)", bk1.Name, args[1])
+	q, err := query.Parse(args[1])
+	if err != nil {
+		exitValidationError(err.Error(), cmd)
 	}
+	q = &query.And{Nodes: []query.Node{
+		&query.Phrase{Field: query.FieldBook, Value: bk1.Name},
+		q,
+	}}
 
-	_, total, err := idxConn.SearchNote(query, 1, 0)
+	res, err := idxConn.SearchNote(q, 1, nil)
 	exitOnError(err)
 
+	total := res.Total
 	if total == 0 {
 		fmt.Println("There were no notes that matched you query")
 		return
@@ -72,9 +74,11 @@ func splitBooksQueryCmdRun(cmd *cobra.Command, args []string) {
 
 		var offset uint64
 		for {
-			ids, total, err := idxConn.SearchNote(query, 2048, 0)
+			res, err := idxConn.SearchNote(q, 2048, nil)
 			exitOnError(err)
 
+			ids, total := res.IDs(), res.Total
+
 			err = dbConn.EditNoteByIDBook(ids, bk2)
 			exitOnError(err)
 
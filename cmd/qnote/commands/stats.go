@@ -0,0 +1,142 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/cmd/shared/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	statsTopTags     int
+	statsWeeks       int
+	statsStaleMonths int
+)
+
+func init() {
+	RootCmd.AddCommand(StatsCmd)
+	StatsCmd.AddCommand(StatsStaleCmd)
+
+	viper.SetDefault("stats_top_tags", "10")
+	viper.SetDefault("stats_weeks", "12")
+	viper.SetDefault("stats_stale_months", "6")
+
+	StatsCmd.PersistentFlags().StringVarP(&displayFormat, "display-format", "f", viper.GetString("display_format"),
+		fmt.Sprintf("Format to display stats and notes in [%s]", strings.Join(displayFormatOptions, ", ")))
+	StatsCmd.PersistentFlags().IntVarP(&statsStaleMonths, "stale-months", "", viper.GetInt("stats_stale_months"),
+		"Notes not modified in this many months are stale")
+
+	StatsCmd.Flags().IntVarP(&statsTopTags, "top", "", viper.GetInt("stats_top_tags"),
+		"Number of top tags and tag pairs to show")
+	StatsCmd.Flags().IntVarP(&statsWeeks, "weeks", "", viper.GetInt("stats_weeks"),
+		"Number of weeks to show created and modified notes for")
+
+	StatsStaleCmd.Flags().StringVarP(&displayOrder, "display-order", "d", viper.GetString("display_order"),
+		fmt.Sprintf("The order to display Notes [%s]", strings.Join(displayOrderOptions, ", ")))
+	StatsStaleCmd.Flags().StringVarP(&sortBy, "sort-by", "s", viper.GetString("order_by"),
+		fmt.Sprintf("Sort notes by [%s]", strings.Join(sortByOptinos, ", ")))
+}
+
+// StatsCmd Show statistics about Notes, Books, and Tags
+var StatsCmd = &cobra.Command{
+	Use:   "stats [flags]",
+	Short: "Show statistics about Notes, Books, and Tags",
+	Long: `Show statistics about Notes, Books, and Tags
+
+Shows the number of notes per Book and type, the most used tags and the tags
+most often used together, the number of notes created and modified each week,
+the average title and body length, and the number of stale notes (notes not
+modified in '--stale-months' months).
+
+Statistics are for all Books unless a Book is given with '--notebook'.
+
+Use 'qnote stats stale' to list the stale notes.`,
+	PersistentPreRun: preseistentPreGetRoot,
+	Run:              statsCmdRun,
+}
+
+func statsCmdRun(cmd *cobra.Command, args []string) {
+	bk := getStatsBook(cmd)
+
+	stats := &quicknote.Stats{
+		StaleBefore: getStaleBefore(),
+	}
+	if bk != nil {
+		stats.Book = bk.Name
+	}
+
+	var err error
+	stats.Books, err = dbConn.GetNoteCountsByBook()
+	exitOnError(err)
+
+	stats.Types, err = dbConn.GetNoteCountsByType(bk)
+	exitOnError(err)
+
+	stats.Tags, err = dbConn.GetTopTags(bk, statsTopTags)
+	exitOnError(err)
+
+	stats.TagPairs, err = dbConn.GetTagPairs(bk, statsTopTags)
+	exitOnError(err)
+
+	since := time.Now().AddDate(0, 0, -7*statsWeeks)
+	stats.Weeks, err = dbConn.GetNoteCountsByWeek(bk, since)
+	exitOnError(err)
+
+	stats.Length, err = dbConn.GetNoteLengthStats(bk)
+	exitOnError(err)
+
+	stats.Stale, err = dbConn.CountStaleNotes(bk, stats.StaleBefore)
+	exitOnError(err)
+
+	err = utils.PrintStats(stats, displayFormat)
+	exitOnError(err)
+}
+
+// StatsStaleCmd List stale Notes
+var StatsStaleCmd = &cobra.Command{
+	Use:   "stale [flags]",
+	Short: "List Notes not modified in '--stale-months' months",
+	Run:   statsStaleCmdRun,
+}
+
+func statsStaleCmdRun(cmd *cobra.Command, args []string) {
+	notes, err := dbConn.GetStaleNotes(getStatsBook(cmd), getStaleBefore(), sortBy, displayOrder)
+	exitOnError(err)
+
+	err = utils.PrintNotes(notes, displayFormat)
+	exitOnError(err)
+}
+
+// getStatsBook returns the working Book if one was given
+// with '--notebook', otherwise nil for all Books
+func getStatsBook(cmd *cobra.Command) *quicknote.Book {
+	if cmd.Flags().Changed("notebook") {
+		return workingNotebook
+	}
+	return nil
+}
+
+func getStaleBefore() time.Time {
+	return time.Now().AddDate(0, -statsStaleMonths, 0)
+}
@@ -0,0 +1,151 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/cmd/shared/config"
+	"github.com/anmil/quicknote/cmd/shared/utils"
+	"github.com/spf13/cobra"
+)
+
+// Command line variables
+var (
+	reparseAll    bool
+	reparseDryRun bool
+)
+
+func init() {
+	RootCmd.AddCommand(TagsCmd)
+	TagsCmd.AddCommand(TagsReparseCmd)
+
+	TagsReparseCmd.Flags().BoolVarP(&reparseAll, "all", "a", false, "Re-parse the notes of all Books")
+	TagsReparseCmd.Flags().BoolVarP(&reparseDryRun, "dry-run", "", false, "Print the changed tags without saving them")
+}
+
+// TagsCmd Manage tags
+var TagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "Manage the tags of notes",
+}
+
+// TagsReparseCmd Re-extracts the tags of existing notes
+var TagsReparseCmd = &cobra.Command{
+	Use:   "reparse [flags]",
+	Short: "Re-extracts the tags and people of the notes in the working Book",
+	Long: `Parses the notes in the working Book again with their parser and the
+tag rules of the config file, and saves the notes whose tags or mentioned
+people changed.
+
+Run it after changing "tag_punctuation", "tag_min_length", "tag_fold_case"
+or the parser of a Book or note type. The titles and bodies of the notes
+are not changed, and the extra tags added in the editor header are kept.`,
+	Run: tagsReparseCmdRun,
+}
+
+func tagsReparseCmdRun(cmd *cobra.Command, args []string) {
+	s, err := config.GetParserSelector()
+	exitOnError(err)
+
+	var notes quicknote.Notes
+	if reparseAll {
+		notes, err = dbConn.GetAllNotes("id", "asc")
+	} else {
+		notes, err = dbConn.GetAllBookNotes(workingNotebook, "id", "asc")
+	}
+	exitOnError(err)
+
+	changed := make(quicknote.Notes, 0)
+	changedTags := make([][]string, 0)
+	for _, n := range notes {
+		p, err := s.Parser(n.Book.Name, n.Type)
+		exitOnError(err)
+
+		// Tags added in the editor header are kept
+		oldTags := n.GetTagStringArray()
+		newTags := getNoteTags(p, getExtraTags(n, p))
+		sort.Strings(oldTags)
+		sort.Strings(newTags)
+
+		oldPeople := append([]string(nil), n.People...)
+		newPeople := append([]string(nil), getMentions(p)...)
+		sort.Strings(oldPeople)
+		sort.Strings(newPeople)
+
+		tagsChanged := !sameSortedStrings(oldTags, newTags)
+		peopleChanged := !sameSortedStrings(oldPeople, newPeople)
+		if tagsChanged {
+			fmt.Printf("%d: %s -> %s\n", n.ID, strings.Join(oldTags, ", "), strings.Join(newTags, ", "))
+		}
+		if peopleChanged {
+			fmt.Printf("%d: people %s -> %s\n", n.ID, strings.Join(oldPeople, ", "), strings.Join(newPeople, ", "))
+		}
+		if tagsChanged || peopleChanged {
+			n.People = newPeople
+			changed = append(changed, n)
+			changedTags = append(changedTags, newTags)
+		}
+	}
+
+	if len(changed) == 0 {
+		fmt.Println("No tags changed")
+		return
+	} else if reparseDryRun {
+		fmt.Printf("Tags of %d notes would change\n", len(changed))
+		return
+	}
+
+	cMsg := fmt.Sprintf("Save the new tags of %d notes?", len(changed))
+	if !skipConfirm && !utils.AskForConfirmationMust(cMsg) {
+		return
+	}
+
+	for i, n := range changed {
+		n.Tags = make(quicknote.Tags, 0, len(changedTags[i]))
+		for _, t := range changedTags[i] {
+			tag, err := dbConn.GetOrCreateTagByName(t)
+			exitOnError(err)
+			n.Tags = append(n.Tags, tag)
+		}
+
+		err = dbConn.EditNote(n)
+		exitOnError(err)
+	}
+
+	err = idxConn.IndexNotes(changed)
+	exitOnError(err)
+
+	fmt.Printf("Updated the tags of %d notes\n", len(changed))
+}
+
+// sameSortedStrings returns true if the sorted slices a and b are the same
+func sameSortedStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot file extensions
+var (
+	SQLiteExt = ".db"
+	QNOTExt   = ".qnot.gz"
+)
+
+// ErrInvalidSnapshotName the file name is not a snapshot name
+var ErrInvalidSnapshotName = errors.New("Invalid snapshot file name")
+
+const namePrefix = "qnote-"
+const nameTimeLayout = "20060102-150405"
+
+// Snapshot is a backup file stored in the backup directory
+type Snapshot struct {
+	Path    string
+	Created time.Time
+}
+
+func (s *Snapshot) String() string {
+	return fmt.Sprintf("<Snapshot Path: %s Created: %s>", s.Path, s.Created)
+}
+
+// Snapshots sorted from newest to oldest
+type Snapshots []*Snapshot
+
+func (s Snapshots) Len() int {
+	return len(s)
+}
+
+func (s Snapshots) Less(i, j int) bool {
+	return s[i].Created.After(s[j].Created)
+}
+
+func (s Snapshots) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// SnapshotName returns the file name for a snapshot
+// taken at t with the file extension ext
+func SnapshotName(t time.Time, ext string) string {
+	return namePrefix + t.Format(nameTimeLayout) + ext
+}
+
+// ParseSnapshotName returns the time the snapshot was taken
+// from its file name.
+func ParseSnapshotName(name string) (time.Time, error) {
+	name = filepath.Base(name)
+	if !strings.HasPrefix(name, namePrefix) || len(name) < len(namePrefix)+len(nameTimeLayout) {
+		return time.Time{}, ErrInvalidSnapshotName
+	}
+
+	ts := name[len(namePrefix) : len(namePrefix)+len(nameTimeLayout)]
+	ext := name[len(namePrefix)+len(nameTimeLayout):]
+	if ext != SQLiteExt && ext != QNOTExt {
+		return time.Time{}, ErrInvalidSnapshotName
+	}
+
+	t, err := time.ParseInLocation(nameTimeLayout, ts, time.Local)
+	if err != nil {
+		return time.Time{}, ErrInvalidSnapshotName
+	}
+	return t, nil
+}
+
+// IsSQLiteSnapshot returns true if the file is a SQLite database snapshot
+func IsSQLiteSnapshot(p string) bool {
+	return strings.HasSuffix(p, SQLiteExt)
+}
+
+// ListSnapshots returns all snapshots in dir sorted from newest to
+// oldest. Files that are not snapshots are ignored.
+func ListSnapshots(dir string) (Snapshots, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make(Snapshots, 0, len(files))
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		created, err := ParseSnapshotName(f.Name())
+		if err != nil {
+			continue
+		}
+
+		snapshots = append(snapshots, &Snapshot{
+			Path:    filepath.Join(dir, f.Name()),
+			Created: created,
+		})
+	}
+
+	sort.Sort(snapshots)
+	return snapshots, nil
+}
+
+// Expired returns the snapshots that are not kept by the retention rules.
+//
+// The newest snapshot of each day is kept for the keepDaily most recent days
+// that have a snapshot, and the newest snapshot of each ISO week is kept for
+// the keepWeekly most recent weeks. A snapshot kept by either rule is not
+// expired. If both values are zero or less nothing expires.
+func Expired(snapshots Snapshots, keepDaily, keepWeekly int) Snapshots {
+	if keepDaily <= 0 && keepWeekly <= 0 {
+		return Snapshots{}
+	}
+
+	sorted := make(Snapshots, len(snapshots))
+	copy(sorted, snapshots)
+	sort.Sort(sorted)
+
+	keep := make(map[*Snapshot]bool)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+
+	for _, s := range sorted {
+		day := s.Created.Format("2006-01-02")
+		if _, found := days[day]; !found && len(days) < keepDaily {
+			days[day] = true
+			keep[s] = true
+		}
+
+		year, wk := s.Created.ISOWeek()
+		week := fmt.Sprintf("%d-%d", year, wk)
+		if _, found := weeks[week]; !found && len(weeks) < keepWeekly {
+			weeks[week] = true
+			keep[s] = true
+		}
+	}
+
+	expired := make(Snapshots, 0)
+	for _, s := range sorted {
+		if !keep[s] {
+			expired = append(expired, s)
+		}
+	}
+	return expired
+}
+
+// Prune removes all snapshots from dir that are expired according to the
+// retention rules. See Expired. The removed snapshots are returned.
+func Prune(dir string, keepDaily, keepWeekly int) (Snapshots, error) {
+	snapshots, err := ListSnapshots(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := Expired(snapshots, keepDaily, keepWeekly)
+	for _, s := range expired {
+		if err := os.Remove(s.Path); err != nil {
+			return nil, err
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,119 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotNameUnit(t *testing.T) {
+	created := time.Date(2017, 3, 20, 14, 43, 9, 0, time.Local)
+
+	name := SnapshotName(created, QNOTExt)
+	if name != "qnote-20170320-144309.qnot.gz" {
+		t.Fatalf("Unexpected snapshot name %s", name)
+	}
+
+	if ts, err := ParseSnapshotName(name); err != nil {
+		t.Fatal(err)
+	} else if !ts.Equal(created) {
+		t.Fatalf("Expected %s, got %s", created, ts)
+	}
+
+	for _, name := range []string{"notes.db", "qnote-2017.db", "qnote-20170320-144309.txt"} {
+		if _, err := ParseSnapshotName(name); err != ErrInvalidSnapshotName {
+			t.Fatalf("Expected ErrInvalidSnapshotName for %s", name)
+		}
+	}
+}
+
+func TestExpiredUnit(t *testing.T) {
+	// Two snapshots a day for 30 days, starting on a Monday
+	start := time.Date(2017, 3, 6, 8, 0, 0, 0, time.Local)
+
+	snapshots := make(Snapshots, 0)
+	for i := 0; i < 30; i++ {
+		day := start.AddDate(0, 0, i)
+		snapshots = append(snapshots, &Snapshot{Created: day})
+		snapshots = append(snapshots, &Snapshot{Created: day.Add(8 * time.Hour)})
+	}
+
+	if expired := Expired(snapshots, 0, 0); len(expired) != 0 {
+		t.Fatalf("Expected 0 expired snapshots, got %d", len(expired))
+	}
+
+	// 7 days and 4 weeks, the newest 7 days span the
+	// two newest weeks so 7 + 2 are kept
+	expired := Expired(snapshots, 7, 4)
+	if kept := len(snapshots) - len(expired); kept != 9 {
+		t.Fatalf("Expected 9 kept snapshots, got %d", kept)
+	}
+
+	newest := start.AddDate(0, 0, 29).Add(8 * time.Hour)
+	for _, s := range expired {
+		if s.Created.Equal(newest) {
+			t.Fatal("Newest snapshot must never expire")
+		}
+	}
+}
+
+func TestPruneUnit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qnote-backup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2017, 3, 6, 8, 0, 0, 0, time.Local)
+	for i := 0; i < 5; i++ {
+		name := SnapshotName(start.AddDate(0, 0, i), SQLiteExt)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Not a snapshot, must be left alone
+	other := filepath.Join(dir, "notes.db")
+	if err := ioutil.WriteFile(other, []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Prune(dir, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(removed) != 3 {
+		t.Fatalf("Expected 3 removed snapshots, got %d", len(removed))
+	}
+
+	snapshots, err := ListSnapshots(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(snapshots) != 2 {
+		t.Fatalf("Expected 2 snapshots, got %d", len(snapshots))
+	} else if !snapshots[0].Created.Equal(start.AddDate(0, 0, 4)) {
+		t.Fatal("Expected the newest snapshot first")
+	}
+
+	if _, err := os.Stat(other); err != nil {
+		t.Fatal(err)
+	}
+}
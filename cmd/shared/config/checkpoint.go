@@ -0,0 +1,78 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// IndexCheckpoint records how far the index was brought up to date with
+// the database by the last successful reindex. Notes modified after Time
+// or with an ID greater than MaxID need to be indexed.
+type IndexCheckpoint struct {
+	Time  time.Time `json:"time"`
+	MaxID int64     `json:"max_id"`
+}
+
+// getIndexCheckpointPath returns the checkpoint file for the config
+// index provider, each Elasticsearch index has its own checkpoint
+func getIndexCheckpointPath() string {
+	name := IndexProvider
+	if IndexProvider == "elastic" {
+		name += "-" + viper.GetString("elastic_index_name")
+	}
+	return path.Join(DataDirectory, "index-checkpoint-"+name+".json")
+}
+
+// GetIndexCheckpoint returns the checkpoint for the config index
+// provider, nil is returned if the index was never fully reindexed
+func GetIndexCheckpoint() (*IndexCheckpoint, error) {
+	data, err := ioutil.ReadFile(getIndexCheckpointPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	cp := &IndexCheckpoint{}
+	if err = json.Unmarshal(data, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// SaveIndexCheckpoint saves the checkpoint for the config index provider
+func SaveIndexCheckpoint(cp *IndexCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	// Write then rename so a crash never leaves a partial checkpoint
+	p := getIndexCheckpointPath()
+	if err = ioutil.WriteFile(p+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(p+".tmp", p)
+}
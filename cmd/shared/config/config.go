@@ -20,16 +20,21 @@ package config
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/anmil/quicknote"
 	"github.com/anmil/quicknote/cmd/shared/utils"
 	"github.com/anmil/quicknote/db"
 	"github.com/anmil/quicknote/index"
+	"github.com/anmil/quicknote/index/elastic"
+	"github.com/anmil/quicknote/index/multi"
+	"github.com/anmil/quicknote/parser"
 	"github.com/spf13/viper"
 )
 
@@ -75,6 +80,15 @@ func init() {
 	viper.SetDefault("bleve_shard_count", "16")
 	viper.SetDefault("elastic_url", "http://127.0.0.1:9200")
 	viper.SetDefault("elastic_index_name", "qnote")
+	viper.SetDefault("elastic_bulk_actions", "500")
+	viper.SetDefault("elastic_bulk_workers", "2")
+	viper.SetDefault("multi_providers", "bleve,elastic")
+	viper.SetDefault("multi_primary", "bleve")
+	viper.SetDefault("lock_timeout", "10")
+	viper.SetDefault("parser", parser.Basic)
+	viper.SetDefault("tag_punctuation", parser.DefaultTagPunctuation)
+	viper.SetDefault("tag_min_length", "2")
+	viper.SetDefault("tag_fold_case", "true")
 
 	IndexProvider = viper.GetString("index_provider")
 }
@@ -95,6 +109,30 @@ func isTestingMode() {
 	}
 }
 
+// LockDataDirectory takes the advisory lock on the data directory so only
+// one qnote process uses the database and index at a time. If another
+// process holds the lock, it waits up to lock_timeout seconds.
+func LockDataDirectory() (*utils.FileLock, error) {
+	return LockDataDirectoryWait(time.Duration(viper.GetInt("lock_timeout")) * time.Second)
+}
+
+// LockDataDirectoryWait is LockDataDirectory waiting up to timeout
+func LockDataDirectoryWait(timeout time.Duration) (*utils.FileLock, error) {
+	l := utils.NewFileLock(path.Join(DataDirectory, "qnote.lock"))
+	if err := l.Lock(timeout); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// CloseIndexConn closes the index if its provider keeps files open
+func CloseIndexConn(idx quicknote.Index) error {
+	if c, ok := idx.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 // GetDBConn gets a new Database connection for the config provider
 func GetDBConn() (quicknote.DB, error) {
 	switch viper.GetString("db_provider") {
@@ -107,9 +145,13 @@ func GetDBConn() (quicknote.DB, error) {
 	}
 }
 
+// GetSqliteDBPath returns the path to the SQLite database file
+func GetSqliteDBPath() string {
+	return path.Join(DataDirectory, "notes.db")
+}
+
 func getSqliteDBConn() (quicknote.DB, error) {
-	fp := path.Join(DataDirectory, "notes.db")
-	d, err := db.NewDatabase("sqlite", fp)
+	d, err := db.NewDatabase("sqlite", GetSqliteDBPath())
 	if err != nil {
 		return nil, err
 	}
@@ -133,16 +175,59 @@ func getPostgresDBConn() (quicknote.DB, error) {
 
 // GetIndexConn gets a new Index connection for the config provider
 func GetIndexConn() (quicknote.Index, error) {
-	switch IndexProvider {
+	idxConn, err := getIndexConn(IndexProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	if li, ok := idxConn.(quicknote.LanguageIndex); ok {
+		langs, err := GetLanguages()
+		if err != nil {
+			return nil, err
+		}
+		li.SetLanguages(langs)
+	}
+	if ti, ok := idxConn.(quicknote.TagCaseIndex); ok {
+		ti.SetFoldTagCase(viper.GetBool("tag_fold_case"))
+	}
+	return idxConn, nil
+}
+
+func getIndexConn(provider string) (quicknote.Index, error) {
+	switch provider {
 	case "bleve":
 		return getBleveConn()
 	case "elastic":
 		return getESConn()
+	case "multi":
+		return getMultiConn()
 	default:
 		return nil, errors.New("Unsupported index provider")
 	}
 }
 
+// GetLanguages returns the languages of the Books from the config file
+func GetLanguages() (*quicknote.Languages, error) {
+	return quicknote.NewLanguages(viper.GetString("language"), viper.GetStringMapString("book_languages"))
+}
+
+// GetParserSelector returns the parsers of the Books and note types
+// and their tag rules from the config file
+func GetParserSelector() (*parser.Selector, error) {
+	s, err := parser.NewSelector(viper.GetString("parser"),
+		viper.GetStringMapString("book_parsers"), viper.GetStringMapString("type_parsers"))
+	if err != nil {
+		return nil, err
+	}
+
+	s.Rules, err = parser.NewTagRules(viper.GetString("tag_punctuation"),
+		viper.GetInt("tag_min_length"), viper.GetBool("tag_fold_case"))
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 func getBleveConn() (quicknote.Index, error) {
 	shareds := viper.GetString("bleve_shard_count")
 	idxConn, err := index.NewIndex("bleve", DataDirectory, shareds)
@@ -153,6 +238,9 @@ func getBleveConn() (quicknote.Index, error) {
 }
 
 func getESConn() (quicknote.Index, error) {
+	elastic.BulkActions = viper.GetInt("elastic_bulk_actions")
+	elastic.BulkWorkers = viper.GetInt("elastic_bulk_workers")
+
 	url := viper.GetString("elastic_url")
 	indexName := viper.GetString("elastic_index_name")
 	idxConn, err := index.NewIndex("elastic", url, indexName)
@@ -162,6 +250,27 @@ func getESConn() (quicknote.Index, error) {
 	return idxConn, nil
 }
 
+func getMultiConn() (quicknote.Index, error) {
+	var names []string
+	var indexes []quicknote.Index
+	for _, name := range strings.Split(viper.GetString("multi_providers"), ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		} else if name == "multi" {
+			return nil, errors.New("multi_providers can not have the multi provider")
+		}
+
+		idxConn, err := getIndexConn(name)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		indexes = append(indexes, idxConn)
+	}
+	return multi.NewIndex(names, indexes, viper.GetString("multi_primary"))
+}
+
 // GetWorkingBook gets the config working Book
 func GetWorkingBook(db quicknote.DB, bkName string) (*quicknote.Book, error) {
 	if bkName == viper.GetString("default_book") {
@@ -170,6 +279,18 @@ func GetWorkingBook(db quicknote.DB, bkName string) (*quicknote.Book, error) {
 	return db.GetBookByName(bkName)
 }
 
+// GetWorkingSavedSearch gets the saved search for a working Book name
+// starting with quicknote.SavedSearchPrefix
+func GetWorkingSavedSearch(db quicknote.DB, name string) (*quicknote.SavedSearch, error) {
+	s, err := db.GetSavedSearchByName(quicknote.SavedSearchName(name))
+	if err != nil {
+		return nil, err
+	} else if s == nil {
+		return nil, fmt.Errorf("Saved search %s does not exists", quicknote.SavedSearchName(name))
+	}
+	return s, nil
+}
+
 // Default config file for QuickNote
 var defaultConfigFileText = `
 # Default Book to use with call commands
@@ -218,6 +339,39 @@ db_provider: sqlite
 #   port: 5432
 #   sslmode: disable
 
+# Language of the notes, the words of the title and body
+# are stemmed so searching for "deploying" finds
+# "deployment". One of de, en, es, fr, it, nl, pt or
+# empty to not stem words. book_languages sets the
+# language of single Books. Run "qnote search reindex"
+# after changing the languages.
+language: ""
+# book_languages:
+#   Work: en
+#   Arbeit: de
+
+# Parser of the text entered in the editor. basic uses the
+# first line as the title and words starting with '#' as
+# tags. markdown uses the first heading as the title,
+# ignores tags in code and reads YAML front matter for
+# the title, tags and other fields. book_parsers and
+# type_parsers set the parser of single Books and note
+# types, a Book's parser is used before its type's.
+parser: basic
+# book_parsers:
+#   Wiki: markdown
+# type_parsers:
+#   url: basic
+
+# Tags are the letters, numbers and tag_punctuation characters
+# after a '#', punctuation at the end of a tag is removed.
+# Tags shorter than tag_min_length characters are ignored
+# and tag_fold_case lower cases them. Run "qnote tags
+# reparse" after changing the tag rules.
+tag_punctuation: _-./
+tag_min_length: 2
+tag_fold_case: true
+
 # Indexing provider
 # Currently only Bleve and Elasticsearch
 # Bleve: http://www.blevesearch.com/docs/Query-String-Query/
@@ -225,6 +379,7 @@ db_provider: sqlite
 # are supported.
 index_provider: bleve
 # index_provider: elastic
+# index_provider: multi
 
 # Qnote will split notes across multiple Bleve indexes
 # bleve_shard_count is the number of indexes to use.
@@ -232,11 +387,67 @@ index_provider: bleve
 # read/write disc speed. If the default does not work
 # you will need to experiment to find the correct value.
 #
-# If you change this value, you will need to delete the .bleve
-# index folders in the data directory and call
-# "qnote search reindex" to rebuilt the indexes
+# This is only used when the index is created, to change
+# the number of shards of an existing index run
+# "qnote search reshard <count>"
 bleve_shard_count: 16
 
 # elastic_url: http://127.0.0.1:9200
 # elastic_index_name: qnote
+
+# Notes are sent to ElasticSearch in bulk requests of
+# elastic_bulk_actions notes, elastic_bulk_workers
+# requests at a time
+# elastic_bulk_actions: 500
+# elastic_bulk_workers: 2
+
+# The multi provider writes notes to all the providers in
+# multi_providers and searches multi_primary. Use it to
+# move to another provider: run "qnote search reindex",
+# check the new index with "qnote search --compare" and
+# then make it the index_provider.
+# multi_providers: bleve,elastic
+# multi_primary: bleve
+
+# Only one qnote or qnote-cui process can use the data
+# directory at a time. Number of seconds to wait for
+# another process to finish before giving up.
+lock_timeout: 10
+
+# Directory "qnote backup" writes snapshots to
+# (defaults to "backups" in the data directory)
+# backup_directory: $HOME/.config/quicknote/backups
+
+# Retention rules used by "qnote backup". The newest
+# snapshot of each day is kept for backup_keep_daily days
+# and the newest of each week for backup_keep_weekly weeks.
+backup_keep_daily: 7
+backup_keep_weekly: 4
+
+# Defaults for "qnote stats". Number of top tags and tag
+# pairs, number of weeks of created/modified notes, and
+# the months after which a not modified note is stale.
+stats_top_tags: 10
+stats_weeks: 12
+stats_stale_months: 6
+
+# Facets counted under "qnote search" results, any of
+# book, tags, type and created (by month). Leave empty
+# to not show facets. search_facet_size is the number
+# of terms shown for each facet.
+search_facets: book,tags,type,created
+search_facet_size: 5
+
+# Order of "qnote search" results. Comma separated fields,
+# each can end with :asc or :desc, of relevance, created,
+# modified, title and id. search_display_order is the
+# order of fields without one.
+search_sort_by: relevance
+search_display_order: desc
+
+# Number of notes shown by "qnote related"
+related_limit: 10
+
+# Number of tags or titles printed by "qnote complete"
+complete_limit: 10
 `
@@ -60,7 +60,7 @@ var ErrTagNoteFound = errors.New("Note how unknown tag")
 var MagicStr = "QNOT"
 
 // CurrentVersion the current format version used for encoding and decoding
-var CurrentVersion uint32 = 1
+var CurrentVersion uint32 = 3
 
 // HeaderLen length of the header block
 var HeaderLen = 16
@@ -70,9 +70,11 @@ type RecordType byte
 
 // List of record types
 var (
-	Book RecordType = 0
-	Tag  RecordType = 1
-	Note RecordType = 2
+	Book   RecordType = 0
+	Tag    RecordType = 1
+	Note   RecordType = 2
+	People RecordType = 3
+	Due    RecordType = 4
 )
 
 // BinaryEncoder encodes a Note into the QNOT format
@@ -107,16 +109,21 @@ var (
 //
 // 	| 4 byte magic "QNOT" | 4 byte version (uint32) | 8 byte timestamp (uint64) |
 //
-// There are three types of records; Book, Tag, and Note. The first byte of a
-// record specifies the record type.
+// There are five types of records; Book, Tag, Note, People and Due. The first
+// byte of a record specifies the record type.
 //
 // All Book and Tag records that a Note record references MUST appear before
-// the Note record that referenced it.
+// the Note record that referenced it. The People and Due records of a Note are
+// written right before the Note record, only for Notes that mention people or
+// have a due date. Version 1 files do not have People records and versions 1
+// and 2 do not have Due records.
 //
 // Record Types
-// Book = 0 (0x00)
-// Tag  = 1 (0x01)
-// Note = 2 (0x02)
+// Book   = 0 (0x00)
+// Tag    = 1 (0x01)
+// Note   = 2 (0x02)
+// People = 3 (0x03)
+// Due    = 4 (0x04)
 //
 // Book Record
 //
@@ -152,6 +159,20 @@ var (
 // 	| 8 byte number of tags (uint64)         |
 // 	| 8 byte tag ID (uint64)                 | <- repeats for each tag
 //
+// People Record
+//
+// 	| 1 byte record type "3"                 |
+// 	| 8 byte note ID (uint64)                |
+// 	| 8 byte number of people (uint64)       |
+// 	| 8 byte Name string length (uint64)     | <- repeats for each person
+// 	| varlen Name byte string                |
+//
+// Due Record
+//
+// 	| 1 byte record type "4"                 |
+// 	| 8 byte note ID (uint64)                |
+// 	| 8 byte Due timestamp (uint64)          |
+//
 type BinaryEncoder struct {
 	w io.Writer
 
@@ -223,6 +244,22 @@ func (b *BinaryEncoder) WriteNote(n *quicknote.Note) (uint64, error) {
 		bytesWritten += bw
 	}
 
+	if len(n.People) > 0 {
+		bw, err = b.writePeople(n)
+		if err != nil {
+			return bytesWritten, err
+		}
+		bytesWritten += bw
+	}
+
+	if !n.Due.IsZero() {
+		bw, err = b.writeDue(n)
+		if err != nil {
+			return bytesWritten, err
+		}
+		bytesWritten += bw
+	}
+
 	bw, err = b.writeNote(n)
 	if err != nil {
 		return bytesWritten, err
@@ -324,6 +361,43 @@ func (b *BinaryEncoder) writeNote(n *quicknote.Note) (uint64, error) {
 	return b.writeBuffer(buff)
 }
 
+func (b *BinaryEncoder) writePeople(n *quicknote.Note) (uint64, error) {
+	buff := &bytes.Buffer{}
+
+	if _, err := buff.Write([]byte{byte(People)}); err != nil {
+		return 0, err
+	}
+	if err := writeInt64(buff, n.ID); err != nil {
+		return 0, err
+	}
+	if err := writeInt64(buff, int64(len(n.People))); err != nil {
+		return 0, err
+	}
+	for _, name := range n.People {
+		if err := writeString(buff, name); err != nil {
+			return 0, err
+		}
+	}
+
+	return b.writeBuffer(buff)
+}
+
+func (b *BinaryEncoder) writeDue(n *quicknote.Note) (uint64, error) {
+	buff := &bytes.Buffer{}
+
+	if _, err := buff.Write([]byte{byte(Due)}); err != nil {
+		return 0, err
+	}
+	if err := writeInt64(buff, n.ID); err != nil {
+		return 0, err
+	}
+	if err := writeTime(buff, n.Due); err != nil {
+		return 0, err
+	}
+
+	return b.writeBuffer(buff)
+}
+
 func writeString(buff io.Writer, s string) error {
 	data := []byte(s)
 
@@ -387,8 +461,10 @@ type BinaryDecoder struct {
 	Header *Header
 	Err    error
 
-	wBooks map[int64]*quicknote.Book
-	wTags  map[int64]*quicknote.Tag
+	wBooks  map[int64]*quicknote.Book
+	wTags   map[int64]*quicknote.Tag
+	wPeople map[int64][]string
+	wDue    map[int64]time.Time
 }
 
 // Header QNOT file header block
@@ -400,9 +476,11 @@ type Header struct {
 // NewBinaryDecoder returns a new BinaryDecoder
 func NewBinaryDecoder(r io.Reader) *BinaryDecoder {
 	return &BinaryDecoder{
-		r:      r,
-		wBooks: make(map[int64]*quicknote.Book),
-		wTags:  make(map[int64]*quicknote.Tag),
+		r:       r,
+		wBooks:  make(map[int64]*quicknote.Book),
+		wTags:   make(map[int64]*quicknote.Tag),
+		wPeople: make(map[int64][]string),
+		wDue:    make(map[int64]time.Time),
 	}
 }
 
@@ -483,6 +561,20 @@ func (d *BinaryDecoder) parseNotes(out chan *quicknote.Note) {
 				break
 			}
 			d.wTags[tag.ID] = tag
+		case People:
+			id, people, err := d.parsePeople()
+			if err != nil {
+				d.Err = err
+				break
+			}
+			d.wPeople[id] = people
+		case Due:
+			id, due, err := d.parseDue()
+			if err != nil {
+				d.Err = err
+				break
+			}
+			d.wDue[id] = due
 		default:
 			d.Err = ErrInvalidRecordType
 			break
@@ -579,9 +671,55 @@ func (d *BinaryDecoder) parseNote() (*quicknote.Note, error) {
 		n.Tags = append(n.Tags, tag)
 	}
 
+	if people, found := d.wPeople[n.ID]; found {
+		n.People = people
+		delete(d.wPeople, n.ID)
+	}
+	if due, found := d.wDue[n.ID]; found {
+		n.Due = due
+		delete(d.wDue, n.ID)
+	}
+
 	return n, nil
 }
 
+func (d *BinaryDecoder) parsePeople() (int64, []string, error) {
+	id, err := readInt64(d.r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	cnt, err := readInt64(d.r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	people := make([]string, 0)
+	for i := int64(0); i < cnt; i++ {
+		name, err := readString(d.r)
+		if err != nil {
+			return 0, nil, err
+		}
+		people = append(people, name)
+	}
+
+	return id, people, nil
+}
+
+func (d *BinaryDecoder) parseDue() (int64, time.Time, error) {
+	id, err := readInt64(d.r)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	due, err := readTime(d.r)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return id, due, nil
+}
+
 func readRecordType(rd io.Reader) (RecordType, error) {
 	buff := make([]byte, 1)
 	_, err := io.ReadFull(rd, buff)
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/anmil/quicknote"
 	"github.com/anmil/quicknote/test"
@@ -235,6 +236,92 @@ func TestBinaryDecoder(t *testing.T) {
 	}
 }
 
+func TestBinaryPeople(t *testing.T) {
+	buff := &bytes.Buffer{}
+
+	enc := NewBinaryEncoder(buff)
+	if _, err := enc.WriteHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	notes := test.GetTestNotesCust(notesJSON)
+	notes[1].People = []string{"alice", "bob"}
+	for _, n := range notes {
+		if _, err := enc.WriteNote(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dec := NewBinaryDecoder(bufio.NewReader(buff))
+	if err := dec.ParseHeader(); err != nil {
+		t.Fatal(err)
+	} else if dec.Header.Version != 3 {
+		t.Error("Expected Version 3, got", dec.Header.Version)
+	}
+
+	notesChan, err := dec.ParseNotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := make(quicknote.Notes, 0)
+	for n := range notesChan {
+		decoded = append(decoded, n)
+	}
+	if dec.Err != nil {
+		t.Fatal(dec.Err)
+	}
+
+	test.CheckNotes(t, decoded, notes)
+	for idx, n := range decoded {
+		if !test.StringSliceEq(n.People, notes[idx].People) {
+			t.Errorf("Expected people %v, got %v", notes[idx].People, n.People)
+		}
+	}
+}
+
+func TestBinaryDue(t *testing.T) {
+	buff := &bytes.Buffer{}
+
+	enc := NewBinaryEncoder(buff)
+	if _, err := enc.WriteHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	notes := test.GetTestNotesCust(notesJSON)
+	notes[0].Due = time.Date(2017, 4, 1, 12, 0, 0, 0, time.UTC)
+	for _, n := range notes {
+		if _, err := enc.WriteNote(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dec := NewBinaryDecoder(bufio.NewReader(buff))
+	if err := dec.ParseHeader(); err != nil {
+		t.Fatal(err)
+	}
+
+	notesChan, err := dec.ParseNotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := make(quicknote.Notes, 0)
+	for n := range notesChan {
+		decoded = append(decoded, n)
+	}
+	if dec.Err != nil {
+		t.Fatal(dec.Err)
+	}
+
+	test.CheckNotes(t, decoded, notes)
+	for idx, n := range decoded {
+		if !n.Due.Equal(notes[idx].Due) {
+			t.Errorf("Expected due date %s, got %s", notes[idx].Due, n.Due)
+		}
+	}
+}
+
 func BenchmarkBinaryEncoder(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		buff := &bytes.Buffer{}
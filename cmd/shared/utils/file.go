@@ -18,6 +18,7 @@
 package utils
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -41,3 +42,24 @@ func ExpandFilePath(p string) (xp string, err error) {
 	}
 	return filepath.Clean(xp), err
 }
+
+// CopyFile copies the file src to dst, dst is created
+// if it does not exists and truncated if it does.
+func CopyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,114 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How often a held lock is checked again while waiting
+const lockPollInterval = 100 * time.Millisecond
+
+// LockedError the lock is held by another process
+type LockedError struct {
+	Path string
+	PID  int
+}
+
+func (e *LockedError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("%s is locked by another qnote process (pid %d), close it and try again", e.Path, e.PID)
+	}
+	return fmt.Sprintf("%s is locked by another qnote process, close it and try again", e.Path)
+}
+
+// FileLock is an advisory lock on a file shared between processes. The
+// lock is released by the OS if the process exits without unlocking.
+type FileLock struct {
+	Path string
+	file *os.File
+}
+
+// NewFileLock returns a new FileLock for the file at path
+func NewFileLock(path string) *FileLock {
+	return &FileLock{Path: path}
+}
+
+// Lock takes the lock, waiting up to timeout for another process to release
+// it. A *LockedError is returned if the lock is still held after timeout.
+// The process's pid is written to the lock file so the error can name the
+// process holding it.
+func (l *FileLock) Lock(timeout time.Duration) error {
+	file, err := os.OpenFile(l.Path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return err
+		} else if locked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			file.Close()
+			return &LockedError{Path: l.Path, PID: readLockPID(l.Path)}
+		}
+		time.Sleep(lockPollInterval)
+	}
+
+	l.file = file
+	if err = file.Truncate(0); err == nil {
+		_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	if err != nil {
+		l.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Unlock releases the lock
+func (l *FileLock) Unlock() error {
+	if l.file == nil {
+		return nil
+	}
+
+	err := unlockFile(l.file)
+	l.file.Close()
+	l.file = nil
+	return err
+}
+
+func readLockPID(path string) int {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
@@ -0,0 +1,65 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !windows
+// +build !windows
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLockUnit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qnote-lock-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "qnote.lock")
+
+	l1 := NewFileLock(p)
+	if err := l1.Lock(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// flock locks belong to the open file, so a second
+	// open in the same process conflicts like another process
+	l2 := NewFileLock(p)
+	err = l2.Lock(200 * time.Millisecond)
+	if lerr, ok := err.(*LockedError); !ok {
+		t.Fatalf("Expected LockedError, got %v", err)
+	} else if lerr.PID != os.Getpid() {
+		t.Fatalf("Expected pid %d, got %d", os.Getpid(), lerr.PID)
+	}
+
+	if err := l1.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l2.Lock(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := l2.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,129 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anmil/quicknote"
+)
+
+// DueLayout is the layout of due dates in the editor and output
+const DueLayout = "2006-01-02"
+
+// noteHeaderEnd is the line ending the header at the top of the editor buffer
+const noteHeaderEnd = "--"
+
+// NoteHeader holds the values of the header at the top of the
+// editor buffer. Tags are added to the tags found in the text.
+type NoteHeader struct {
+	Book string
+	Type string
+	Tags []string
+	Due  time.Time
+}
+
+// FormatNoteHeader returns the header followed by the text
+func FormatNoteHeader(h *NoteHeader, text string) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "book: %s\n", h.Book)
+	fmt.Fprintf(&buf, "type: %s\n", h.Type)
+	fmt.Fprintf(&buf, "tags: %s\n", strings.Join(h.Tags, ", "))
+	if h.Due.IsZero() {
+		buf.WriteString("due: \n")
+	} else {
+		fmt.Fprintf(&buf, "due: %s\n", h.Due.Format(DueLayout))
+	}
+	buf.WriteString(noteHeaderEnd + "\n")
+	buf.WriteString(text)
+	return buf.String()
+}
+
+// ParseNoteHeader parses the header at the top of text made by
+// FormatNoteHeader, returning it and the text below it
+func ParseNoteHeader(text string) (*NoteHeader, string, error) {
+	lines := strings.Split(text, "\n")
+
+	end := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == noteHeaderEnd {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, "", fmt.Errorf("The header must end with a %q line", noteHeaderEnd)
+	}
+
+	h := &NoteHeader{}
+	for _, line := range lines[:end] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, "", fmt.Errorf("Invalid header line %q", line)
+		}
+		value := strings.TrimSpace(parts[1])
+
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case "book":
+			h.Book = value
+		case "type":
+			h.Type = value
+		case "tags":
+			h.Tags = splitHeaderTags(value)
+		case "due":
+			if value == "" {
+				continue
+			}
+			due, err := time.ParseInLocation(DueLayout, value, time.Local)
+			if err != nil {
+				return nil, "", fmt.Errorf("Invalid due date %q, use YYYY-MM-DD", value)
+			}
+			h.Due = due
+		default:
+			return nil, "", fmt.Errorf("Unknown header %q", parts[0])
+		}
+	}
+
+	if h.Book == "" {
+		return nil, "", fmt.Errorf("The header has no book")
+	}
+	if !InSliceString(h.Type, quicknote.NoteTypes) {
+		return nil, "", fmt.Errorf("Invalid type %q, use one of %s", h.Type, strings.Join(quicknote.NoteTypes, ", "))
+	}
+
+	return h, strings.Join(lines[end+1:], "\n"), nil
+}
+
+// splitHeaderTags splits the tags on commas and spaces
+func splitHeaderTags(value string) []string {
+	fields := strings.FieldsFunc(value, func(c rune) bool {
+		return c == ',' || c == ' ' || c == '\t'
+	})
+	tags := make([]string, 0, len(fields))
+	for _, f := range fields {
+		tags = append(tags, strings.TrimPrefix(f, "#"))
+	}
+	return tags
+}
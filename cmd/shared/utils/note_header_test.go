@@ -0,0 +1,67 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNoteHeaderUnit(t *testing.T) {
+	h := &NoteHeader{
+		Book: "Work",
+		Type: "basic",
+		Tags: []string{"todo", "urgent"},
+		Due:  time.Date(2017, 4, 1, 0, 0, 0, 0, time.Local),
+	}
+	text := "Title #tag\n\nBody\n--\nmore"
+
+	parsed, rest, err := ParseNoteHeader(FormatNoteHeader(h, text))
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(parsed, h) {
+		t.Fatalf("Expected %v, got %v", h, parsed)
+	} else if rest != text {
+		t.Fatalf("Expected text %q, got %q", text, rest)
+	}
+
+	parsed, _, err = ParseNoteHeader("Book: General\ntype: url\ntags: #a b,c\ndue:\n--\ntext")
+	if err != nil {
+		t.Fatal(err)
+	} else if parsed.Book != "General" || parsed.Type != "url" || !parsed.Due.IsZero() ||
+		!reflect.DeepEqual(parsed.Tags, []string{"a", "b", "c"}) {
+		t.Fatalf("Unexpected header %v", parsed)
+	}
+}
+
+func TestParseNoteHeaderErrorsUnit(t *testing.T) {
+	bad := []string{
+		"book: General\ntype: basic\ntext",
+		"book: General\ntype: basic\ndue: tomorrow\n--\ntext",
+		"book: General\ntype: other\n--\ntext",
+		"book: \ntype: basic\n--\ntext",
+		"book: General\ntype: basic\ncolor: red\n--\ntext",
+		"book: General\ntype: basic\nno colon\n--\ntext",
+	}
+	for _, text := range bad {
+		if _, _, err := ParseNoteHeader(text); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const noteSectionHelp = `# Each note starts with a "--- qnote:note" line. Edit the text below it,
+# or change book="..." to move the note to another Book. Do not change the
+# id. Notes removed from the buffer are not changed. Lines before the first
+# note are ignored.
+`
+
+var noteSectionRe = regexp.MustCompile(`^--- qnote:note id=(\d+) book=("(?:[^"\\]|\\.)*") ---$`)
+
+// NoteSection is the text of one note in a buffer holding
+// several notes
+type NoteSection struct {
+	ID   int64
+	Book string
+	Text string
+}
+
+// FormatNoteSections returns the sections as one buffer, each
+// starting with a delimiter line holding the note's ID and Book
+func FormatNoteSections(sections []*NoteSection) string {
+	var buf bytes.Buffer
+	buf.WriteString(noteSectionHelp)
+	for _, s := range sections {
+		fmt.Fprintf(&buf, "\n--- qnote:note id=%d book=%s ---\n", s.ID, strconv.Quote(s.Book))
+		buf.WriteString(strings.TrimRight(s.Text, "\n"))
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// ParseNoteSections parses a buffer made by FormatNoteSections. Text
+// before the first delimiter line is ignored
+func ParseNoteSections(text string) ([]*NoteSection, error) {
+	sections := make([]*NoteSection, 0)
+	seen := make(map[int64]bool)
+
+	var cur *NoteSection
+	var lines []string
+	closeSection := func() {
+		if cur != nil {
+			cur.Text = strings.TrimRight(strings.Join(lines, "\n"), "\n")
+			sections = append(sections, cur)
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if !strings.HasPrefix(line, "--- qnote:note ") {
+			lines = append(lines, line)
+			continue
+		}
+
+		m := noteSectionRe.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("Invalid note line %q", line)
+		}
+		id, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		book, err := strconv.Unquote(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid book in note line %q", line)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("Note %d is in the buffer more than once", id)
+		}
+		seen[id] = true
+
+		closeSection()
+		cur = &NoteSection{ID: id, Book: book}
+		lines = nil
+	}
+	closeSection()
+
+	return sections, nil
+}
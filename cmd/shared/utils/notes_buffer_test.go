@@ -0,0 +1,63 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNoteSectionsUnit(t *testing.T) {
+	sections := []*NoteSection{
+		{ID: 12, Book: "General", Text: "Title one #tag\n\nBody one\n"},
+		{ID: 15, Book: `Work "stuff"`, Text: "Title two\n\n--- not a note\n"},
+	}
+
+	text := FormatNoteSections(sections)
+	parsed, err := ParseNoteSections(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*NoteSection{
+		{ID: 12, Book: "General", Text: "Title one #tag\n\nBody one"},
+		{ID: 15, Book: `Work "stuff"`, Text: "Title two\n\n--- not a note"},
+	}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Fatalf("Expected %v, got %v", expected, parsed)
+	}
+}
+
+func TestParseNoteSectionsErrorsUnit(t *testing.T) {
+	bad := []string{
+		"--- qnote:note id=a book=\"General\" ---\ntext",
+		"--- qnote:note id=1 book=General ---\ntext",
+		"--- qnote:note id=1 book=\"General\" ---\na\n--- qnote:note id=1 book=\"General\" ---\nb",
+	}
+	for _, text := range bad {
+		if _, err := ParseNoteSections(text); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+
+	if s, err := ParseNoteSections("only help text\n"); err != nil {
+		t.Fatal(err)
+	} else if len(s) != 0 {
+		t.Fatalf("Expected no sections, got %v", s)
+	}
+}
@@ -92,7 +92,7 @@ func PrintNoteColored(n *quicknote.Note, titleOnly bool) {
 	if titleOnly {
 		printNoteTitleOnly(n)
 	} else {
-		printDetailedNoteColored(n)
+		printDetailedNoteColored(n, nil)
 	}
 }
 
@@ -121,14 +121,16 @@ func printNoteTitleOnly(n *quicknote.Note) {
 func printDetailedNotes(notes quicknote.Notes) {
 	nLen := len(notes)
 	for idx, n := range notes {
-		printDetailedNoteColored(n)
+		printDetailedNoteColored(n, nil)
 		if idx+1 < nLen {
 			fmt.Printf("\n")
 		}
 	}
 }
 
-func printDetailedNoteColored(n *quicknote.Note) {
+// printDetailedNoteColored prints the Note, when hit is
+// given the matched terms are highlighted
+func printDetailedNoteColored(n *quicknote.Note, hit *quicknote.SearchHit) {
 	fmt.Print(FgCyan("ID: "))
 	fmt.Print(n.ID)
 
@@ -147,11 +149,32 @@ func printDetailedNoteColored(n *quicknote.Note) {
 	fmt.Println(FgMagenta("--------------------------------------------------"))
 
 	fmt.Print(FgCyan("Title: "))
-	fmt.Println(n.Title)
+	if frags := hitFragments(hit, "title"); len(frags) > 0 {
+		fmt.Println(HighlightFragment(frags[0]))
+	} else {
+		fmt.Println(n.Title)
+	}
 
 	fmt.Print(FgCyan("Tags: "))
 	fmt.Println(strings.Join(colorTags(n.Tags), ", "))
 
+	if len(n.People) > 0 {
+		fmt.Print(FgCyan("People: "))
+		fmt.Println(strings.Join(colorPeople(n.People), ", "))
+	}
+
+	if !n.Due.IsZero() {
+		fmt.Print(FgCyan("Due: "))
+		fmt.Println(n.Due.Format(DueLayout))
+	}
+
+	if frags := hitFragments(hit, "body"); len(frags) > 0 {
+		fmt.Println(FgCyan("Matches:"))
+		for _, f := range frags {
+			fmt.Printf("  %s\n", HighlightFragment(f))
+		}
+	}
+
 	if len(n.Body) > 0 {
 		fmt.Printf("\n%s\n", n.Body)
 	}
@@ -165,6 +188,14 @@ func colorTags(tags quicknote.Tags) []string {
 	return ctags
 }
 
+func colorPeople(people []string) []string {
+	cpeople := make([]string, 0, len(people))
+	for _, p := range people {
+		cpeople = append(cpeople, FgBlue("@"+p))
+	}
+	return cpeople
+}
+
 // PrintNotesIDs prints the Note's ids
 func PrintNotesIDs(notes quicknote.Notes) {
 	for _, n := range notes {
@@ -0,0 +1,80 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Width of the bar in characters
+const progressBarWidth = 40
+
+// ProgressBar prints the progress of a long running task
+// with an estimate of the time remaining
+type ProgressBar struct {
+	Total   int
+	current int
+	start   time.Time
+	out     io.Writer
+}
+
+// NewProgressBar returns a new ProgressBar for total items printing to stdout
+func NewProgressBar(total int) *ProgressBar {
+	return &ProgressBar{
+		Total: total,
+		start: time.Now(),
+		out:   os.Stdout,
+	}
+}
+
+// Add adds n finished items and redraws the bar
+func (p *ProgressBar) Add(n int) {
+	p.current += n
+	if p.current > p.Total {
+		p.current = p.Total
+	}
+	fmt.Fprintf(p.out, "\r%s", p.render(time.Now()))
+}
+
+// Finish ends the bar's line
+func (p *ProgressBar) Finish() {
+	fmt.Fprintln(p.out)
+}
+
+func (p *ProgressBar) render(now time.Time) string {
+	ratio := 1.0
+	if p.Total > 0 {
+		ratio = float64(p.current) / float64(p.Total)
+	}
+
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+
+	eta := "--"
+	if p.current > 0 {
+		elapsed := now.Sub(p.start)
+		remaining := time.Duration(float64(elapsed) / float64(p.current) * float64(p.Total-p.current))
+		eta = remaining.Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("[%s] %3d%% %d/%d ETA %s ", bar, int(ratio*100), p.current, p.Total, eta)
+}
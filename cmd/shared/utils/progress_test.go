@@ -0,0 +1,47 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressBarUnit(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := NewProgressBar(100)
+	p.out = out
+
+	now := time.Now()
+	p.start = now.Add(-10 * time.Second)
+	p.current = 50
+
+	s := p.render(now)
+	if !strings.Contains(s, " 50% 50/100 ETA 10s") {
+		t.Fatalf("Unexpected progress bar %q", s)
+	} else if strings.Count(s, "=") != progressBarWidth/2 {
+		t.Fatalf("Expected a half filled bar, got %q", s)
+	}
+
+	p.Add(100)
+	if !strings.Contains(out.String(), "100% 100/100 ETA 0s") {
+		t.Fatalf("Unexpected progress bar %q", out.String())
+	}
+}
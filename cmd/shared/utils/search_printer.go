@@ -0,0 +1,279 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anmil/quicknote"
+
+	"github.com/fatih/color"
+)
+
+// FgMatch is the color of the matched terms in search results
+var FgMatch = color.New(color.FgHiYellow, color.Bold).SprintFunc()
+
+// PrintSearchResults prints the notes of a search in the given format.
+// The text and short formats highlight the terms that matched.
+func PrintSearchResults(notes quicknote.Notes, res *quicknote.SearchResult, format string) error {
+	switch format {
+	case "text":
+		printSearchResultsDetailed(notes, res)
+	case "short":
+		printSearchResultsShort(notes, res)
+	default:
+		return PrintNotes(notes, format)
+	}
+	return nil
+}
+
+func printSearchResultsDetailed(notes quicknote.Notes, res *quicknote.SearchResult) {
+	nLen := len(notes)
+	for idx, n := range notes {
+		printDetailedNoteColored(n, res.Hit(n.ID))
+		if idx+1 < nLen {
+			fmt.Printf("\n")
+		}
+	}
+}
+
+func printSearchResultsShort(notes quicknote.Notes, res *quicknote.SearchResult) {
+	for _, n := range notes {
+		hit := res.Hit(n.ID)
+
+		fmt.Print(FgCyan("ID: "))
+		fmt.Print(FgMagenta(n.ID))
+		fmt.Print(FgCyan(" Title: "))
+		if frags := hitFragments(hit, "title"); len(frags) > 0 {
+			fmt.Println(HighlightFragment(frags[0]))
+		} else {
+			fmt.Println(n.Title)
+		}
+
+		for _, f := range hitFragments(hit, "body") {
+			fmt.Printf("    %s\n", HighlightFragment(f))
+		}
+	}
+}
+
+// PrintExplanations prints how the score of each note was computed
+func PrintExplanations(notes quicknote.Notes, res *quicknote.SearchResult) {
+	for idx, n := range notes {
+		if idx > 0 {
+			fmt.Println()
+		}
+
+		hit := res.Hit(n.ID)
+		fmt.Print(FgCyan("ID: "))
+		fmt.Print(FgMagenta(n.ID))
+		fmt.Print(FgCyan(" Title: "))
+		fmt.Println(n.Title)
+
+		if hit == nil || hit.Explanation == nil {
+			fmt.Println("    No explanation")
+			continue
+		}
+		fmt.Print(FormatExplanation(hit.Explanation, 1))
+	}
+}
+
+// FormatExplanation returns the explanation as a tree, one line for
+// each part of the score indented under the part it is used in. Parts
+// with one child of the same value are left out, the child says more.
+func FormatExplanation(e *quicknote.Explanation, depth int) string {
+	var buf bytes.Buffer
+	formatExplanation(&buf, e, depth)
+	return buf.String()
+}
+
+func formatExplanation(buf *bytes.Buffer, e *quicknote.Explanation, depth int) {
+	for len(e.Children) == 1 && e.Children[0].Value == e.Value {
+		e = e.Children[0]
+	}
+	fmt.Fprintf(buf, "%s%.4f %s\n", strings.Repeat("    ", depth), e.Value, e.Message)
+	for _, child := range e.Children {
+		formatExplanation(buf, child, depth+1)
+	}
+}
+
+// PrintComparison prints the ranked note IDs of a search in each index
+// side by side, ranks that are not the same in all of them are marked
+// with a *. The notes only found by some of the indexes are listed under.
+func PrintComparison(compared []*quicknote.ComparedResult) {
+	if len(compared) == 0 {
+		return
+	}
+
+	fmt.Print(FgCyan(fmt.Sprintf("%-6s", "Rank")))
+	for i, c := range compared {
+		name := c.Name
+		if i == 0 {
+			name += " (primary)"
+		}
+		fmt.Print(FgCyan(fmt.Sprintf("%-20s", name)))
+	}
+	fmt.Println()
+
+	ranks, same := 0, 0
+	for _, c := range compared {
+		if len(c.Result.Hits) > ranks {
+			ranks = len(c.Result.Hits)
+		}
+	}
+	for rank := 0; rank < ranks; rank++ {
+		ids := make([]string, len(compared))
+		for i, c := range compared {
+			if rank < len(c.Result.Hits) {
+				ids[i] = strconv.FormatInt(c.Result.Hits[rank].ID, 10)
+			} else {
+				ids[i] = "-"
+			}
+		}
+
+		fmt.Printf("%-6d", rank+1)
+		for _, id := range ids {
+			fmt.Printf("%-20s", id)
+		}
+		if sameStrings(ids) {
+			same++
+			fmt.Println()
+		} else {
+			fmt.Println(FgRed("*"))
+		}
+	}
+
+	fmt.Println()
+	for _, c := range compared {
+		fmt.Printf("%s: %d results", c.Name, c.Result.Total)
+		var others []int64
+		for _, o := range compared {
+			if o != c {
+				others = append(others, o.Result.IDs()...)
+			}
+		}
+		if only := missingIDs(c.Result.IDs(), others); len(compared) > 1 && len(only) > 0 {
+			fmt.Printf(", only in %s: %s", c.Name, FgMagenta(fmt.Sprint(only)))
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%d of %d ranks are the same\n", same, ranks)
+}
+
+// sameStrings returns true if all of s are the same
+func sameStrings(s []string) bool {
+	for _, v := range s {
+		if v != s[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// missingIDs returns the IDs in ids that are not in others
+func missingIDs(ids, others []int64) []int64 {
+	found := make(map[int64]bool, len(others))
+	for _, id := range others {
+		found[id] = true
+	}
+
+	missing := make([]int64, 0)
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// PrintFacets prints the term counts of each facet on a line
+func PrintFacets(facets []*quicknote.Facet) {
+	for idx, facet := range facets {
+		if idx == 0 {
+			fmt.Println()
+		}
+
+		fmt.Print(FgCyan(fmt.Sprintf("%s: ", strings.Title(facet.Field))))
+		if len(facet.Terms) == 0 {
+			fmt.Println("-")
+			continue
+		}
+
+		terms := make([]string, len(facet.Terms))
+		for i, t := range facet.Terms {
+			terms[i] = fmt.Sprintf("%s (%s)", FgBlue(t.Term), FgMagenta(t.Count))
+		}
+		fmt.Println(strings.Join(terms, ", "))
+	}
+}
+
+// PrintSuggestions prints the "did you mean" queries on a line
+func PrintSuggestions(suggestions []string) {
+	if len(suggestions) == 0 {
+		return
+	}
+
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = FgBlue(fmt.Sprintf("%q", s))
+	}
+	fmt.Print(FgCyan("Did you mean: "))
+	fmt.Println(strings.Join(quoted, ", "))
+}
+
+// HighlightFragment colors the matched terms of a search fragment
+// and puts it on a single line
+func HighlightFragment(frag string) string {
+	var out string
+	for {
+		start := strings.Index(frag, quicknote.HighlightStart)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(frag[start:], quicknote.HighlightEnd)
+		if end < 0 {
+			break
+		}
+		end += start
+
+		out += frag[:start] + FgMatch(frag[start+len(quicknote.HighlightStart):end])
+		frag = frag[end+len(quicknote.HighlightEnd):]
+	}
+	out += frag
+
+	return strings.Join(strings.Fields(out), " ")
+}
+
+// hitFragments returns the fragments of field that have a
+// matched term, providers may return the start of a field
+// that did not match
+func hitFragments(hit *quicknote.SearchHit, field string) []string {
+	if hit == nil {
+		return nil
+	}
+
+	frags := make([]string, 0, len(hit.Fragments[field]))
+	for _, f := range hit.Fragments[field] {
+		if strings.Contains(f, quicknote.HighlightStart) {
+			frags = append(frags, f)
+		}
+	}
+	return frags
+}
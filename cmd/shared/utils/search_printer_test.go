@@ -0,0 +1,73 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/anmil/quicknote"
+
+	"github.com/fatih/color"
+)
+
+func TestHighlightFragmentUnit(t *testing.T) {
+	color.NoColor = true
+	defer func() { color.NoColor = false }()
+
+	frag := "first <mark>match</mark>\n  and <mark>second</mark> one"
+	if s := HighlightFragment(frag); s != "first match and second one" {
+		t.Errorf("Expected \"first match and second one\", got %q", s)
+	}
+
+	frag = "unclosed <mark>match"
+	if s := HighlightFragment(frag); s != frag {
+		t.Errorf("Expected %q, got %q", frag, s)
+	}
+}
+
+func TestFormatExplanationUnit(t *testing.T) {
+	e := &quicknote.Explanation{Value: 1.5, Message: "sum of:", Children: []*quicknote.Explanation{
+		{Value: 1, Message: "weight(title:apples)", Children: []*quicknote.Explanation{
+			{Value: 2, Message: "boost"},
+		}},
+		{Value: 0.5, Message: "product of:", Children: []*quicknote.Explanation{
+			{Value: 0.5, Message: "weight(body:apples)"},
+		}},
+	}}
+
+	expected := "1.5000 sum of:\n" +
+		"    1.0000 weight(title:apples)\n" +
+		"        2.0000 boost\n" +
+		"    0.5000 weight(body:apples)\n"
+	if s := FormatExplanation(e, 0); s != expected {
+		t.Errorf("Expected %q, got %q", expected, s)
+	}
+}
+
+func TestMissingIDsUnit(t *testing.T) {
+	if ids := missingIDs([]int64{1, 2, 3, 4}, []int64{4, 2, 5}); fmt.Sprint(ids) != "[1 3]" {
+		t.Errorf("Expected [1 3], got %v", ids)
+	}
+	if ids := missingIDs([]int64{1}, nil); fmt.Sprint(ids) != "[1]" {
+		t.Errorf("Expected [1], got %v", ids)
+	}
+	if ids := missingIDs([]int64{1}, []int64{1}); len(ids) != 0 {
+		t.Errorf("Expected no IDs, got %v", ids)
+	}
+}
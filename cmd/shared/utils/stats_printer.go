@@ -0,0 +1,174 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/anmil/quicknote"
+)
+
+// PrintStats prints the Stats in the given format. The
+// formats are the same as PrintNotes, ids prints as text.
+func PrintStats(stats *quicknote.Stats, format string) error {
+	var err error
+	switch format {
+	case "ids", "text", "short":
+		PrintStatsColored(stats)
+	case "csv":
+		err = PrintStatsCSV(stats)
+	case "json":
+		err = PrintStatsJSON(stats)
+	}
+	return err
+}
+
+// PrintStatsColored prints the Stats to stdout in color
+func PrintStatsColored(stats *quicknote.Stats) {
+	if stats.Book != "" {
+		fmt.Print(FgCyan("Book: "))
+		fmt.Println(stats.Book)
+	}
+
+	fmt.Print(FgCyan("Notes: "))
+	fmt.Print(stats.Length.Notes)
+	fmt.Print(FgCyan(" Avg Title Length: "))
+	fmt.Printf("%.1f", stats.Length.AvgTitle)
+	fmt.Print(FgCyan(" Avg Body Length: "))
+	fmt.Printf("%.1f\n", stats.Length.AvgBody)
+
+	fmt.Print(FgCyan("Stale Notes: "))
+	fmt.Printf("%d (not modified since %s)\n", stats.Stale, stats.StaleBefore.Format("2006-01-02"))
+
+	printCountStatsColored("Notes per Book", stats.Books)
+	printCountStatsColored("Notes per Type", stats.Types)
+	printCountStatsColored("Top Tags", stats.Tags)
+
+	printStatsHeader("Tag Pairs")
+	for _, p := range stats.TagPairs {
+		fmt.Printf("%s + %s: %d\n", FgBlue(p.Tag1), FgBlue(p.Tag2), p.Count)
+	}
+
+	printStatsHeader("Notes per Week")
+	for _, w := range stats.Weeks {
+		fmt.Print(w.Week)
+		fmt.Print(FgCyan(" Created: "))
+		fmt.Print(w.Created)
+		fmt.Print(FgCyan(" Modified: "))
+		fmt.Println(w.Modified)
+	}
+}
+
+func printStatsHeader(title string) {
+	fmt.Println()
+	fmt.Println(FgCyan(title))
+	fmt.Println(FgMagenta("--------------------------------------------------"))
+}
+
+func printCountStatsColored(title string, stats quicknote.CountStats) {
+	printStatsHeader(title)
+	for _, s := range stats {
+		fmt.Printf("%s: %d\n", s.Name, s.Count)
+	}
+}
+
+// PrintStatsCSV prints Stats in csv format, one row per value
+func PrintStatsCSV(stats *quicknote.Stats) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"stat", "name", "value"})
+
+	rows := [][]string{
+		{"notes", stats.Book, strconv.FormatInt(stats.Length.Notes, 10)},
+		{"avg_title_length", stats.Book, strconv.FormatFloat(stats.Length.AvgTitle, 'f', 1, 64)},
+		{"avg_body_length", stats.Book, strconv.FormatFloat(stats.Length.AvgBody, 'f', 1, 64)},
+		{"stale", stats.StaleBefore.Format("2006-01-02"), strconv.FormatInt(stats.Stale, 10)},
+	}
+	for _, s := range stats.Books {
+		rows = append(rows, []string{"book", s.Name, strconv.FormatInt(s.Count, 10)})
+	}
+	for _, s := range stats.Types {
+		rows = append(rows, []string{"type", s.Name, strconv.FormatInt(s.Count, 10)})
+	}
+	for _, s := range stats.Tags {
+		rows = append(rows, []string{"tag", s.Name, strconv.FormatInt(s.Count, 10)})
+	}
+	for _, p := range stats.TagPairs {
+		rows = append(rows, []string{"tag_pair", p.Tag1 + ", " + p.Tag2, strconv.FormatInt(p.Count, 10)})
+	}
+	for _, wk := range stats.Weeks {
+		rows = append(rows, []string{"week_created", wk.Week, strconv.FormatInt(wk.Created, 10)})
+		rows = append(rows, []string{"week_modified", wk.Week, strconv.FormatInt(wk.Modified, 10)})
+	}
+
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	err := w.Error()
+	return err
+}
+
+// PrintStatsJSON prints Stats in json format
+func PrintStatsJSON(stats *quicknote.Stats) error {
+	b, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// PrintCountStats prints the names and counts in the given
+// format. The formats are the same as PrintNotes, ids prints
+// only the names.
+func PrintCountStats(stats quicknote.CountStats, format string) error {
+	switch format {
+	case "ids":
+		for _, s := range stats {
+			fmt.Println(s.Name)
+		}
+	case "text", "short":
+		for _, s := range stats {
+			fmt.Printf("%s: %d\n", FgBlue(s.Name), s.Count)
+		}
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"name", "count"})
+		for _, s := range stats {
+			if err := w.Write([]string{s.Name, strconv.FormatInt(s.Count, 10)}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	case "json":
+		b, err := json.Marshal(stats)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	}
+	return nil
+}
@@ -17,6 +17,8 @@
 
 package quicknote
 
+import "time"
+
 // DB interface for the database providers
 type DB interface {
 	GetAllNotes(sortBy, order string) (Notes, error)
@@ -24,9 +26,14 @@ type DB interface {
 	GetNoteByID(id int64) (*Note, error)
 	GetNoteByNote(n *Note) error
 	GetNotesByIDs(ids []int64) (Notes, error)
+	GetNotesModifiedSince(bk *Book, since time.Time, afterID int64) (Notes, error)
+	GetAllNoteIDs(bk *Book) ([]int64, error)
 	CreateNote(n *Note) error
 	EditNote(n *Note) error
+	EditNotes(notes Notes) error
 	DeleteNote(n *Note) error
+	InsertNote(n *Note) error
+	CountNotes() (int64, error)
 
 	GetAllBooks() (Books, error)
 	GetOrCreateBookByName(name string) (*Book, error)
@@ -37,6 +44,8 @@ type DB interface {
 	EditBook(b1 *Book) error
 	LoadBook(b *Book) error
 	DeleteBook(bk *Book) error
+	InsertBook(b *Book) error
+	CountBooks() (int64, error)
 
 	GetAllBookTags(bk *Book) (Tags, error)
 	GetAllTags() (Tags, error)
@@ -44,6 +53,26 @@ type DB interface {
 	LoadNoteTags(n *Note) error
 	GetOrCreateTagByName(name string) (*Tag, error)
 	GetTagByName(name string) (*Tag, error)
+	InsertTag(t *Tag) error
+	CountTags() (int64, error)
+
+	GetPeople(bk *Book) (CountStats, error)
+	GetAllMentionNotes(bk *Book, name, sortBy, order string) (Notes, error)
+	LoadNotePeople(n *Note) error
+
+	GetAllSavedSearches() (SavedSearches, error)
+	GetSavedSearchByName(name string) (*SavedSearch, error)
+	SaveSearch(s *SavedSearch) error
+	DeleteSavedSearch(s *SavedSearch) error
+
+	GetNoteCountsByBook() (CountStats, error)
+	GetNoteCountsByType(bk *Book) (CountStats, error)
+	GetTopTags(bk *Book, limit int) (CountStats, error)
+	GetTagPairs(bk *Book, limit int) (TagPairStats, error)
+	GetNoteCountsByWeek(bk *Book, since time.Time) (WeekStats, error)
+	GetNoteLengthStats(bk *Book) (*LengthStat, error)
+	CountStaleNotes(bk *Book, before time.Time) (int64, error)
+	GetStaleNotes(bk *Book, before time.Time, sortBy, order string) (Notes, error)
 
 	Close() error
 }
@@ -0,0 +1,144 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/anmil/quicknote"
+)
+
+// ErrTargetNotEmpty the database being copied to already has data
+var ErrTargetNotEmpty = errors.New("Target database is not empty")
+
+// Counts holds the number of Books, Tags, and Notes in a database
+type Counts struct {
+	Books int64
+	Tags  int64
+	Notes int64
+}
+
+func (c *Counts) String() string {
+	return fmt.Sprintf("<Counts Books: %d Tags: %d Notes: %d>", c.Books, c.Tags, c.Notes)
+}
+
+// IsEmpty returns true if there are no Books, Tags, or Notes
+func (c *Counts) IsEmpty() bool {
+	return c.Books == 0 && c.Tags == 0 && c.Notes == 0
+}
+
+// GetCounts returns the number of Books, Tags, and Notes in d
+func GetCounts(d quicknote.DB) (*Counts, error) {
+	var err error
+	c := &Counts{}
+
+	if c.Books, err = d.CountBooks(); err != nil {
+		return nil, err
+	}
+	if c.Tags, err = d.CountTags(); err != nil {
+		return nil, err
+	}
+	if c.Notes, err = d.CountNotes(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Copy copies all Books, Tags, Notes, and saved searches from src to dst keeping their IDs,
+// Created, and Modified. dst must be empty. Notes are copied one Book at a
+// time so the whole database is never loaded at once. progress, if not nil,
+// is called after each Book with the number of Notes copied so far.
+func Copy(src, dst quicknote.DB, progress func(bk *quicknote.Book, copied int64)) error {
+	c, err := GetCounts(dst)
+	if err != nil {
+		return err
+	} else if !c.IsEmpty() {
+		return ErrTargetNotEmpty
+	}
+
+	books, err := src.GetAllBooks()
+	if err != nil {
+		return err
+	}
+	for _, bk := range books {
+		if err = dst.InsertBook(bk); err != nil {
+			return err
+		}
+	}
+
+	tags, err := src.GetAllTags()
+	if err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if err = dst.InsertTag(t); err != nil {
+			return err
+		}
+	}
+
+	var copied int64
+	for _, bk := range books {
+		notes, err := src.GetAllBookNotes(bk, "id", "asc")
+		if err != nil {
+			return err
+		}
+
+		for _, n := range notes {
+			if err = dst.InsertNote(n); err != nil {
+				return err
+			}
+			copied++
+		}
+
+		if progress != nil {
+			progress(bk, copied)
+		}
+	}
+
+	searches, err := src.GetAllSavedSearches()
+	if err != nil {
+		return err
+	}
+	for _, s := range searches {
+		if err = dst.SaveSearch(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Verify compares the number of Books, Tags, and Notes in src and dst
+// and returns an error if they do not match.
+func Verify(src, dst quicknote.DB) error {
+	sc, err := GetCounts(src)
+	if err != nil {
+		return err
+	}
+
+	dc, err := GetCounts(dst)
+	if err != nil {
+		return err
+	}
+
+	if *sc != *dc {
+		return fmt.Errorf("Count mismatch, source %s target %s", sc, dc)
+	}
+	return nil
+}
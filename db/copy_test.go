@@ -0,0 +1,104 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/test"
+)
+
+func openCopyDatabase(t *testing.T, name string) quicknote.DB {
+	d, err := NewDatabase("sqlite", "file:"+name+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestCopyUnit(t *testing.T) {
+	src := openCopyDatabase(t, "copysrc")
+	defer src.Close()
+	dst := openCopyDatabase(t, "copydst")
+	defer dst.Close()
+
+	notes := test.GetTestNotes()
+	for _, n := range notes {
+		bk, err := src.GetOrCreateBookByName(n.Book.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n.Book = bk
+
+		for i, tag := range n.Tags {
+			if n.Tags[i], err = src.GetOrCreateTagByName(tag.Name); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err = src.CreateNote(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Remove a note so the IDs have a gap
+	if err := src.DeleteNote(notes[0]); err != nil {
+		t.Fatal(err)
+	}
+	notes = notes[1:]
+
+	search := &quicknote.SavedSearch{Name: "quis", Query: "tag:quis"}
+	if err := src.SaveSearch(search); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Copy(src, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Verify(src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range notes {
+		nn, err := dst.GetNoteByID(n.ID)
+		if err != nil {
+			t.Fatal(err)
+		} else if nn == nil {
+			t.Fatalf("Note %d was not copied", n.ID)
+		}
+
+		if !nn.Created.Equal(n.Created) || !nn.Modified.Equal(n.Modified) {
+			t.Fatalf("Note %d timestamps were not kept", n.ID)
+		} else if nn.Book.Name != n.Book.Name {
+			t.Fatalf("Note %d expected Book %s, got %s", n.ID, n.Book.Name, nn.Book.Name)
+		}
+		test.CheckTags(t, nn.Tags, n.Tags)
+	}
+
+	if s, err := dst.GetSavedSearchByName(search.Name); err != nil {
+		t.Fatal(err)
+	} else if s == nil || s.Query != search.Query {
+		t.Fatalf("Saved search %s was not copied", search.Name)
+	}
+
+	if err := Copy(src, dst, nil); err != ErrTargetNotEmpty {
+		t.Fatal("Expected ErrTargetNotEmpty")
+	}
+}
@@ -130,6 +130,57 @@ func (d *Database) CreateBook(b *quicknote.Book) error {
 	return nil
 }
 
+// getOrCreateBookTx sets the ID of b to the ID of the Book with its
+// name, creating the Book in tx if it does not exist
+func (d *Database) getOrCreateBookTx(b *quicknote.Book, tx *sql.Tx) error {
+	stmt, err := tx.Prepare("SELECT id FROM books WHERE name = $1;")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(b.Name).Scan(&b.ID)
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	insStmt, err := tx.Prepare("INSERT INTO books (created, modified, name) VALUES ($1,$2,$3) RETURNING id;")
+	if err != nil {
+		return err
+	}
+	defer insStmt.Close()
+
+	return insStmt.QueryRow(b.Created, b.Modified, b.Name).Scan(&b.ID)
+}
+
+// InsertBook saves the Book to the database keeping its ID, Created, and Modified
+func (d *Database) InsertBook(b *quicknote.Book) error {
+	sqlStr := "INSERT INTO books (id, created, modified, name) VALUES ($1,$2,$3,$4);"
+
+	tx, stmt, err := d.getTxStmt(sqlStr)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(b.ID, b.Created, b.Modified, b.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = d.syncSequence(tx, "books"); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CountBooks returns the number of Books
+func (d *Database) CountBooks() (int64, error) {
+	return d.countRows("books")
+}
+
 // MergeBooks merge all notes from Book b1 into Book b2
 func (d *Database) MergeBooks(b1 *quicknote.Book, b2 *quicknote.Book) error {
 	tx, err := d.db.Begin()
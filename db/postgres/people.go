@@ -0,0 +1,103 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/anmil/quicknote"
+)
+
+// GetPeople returns the people mentioned in the Book bk, or all
+// Books if bk is nil, with the number of notes mentioning them
+func (d *Database) GetPeople(bk *quicknote.Book) (quicknote.CountStats, error) {
+	sqlStr := "SELECT p.name, COUNT(*) AS cnt FROM people AS p " +
+		"JOIN notes AS n ON n.id = p.note_id " +
+		"WHERE ($1 = 0 OR n.bk_id = $1) " +
+		"GROUP BY p.name ORDER BY cnt DESC, p.name ASC;"
+
+	rows, err := d.db.Query(sqlStr, optionalBookID(bk))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return loadCountStatsFromRows(rows)
+}
+
+// GetAllMentionNotes returns the notes in the Book bk, or
+// all Books if bk is nil, that mention the person name
+func (d *Database) GetAllMentionNotes(bk *quicknote.Book, name, sortBy, order string) (quicknote.Notes, error) {
+	sqlStr := "SELECT id, created, modified, bk_id, type, title, body FROM notes " +
+		"WHERE ($1 = 0 OR bk_id = $1) AND id IN (SELECT note_id FROM people WHERE name = $2) ORDER BY %s %s;"
+
+	// See GetAllBookNotes for why I'm doing this
+	query := fmt.Sprintf(sqlStr, sortBy, order)
+
+	rows, err := d.db.Query(query, optionalBookID(bk), name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return d.loadNotesFromRows(rows)
+}
+
+// LoadNotePeople loads the people mentioned in the given Note
+func (d *Database) LoadNotePeople(n *quicknote.Note) error {
+	sqlStr := "SELECT name FROM people WHERE note_id = $1 ORDER BY name;"
+
+	rows, err := d.db.Query(sqlStr, n.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	n.People = make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		n.People = append(n.People, name)
+	}
+	return rows.Err()
+}
+
+func (d *Database) createPeopleRel(n *quicknote.Note, tx *sql.Tx) error {
+	sqlStr := "INSERT INTO people (note_id, name) VALUES ($1,$2) ON CONFLICT DO NOTHING;"
+
+	stmt, err := tx.Prepare(sqlStr)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, name := range n.People {
+		if _, err = stmt.Exec(n.ID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Database) deletePeopleRel(n *quicknote.Note, tx *sql.Tx) error {
+	_, err := tx.Exec("DELETE FROM people WHERE note_id = $1;", n.ID)
+	return err
+}
@@ -0,0 +1,80 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/anmil/quicknote/test"
+)
+
+func TestPeoplePostgresIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestPeoplePostgresIntegration in short mode")
+	}
+
+	db := openDatabase(t)
+	defer closeDatabase(db, t)
+
+	notes := test.GetTestNotes()
+	notes[0].People = []string{"alice", "bob"}
+	notes[1].People = []string{"alice"}
+	saveNotes(t, db, notes)
+	bk := notes[0].Book
+
+	if stats, err := db.GetPeople(bk); err != nil {
+		t.Fatal(err)
+	} else if len(stats) != 2 || stats[0].Name != "alice" || stats[0].Count != 2 ||
+		stats[1].Name != "bob" || stats[1].Count != 1 {
+		t.Fatalf("Unexpected people %v", stats)
+	}
+
+	if nn, err := db.GetAllMentionNotes(nil, "alice", "id", "asc"); err != nil {
+		t.Fatal(err)
+	} else if len(nn) != 2 || nn[0].ID != notes[0].ID || nn[1].ID != notes[1].ID {
+		t.Fatalf("Unexpected notes mentioning alice %v", nn)
+	} else if !reflect.DeepEqual(nn[0].People, notes[0].People) {
+		t.Fatalf("Expected people %v, got %v", notes[0].People, nn[0].People)
+	}
+
+	n, err := db.GetNoteByID(notes[2].ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(n.People) != 0 {
+		t.Fatalf("Expected no people, got %v", n.People)
+	}
+
+	notes[0].People = []string{"carol"}
+	if err := db.EditNote(notes[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	notes[0].People = nil
+	if err := db.LoadNotePeople(notes[0]); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(notes[0].People, []string{"carol"}) {
+		t.Fatalf("Expected people [carol], got %v", notes[0].People)
+	}
+
+	if nn, err := db.GetAllMentionNotes(bk, "bob", "id", "asc"); err != nil {
+		t.Fatal(err)
+	} else if len(nn) != 0 {
+		t.Fatalf("Expected no notes mentioning bob, got %v", nn)
+	}
+}
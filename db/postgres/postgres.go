@@ -24,6 +24,8 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/anmil/quicknote"
+
 	// pq must be imported for initialization
 	_ "github.com/lib/pq"
 )
@@ -67,10 +69,34 @@ CREATE TABLE IF NOT EXISTS note_book_tag (
 	bk_id   INTEGER REFERENCES books(id) ON DELETE CASCADE,
 	tag_id  INTEGER REFERENCES tags(id) ON DELETE CASCADE,
 	PRIMARY KEY (note_id, bk_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS people (
+	note_id INTEGER REFERENCES notes(id) ON DELETE CASCADE,
+	name    TEXT    NOT NULL,
+	PRIMARY KEY (note_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_people_name ON people (name);
+
+CREATE TABLE IF NOT EXISTS due_dates (
+	note_id INTEGER PRIMARY KEY REFERENCES notes(id) ON DELETE CASCADE,
+	due     TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS saved_searches (
+	id       SERIAL   PRIMARY KEY,
+	created  TIMESTAMPTZ NOT NULL,
+	modified TIMESTAMPTZ NOT NULL,
+	name     TEXT UNIQUE,
+	query    TEXT      NOT NULL
 );`
 
 var dropAllTables = `
 DROP INDEX IF EXISTS idx_notes_bk_id;
+DROP TABLE IF EXISTS saved_searches;
+DROP TABLE IF EXISTS due_dates;
+DROP TABLE IF EXISTS people;
 DROP TABLE IF EXISTS note_book_tag;
 DROP TABLE IF EXISTS note_tag;
 DROP TABLE IF EXISTS tags;
@@ -167,6 +193,32 @@ func (d *Database) ResetTables() error {
 	return nil
 }
 
+// optionalBookID returns the ID used to limit a query
+// to the Book bk, 0 if bk is nil (all Books).
+func optionalBookID(bk *quicknote.Book) int64 {
+	if bk == nil {
+		return 0
+	}
+	return bk.ID
+}
+
+// countRows returns the number of rows in table, table must
+// never come from user input.
+func (d *Database) countRows(table string) (int64, error) {
+	var cnt int64
+	err := d.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s;", table)).Scan(&cnt)
+	return cnt, err
+}
+
+// syncSequence moves table's id sequence past the highest id. This must
+// be called after inserting rows with an explicit id, otherwise the next
+// SERIAL id could collide with them.
+func (d *Database) syncSequence(tx *sql.Tx, table string) error {
+	sqlStr := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), (SELECT MAX(id) FROM %s));", table, table)
+	_, err := tx.Exec(sqlStr)
+	return err
+}
+
 // splitSliceToChuck slice s into chucks containing the maximum number of
 // objects we can use in a statement.
 //
@@ -27,9 +27,12 @@ import (
 
 var tableNames = []string{
 	"books",
+	"due_dates",
 	"note_book_tag",
 	"note_tag",
 	"notes",
+	"people",
+	"saved_searches",
 	"tags",
 }
 
@@ -0,0 +1,185 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/anmil/quicknote"
+)
+
+// GetNoteCountsByBook returns the number of Notes in each Book
+func (d *Database) GetNoteCountsByBook() (quicknote.CountStats, error) {
+	sqlStr := "SELECT b.name, COUNT(n.id) AS cnt FROM books AS b " +
+		"LEFT JOIN notes AS n ON n.bk_id = b.id " +
+		"GROUP BY b.name ORDER BY cnt DESC, b.name ASC;"
+
+	rows, err := d.db.Query(sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return loadCountStatsFromRows(rows)
+}
+
+// GetNoteCountsByType returns the number of Notes of each type in the
+// Book bk, or all Books if bk is nil
+func (d *Database) GetNoteCountsByType(bk *quicknote.Book) (quicknote.CountStats, error) {
+	sqlStr := "SELECT type, COUNT(*) FROM notes WHERE ($1 = 0 OR bk_id = $1) " +
+		"GROUP BY type ORDER BY type ASC;"
+
+	rows, err := d.db.Query(sqlStr, optionalBookID(bk))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return loadCountStatsFromRows(rows)
+}
+
+// GetTopTags returns the limit most used Tags in the
+// Book bk, or all Books if bk is nil
+func (d *Database) GetTopTags(bk *quicknote.Book, limit int) (quicknote.CountStats, error) {
+	sqlStr := "SELECT t.name, COUNT(*) AS cnt FROM note_tag AS nt " +
+		"JOIN tags AS t ON t.id = nt.tag_id " +
+		"JOIN notes AS n ON n.id = nt.note_id " +
+		"WHERE ($1 = 0 OR n.bk_id = $1) " +
+		"GROUP BY t.name ORDER BY cnt DESC, t.name ASC LIMIT $2;"
+
+	rows, err := d.db.Query(sqlStr, optionalBookID(bk), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return loadCountStatsFromRows(rows)
+}
+
+// GetTagPairs returns the limit pairs of Tags that are most often used on
+// the same Note in the Book bk, or all Books if bk is nil
+func (d *Database) GetTagPairs(bk *quicknote.Book, limit int) (quicknote.TagPairStats, error) {
+	sqlStr := "SELECT t1.name, t2.name, COUNT(*) AS cnt FROM note_tag AS a " +
+		"JOIN note_tag AS b ON b.note_id = a.note_id AND a.tag_id < b.tag_id " +
+		"JOIN tags AS t1 ON t1.id = a.tag_id " +
+		"JOIN tags AS t2 ON t2.id = b.tag_id " +
+		"JOIN notes AS n ON n.id = a.note_id " +
+		"WHERE ($1 = 0 OR n.bk_id = $1) " +
+		"GROUP BY t1.name, t2.name ORDER BY cnt DESC, t1.name ASC, t2.name ASC LIMIT $2;"
+
+	rows, err := d.db.Query(sqlStr, optionalBookID(bk), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pairs := make(quicknote.TagPairStats, 0)
+	for rows.Next() {
+		p := &quicknote.TagPairStat{}
+		if err := rows.Scan(&p.Tag1, &p.Tag2, &p.Count); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+// GetNoteCountsByWeek returns the number of Notes created and modified each
+// week since the given time in the Book bk, or all Books if bk is nil.
+// Weeks start on Monday.
+func (d *Database) GetNoteCountsByWeek(bk *quicknote.Book, since time.Time) (quicknote.WeekStats, error) {
+	sqlStr := "SELECT week, SUM(c), SUM(m) FROM (" +
+		"SELECT to_char(date_trunc('week', created), 'YYYY-MM-DD') AS week, 1 AS c, 0 AS m FROM notes " +
+		"WHERE ($1 = 0 OR bk_id = $1) AND created >= $2 " +
+		"UNION ALL " +
+		"SELECT to_char(date_trunc('week', modified), 'YYYY-MM-DD') AS week, 0 AS c, 1 AS m FROM notes " +
+		"WHERE ($1 = 0 OR bk_id = $1) AND modified >= $2" +
+		") AS weeks GROUP BY week ORDER BY week ASC;"
+
+	rows, err := d.db.Query(sqlStr, optionalBookID(bk), since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	weeks := make(quicknote.WeekStats, 0)
+	for rows.Next() {
+		w := &quicknote.WeekStat{}
+		if err := rows.Scan(&w.Week, &w.Created, &w.Modified); err != nil {
+			return nil, err
+		}
+		weeks = append(weeks, w)
+	}
+	return weeks, rows.Err()
+}
+
+// GetNoteLengthStats returns the average Title and Body length of the
+// Notes in the Book bk, or all Books if bk is nil
+func (d *Database) GetNoteLengthStats(bk *quicknote.Book) (*quicknote.LengthStat, error) {
+	sqlStr := "SELECT COUNT(*), COALESCE(AVG(LENGTH(title)), 0)::float8, COALESCE(AVG(LENGTH(body)), 0)::float8 " +
+		"FROM notes WHERE ($1 = 0 OR bk_id = $1);"
+
+	l := &quicknote.LengthStat{}
+	err := d.db.QueryRow(sqlStr, optionalBookID(bk)).Scan(&l.Notes, &l.AvgTitle, &l.AvgBody)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// CountStaleNotes returns the number of Notes not modified since before
+// in the Book bk, or all Books if bk is nil
+func (d *Database) CountStaleNotes(bk *quicknote.Book, before time.Time) (int64, error) {
+	sqlStr := "SELECT COUNT(*) FROM notes WHERE ($1 = 0 OR bk_id = $1) AND modified < $2;"
+
+	var cnt int64
+	err := d.db.QueryRow(sqlStr, optionalBookID(bk), before).Scan(&cnt)
+	return cnt, err
+}
+
+// GetStaleNotes returns the Notes not modified since before
+// in the Book bk, or all Books if bk is nil
+func (d *Database) GetStaleNotes(bk *quicknote.Book, before time.Time, sortBy, order string) (quicknote.Notes, error) {
+	sqlStr := "SELECT id, created, modified, bk_id, type, title, body FROM notes " +
+		"WHERE ($1 = 0 OR bk_id = $1) AND modified < $2 ORDER BY %s %s;"
+
+	// sortBy and order are checked against a list of accepted values, see GetAllBookNotes
+	query := fmt.Sprintf(sqlStr, sortBy, order)
+
+	rows, err := d.db.Query(query, optionalBookID(bk), before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return d.loadNotesFromRows(rows)
+}
+
+func loadCountStatsFromRows(rows *sql.Rows) (quicknote.CountStats, error) {
+	stats := make(quicknote.CountStats, 0)
+	for rows.Next() {
+		s := &quicknote.CountStat{}
+		if err := rows.Scan(&s.Name, &s.Count); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,95 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/test"
+)
+
+func TestStatsPostgresIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestStatsPostgresIntegration in short mode")
+	}
+
+	db := openDatabase(t)
+	defer closeDatabase(db, t)
+
+	notes := test.GetTestNotes()
+	saveNotes(t, db, notes)
+	bk := notes[0].Book
+
+	if stats, err := db.GetNoteCountsByBook(); err != nil {
+		t.Fatal(err)
+	} else if len(stats) != 1 || stats[0].Name != bk.Name || stats[0].Count != 3 {
+		t.Fatalf("Unexpected Book counts %v", stats)
+	}
+
+	if stats, err := db.GetNoteCountsByType(bk); err != nil {
+		t.Fatal(err)
+	} else if len(stats) != 1 || stats[0].Name != quicknote.Basic || stats[0].Count != 3 {
+		t.Fatalf("Unexpected type counts %v", stats)
+	}
+
+	if stats, err := db.GetTopTags(nil, 2); err != nil {
+		t.Fatal(err)
+	} else if len(stats) != 2 || stats[0].Name != "basic" || stats[1].Name != "parser" || stats[0].Count != 3 {
+		t.Fatalf("Unexpected top tags %v", stats)
+	}
+
+	if pairs, err := db.GetTagPairs(bk, 10); err != nil {
+		t.Fatal(err)
+	} else if len(pairs) != 6 || pairs[0].Count != 3 || pairs[5].Count != 1 {
+		t.Fatalf("Unexpected tag pairs %v", pairs)
+	}
+
+	since := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	if weeks, err := db.GetNoteCountsByWeek(nil, since); err != nil {
+		t.Fatal(err)
+	} else if len(weeks) != 1 || weeks[0].Week != "2017-03-20" || weeks[0].Created != 3 || weeks[0].Modified != 3 {
+		t.Fatalf("Unexpected week counts %v", weeks)
+	}
+
+	if l, err := db.GetNoteLengthStats(bk); err != nil {
+		t.Fatal(err)
+	} else if l.Notes != 3 || l.AvgTitle == 0 || l.AvgBody == 0 {
+		t.Fatalf("Unexpected length stats %v", l)
+	}
+
+	before := time.Date(2017, 4, 1, 0, 0, 0, 0, time.UTC)
+	if cnt, err := db.CountStaleNotes(nil, before); err != nil {
+		t.Fatal(err)
+	} else if cnt != 3 {
+		t.Fatalf("Expected 3 stale notes, got %d", cnt)
+	}
+
+	if cnt, err := db.CountStaleNotes(nil, since); err != nil {
+		t.Fatal(err)
+	} else if cnt != 0 {
+		t.Fatalf("Expected 0 stale notes, got %d", cnt)
+	}
+
+	if nn, err := db.GetStaleNotes(bk, before, "id", "asc"); err != nil {
+		t.Fatal(err)
+	} else {
+		test.CheckNotes(t, nn, notes)
+	}
+}
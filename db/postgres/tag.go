@@ -145,6 +145,57 @@ func (d *Database) CreateTag(t *quicknote.Tag) error {
 	return nil
 }
 
+// getOrCreateTagTx sets the ID of t to the ID of the tag with its
+// name, creating the tag in tx if it does not exist
+func (d *Database) getOrCreateTagTx(t *quicknote.Tag, tx *sql.Tx) error {
+	stmt, err := tx.Prepare("SELECT id FROM tags WHERE name = $1;")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(t.Name).Scan(&t.ID)
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	insStmt, err := tx.Prepare("INSERT INTO tags (created, modified, name) VALUES ($1,$2,$3) RETURNING id;")
+	if err != nil {
+		return err
+	}
+	defer insStmt.Close()
+
+	return insStmt.QueryRow(t.Created, t.Modified, t.Name).Scan(&t.ID)
+}
+
+// InsertTag saves the tag to the database keeping its ID, Created, and Modified
+func (d *Database) InsertTag(t *quicknote.Tag) error {
+	sqlStr := "INSERT INTO tags (id, created, modified, name) VALUES ($1,$2,$3,$4);"
+
+	tx, stmt, err := d.getTxStmt(sqlStr)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(t.ID, t.Created, t.Modified, t.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = d.syncSequence(tx, "tags"); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CountTags returns the number of tags
+func (d *Database) CountTags() (int64, error) {
+	return d.countRows("tags")
+}
+
 // GetTagsByName returns the Tag for the given name
 func (d *Database) GetTagsByName(name string) (*quicknote.Tag, error) {
 	return nil, nil
@@ -215,20 +266,20 @@ func (d *Database) createNoteBookTagRel(n *quicknote.Note, t *quicknote.Tag, tx
 	return nil
 }
 
-func (d *Database) deleteTagRal(n *quicknote.Note) error {
-	if err := d.deleteNoteTagsRel(n); err != nil {
+func (d *Database) deleteTagRal(n *quicknote.Note, tx *sql.Tx) error {
+	if err := d.deleteNoteTagsRel(n, tx); err != nil {
 		return err
 	}
-	if err := d.deleteNoteNookTagsRel(n); err != nil {
+	if err := d.deleteNoteNookTagsRel(n, tx); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *Database) deleteNoteTagsRel(n *quicknote.Note) error {
+func (d *Database) deleteNoteTagsRel(n *quicknote.Note, tx *sql.Tx) error {
 	sqlStr := "DELETE FROM note_tag WHERE note_id = $1"
 
-	stmt, err := d.db.Prepare(sqlStr)
+	stmt, err := tx.Prepare(sqlStr)
 	if err != nil {
 		return err
 	}
@@ -242,10 +293,10 @@ func (d *Database) deleteNoteTagsRel(n *quicknote.Note) error {
 	return nil
 }
 
-func (d *Database) deleteNoteNookTagsRel(n *quicknote.Note) error {
+func (d *Database) deleteNoteNookTagsRel(n *quicknote.Note, tx *sql.Tx) error {
 	sqlStr := "DELETE FROM note_book_tag WHERE note_id = $1"
 
-	stmt, err := d.db.Prepare(sqlStr)
+	stmt, err := tx.Prepare(sqlStr)
 	if err != nil {
 		return err
 	}
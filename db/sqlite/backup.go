@@ -0,0 +1,84 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// ErrNotSQLiteConn the driver connection is not a SQLite connection
+var ErrNotSQLiteConn = errors.New("Connection is not a SQLite connection")
+
+// Backup writes a consistent snapshot of the database to destPath using
+// SQLite's online backup API. Any existing database at destPath is
+// overwritten. The database stays usable while the backup is running.
+func (d *Database) Backup(destPath string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	ctx := context.Background()
+
+	destDB, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return err
+	}
+	defer destDB.Close()
+
+	destConn, err := destDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	srcConn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriverConn interface{}) error {
+		return srcConn.Raw(func(srcDriverConn interface{}) error {
+			dc, ok := destDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return ErrNotSQLiteConn
+			}
+			sc, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return ErrNotSQLiteConn
+			}
+
+			bk, err := dc.Backup("main", sc, "main")
+			if err != nil {
+				return err
+			}
+
+			// -1 copies all pages in a single step, which holds the read
+			// lock on the source for the whole copy so the snapshot is
+			// consistent.
+			if _, err = bk.Step(-1); err != nil {
+				bk.Finish()
+				return err
+			}
+			return bk.Finish()
+		})
+	})
+}
@@ -0,0 +1,54 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/anmil/quicknote/test"
+)
+
+func TestBackupSQLiteUnit(t *testing.T) {
+	db := openDatabase(t)
+	defer closeDatabase(db, t)
+
+	notes := test.GetTestNotes()
+	saveNotes(t, db, notes)
+
+	dir, err := ioutil.TempDir("", "qnote-sqlite-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp := path.Join(dir, "backup.db")
+	if err := db.Backup(fp); err != nil {
+		t.Fatal(err)
+	}
+
+	bdb, err := NewDatabase(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDatabase(bdb, t)
+
+	getNotesAll(t, bdb, notes)
+}
@@ -74,6 +74,10 @@ func (d *Database) GetOrCreateBookByName(name string) (*quicknote.Book, error) {
 			Name:     name,
 		}
 		err = d.CreateBook(bk)
+		if isUniqueViolation(err) {
+			// Created by another goroutine or process since the lookup
+			bk, err = d.GetBookByName(name)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -166,12 +170,71 @@ func (d *Database) CreateBook(b *quicknote.Book) error {
 	return nil
 }
 
+// getOrCreateBookTx sets the ID of b to the ID of the Book with its
+// name, creating the Book in tx if it does not exist
+func (d *Database) getOrCreateBookTx(b *quicknote.Book, tx *sql.Tx) error {
+	stmt, err := tx.Prepare("SELECT id FROM books WHERE name = ?;")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(b.Name).Scan(&b.ID)
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	insStmt, err := tx.Prepare("INSERT INTO books (created, modified, name) VALUES (?,?,?);")
+	if err != nil {
+		return err
+	}
+	defer insStmt.Close()
+
+	res, err := insStmt.Exec(b.Created, b.Modified, b.Name)
+	if err != nil {
+		return err
+	}
+
+	b.ID, err = res.LastInsertId()
+	return err
+}
+
+// InsertBook saves the Book to the database keeping its ID, Created, and Modified
+func (d *Database) InsertBook(b *quicknote.Book) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	sqlStr := "INSERT INTO books (id, created, modified, name) VALUES (?,?,?,?);"
+
+	tx, stmt, err := d.getTxStmt(sqlStr)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(b.ID, b.Created, b.Modified, b.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	d.addBookToCache(b)
+
+	return tx.Commit()
+}
+
+// CountBooks returns the number of Books
+func (d *Database) CountBooks() (int64, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.countRows("books")
+}
+
 // MergeBooks merge all notes from Book b1 into Book b2
 func (d *Database) MergeBooks(b1 *quicknote.Book, b2 *quicknote.Book) error {
 	d.mux.Lock()
 	defer d.mux.Unlock()
 
-	tx, err := d.db.Begin()
+	tx, err := d.begin()
 	if err != nil {
 		return err
 	}
@@ -269,18 +332,26 @@ func (d *Database) DeleteBook(bk *quicknote.Book) error {
 }
 
 func (d *Database) addBookToCache(bk *quicknote.Book) {
+	d.cacheMux.Lock()
+	defer d.cacheMux.Unlock()
 	d.bookNameCache[bk.Name] = bk
 }
 
 func (d *Database) delBookFromCache(bk *quicknote.Book) {
+	d.cacheMux.Lock()
+	defer d.cacheMux.Unlock()
 	delete(d.bookNameCache, bk.Name)
 }
 
 func (d *Database) delBookFromCacheS(name string) {
+	d.cacheMux.Lock()
+	defer d.cacheMux.Unlock()
 	delete(d.bookNameCache, name)
 }
 
 func (d *Database) getFromBookCache(name string) *quicknote.Book {
+	d.cacheMux.RLock()
+	defer d.cacheMux.RUnlock()
 	if bk, found := d.bookNameCache[name]; found {
 		return bk
 	}
@@ -0,0 +1,49 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/anmil/quicknote"
+)
+
+func (d *Database) loadNoteDue(n *quicknote.Note) error {
+	sqlStr := "SELECT due FROM due_dates WHERE note_id = ?;"
+
+	n.Due = time.Time{}
+	err := d.db.QueryRow(sqlStr, n.ID).Scan(&n.Due)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+// setNoteDue replaces the due date of the note, removing
+// it if the note has no due date
+func (d *Database) setNoteDue(n *quicknote.Note, tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM due_dates WHERE note_id = ?;", n.ID); err != nil {
+		return err
+	}
+	if n.Due.IsZero() {
+		return nil
+	}
+	_, err := tx.Exec("INSERT INTO due_dates (note_id, due) VALUES (?,?);", n.ID, n.Due)
+	return err
+}
@@ -21,6 +21,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/anmil/quicknote"
 )
@@ -52,6 +53,14 @@ func (d *Database) GetNoteByID(id int64) (*quicknote.Note, error) {
 		return nil, err
 	}
 
+	if err = d.loadNotePeople(n); err != nil {
+		return nil, err
+	}
+
+	if err = d.loadNoteDue(n); err != nil {
+		return nil, err
+	}
+
 	if err = d.loadBook(n.Book); err != nil {
 		return nil, err
 	}
@@ -81,6 +90,42 @@ func (d *Database) GetNoteByNote(n *quicknote.Note) error {
 	return err
 }
 
+// GetNotesModifiedSince returns the notes in the Book bk, or all Books if bk
+// is nil, that were modified at or after since or have an ID greater than afterID
+func (d *Database) GetNotesModifiedSince(bk *quicknote.Book, since time.Time, afterID int64) (quicknote.Notes, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	sqlStr := "SELECT id, created, modified, bk_id, type, title, body FROM notes " +
+		"WHERE (? = 0 OR bk_id = ?) AND (julianday(modified) >= julianday(?) OR id > ?) ORDER BY id ASC;"
+
+	bkID := optionalBookID(bk)
+	rows, err := d.db.Query(sqlStr, bkID, bkID, sqliteTime(since), afterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return d.loadNotesFromRows(rows)
+}
+
+// GetAllNoteIDs returns the IDs of all notes in the Book bk, or all Books if bk is nil
+func (d *Database) GetAllNoteIDs(bk *quicknote.Book) ([]int64, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	sqlStr := "SELECT id FROM notes WHERE (? = 0 OR bk_id = ?) ORDER BY id ASC;"
+
+	bkID := optionalBookID(bk)
+	rows, err := d.db.Query(sqlStr, bkID, bkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return loadIDsFromRows(rows)
+}
+
 // GetNotesByIDs returns all notes for the given Notebook
 func (d *Database) GetNotesByIDs(ids []int64) (quicknote.Notes, error) {
 	d.mux.Lock()
@@ -211,31 +256,75 @@ func (d *Database) CreateNote(n *quicknote.Note) error {
 		return err
 	}
 
+	if err = d.createPeopleRel(n, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = d.setNoteDue(n, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit()
 }
 
-// EditNote updates the note in the database
-func (d *Database) EditNote(n *quicknote.Note) error {
+// InsertNote saves the note to the database keeping its ID, Created, and Modified.
+// The note's Book and Tags must already exist with the same IDs.
+func (d *Database) InsertNote(n *quicknote.Note) error {
 	d.mux.Lock()
 	defer d.mux.Unlock()
 
-	sqlStr := "UPDATE notes SET modified = ?, title = ?, body = ? WHERE id = ?;"
+	sqlStr := "INSERT INTO notes (id, created, modified, bk_id, type, title, body) " +
+		"VALUES (?,?,?,?,?,?,?);"
 
 	tx, stmt, err := d.getTxStmt(sqlStr)
 	if err != nil {
 		return err
 	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(n.ID, n.Created, n.Modified, n.Book.ID, n.Type, n.Title, n.Body); err != nil {
+		tx.Rollback()
+		return err
+	}
 
-	if _, err = stmt.Exec(n.Modified, n.Title, n.Body, n.ID); err != nil {
+	if err = d.createTagRal(n, tx); err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	if err := d.deleteTagRal(n, tx); err != nil {
+	if err = d.createPeopleRel(n, tx); err != nil {
 		tx.Rollback()
 		return err
 	}
-	if err := d.createTagRal(n, tx); err != nil {
+
+	if err = d.setNoteDue(n, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CountNotes returns the number of notes
+func (d *Database) CountNotes() (int64, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.countRows("notes")
+}
+
+// EditNote updates the note in the database
+func (d *Database) EditNote(n *quicknote.Note) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	tx, err := d.begin()
+	if err != nil {
+		return err
+	}
+
+	if err := d.editNote(n, tx); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -243,6 +332,91 @@ func (d *Database) EditNote(n *quicknote.Note) error {
 	return tx.Commit()
 }
 
+// EditNotes updates all of the notes, including their Books, in one
+// transaction. Either all of the notes are updated or none are. Books
+// and Tags without an ID are found by name or created in the transaction.
+func (d *Database) EditNotes(notes quicknote.Notes) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	tx, err := d.begin()
+	if err != nil {
+		return err
+	}
+
+	// The IDs set in the transaction are cleared if it fails
+	ids := make([]*int64, 0)
+	rollback := func(err error) error {
+		tx.Rollback()
+		for _, id := range ids {
+			*id = 0
+		}
+		return err
+	}
+
+	for _, n := range notes {
+		if n.Book.ID == 0 {
+			if err := d.getOrCreateBookTx(n.Book, tx); err != nil {
+				return rollback(err)
+			}
+			ids = append(ids, &n.Book.ID)
+		}
+		for _, t := range n.Tags {
+			if t.ID == 0 {
+				if err := d.getOrCreateTagTx(t, tx); err != nil {
+					return rollback(err)
+				}
+				ids = append(ids, &t.ID)
+			}
+		}
+
+		if err := d.editNote(n, tx); err != nil {
+			return rollback(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return rollback(err)
+	}
+
+	for _, n := range notes {
+		d.addBookToCache(n.Book)
+		for _, t := range n.Tags {
+			d.addTagToCache(t)
+		}
+	}
+	return nil
+}
+
+func (d *Database) editNote(n *quicknote.Note, tx *sql.Tx) error {
+	sqlStr := "UPDATE notes SET modified = ?, type = ?, title = ?, body = ?, bk_id = ? WHERE id = ?;"
+
+	stmt, err := tx.Prepare(sqlStr)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(n.Modified, n.Type, n.Title, n.Body, n.Book.ID, n.ID); err != nil {
+		return err
+	}
+
+	if err := d.deleteTagRal(n, tx); err != nil {
+		return err
+	}
+	if err := d.createTagRal(n, tx); err != nil {
+		return err
+	}
+
+	if err := d.deletePeopleRel(n, tx); err != nil {
+		return err
+	}
+	if err := d.createPeopleRel(n, tx); err != nil {
+		return err
+	}
+	return d.setNoteDue(n, tx)
+}
+
 // EditNoteByIDBook updates all notes for the given IDs with the Book bk's ID
 func (d *Database) EditNoteByIDBook(ids []int64, bk *quicknote.Book) error {
 	d.mux.Lock()
@@ -258,7 +432,7 @@ func (d *Database) EditNoteByIDBook(ids []int64, bk *quicknote.Book) error {
 		return err
 	}
 
-	tx, err := d.db.Begin()
+	tx, err := d.begin()
 	if err != nil {
 		return err
 	}
@@ -344,6 +518,14 @@ func (d *Database) loadNotesFromRows(rows *sql.Rows) (quicknote.Notes, error) {
 			return nil, err
 		}
 
+		if err = d.loadNotePeople(n); err != nil {
+			return nil, err
+		}
+
+		if err = d.loadNoteDue(n); err != nil {
+			return nil, err
+		}
+
 		notes = append(notes, n)
 	}
 
@@ -359,3 +541,15 @@ func (d *Database) loadNotesFromRows(rows *sql.Rows) (quicknote.Notes, error) {
 
 	return notes, nil
 }
+
+func loadIDsFromRows(rows *sql.Rows) ([]int64, error) {
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
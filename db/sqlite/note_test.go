@@ -18,7 +18,9 @@
 package sqlite
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/anmil/quicknote"
 	"github.com/anmil/quicknote/test"
@@ -34,6 +36,57 @@ func TestCreateNoteSQLiteUnit(t *testing.T) {
 	getNoteByID(t, db, n)
 }
 
+func TestInsertNoteSQLiteUnit(t *testing.T) {
+	db := openDatabase(t)
+	defer closeDatabase(db, t)
+
+	n := test.GetTestNotes()[0]
+	n.ID = 42
+	n.Book.ID = 7
+	for i, tag := range n.Tags {
+		tag.ID = int64(10 + i)
+	}
+
+	if err := db.InsertBook(n.Book); err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range n.Tags {
+		if err := db.InsertTag(tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.InsertNote(n); err != nil {
+		t.Fatal(err)
+	}
+
+	getNoteByID(t, db, n)
+
+	// New notes must not collide with the inserted ID
+	n2 := test.GetTestNotes()[1]
+	saveNote(t, db, n2)
+	if n2.ID <= n.ID {
+		t.Fatalf("Expected an ID greater than %d, got %d", n.ID, n2.ID)
+	}
+
+	if cnt, err := db.CountNotes(); err != nil {
+		t.Fatal(err)
+	} else if cnt != 2 {
+		t.Fatalf("Expected 2 notes, got %d", cnt)
+	}
+
+	if cnt, err := db.CountTags(); err != nil {
+		t.Fatal(err)
+	} else if cnt == 0 {
+		t.Fatal("Expected tags to be counted")
+	}
+
+	if cnt, err := db.CountBooks(); err != nil {
+		t.Fatal(err)
+	} else if cnt == 0 {
+		t.Fatal("Expected books to be counted")
+	}
+}
+
 func TestGetNoteSQLiteUnit(t *testing.T) {
 	db := openDatabase(t)
 	defer closeDatabase(db, t)
@@ -48,6 +101,42 @@ func TestGetNoteSQLiteUnit(t *testing.T) {
 	getNotesAll(t, db, notes)
 }
 
+func TestGetNotesModifiedSinceSQLiteUnit(t *testing.T) {
+	db := openDatabase(t)
+	defer closeDatabase(db, t)
+
+	notes := test.GetTestNotes()
+	saveNotes(t, db, notes)
+
+	// The test notes were all modified on 2017-03-26 01:35 UTC
+	since := time.Date(2017, 3, 27, 0, 0, 0, 0, time.UTC)
+	maxID := notes[len(notes)-1].ID
+	if nn, err := db.GetNotesModifiedSince(nil, since, maxID); err != nil {
+		t.Fatal(err)
+	} else if len(nn) != 0 {
+		t.Fatalf("Expected 0 notes, got %d", len(nn))
+	}
+
+	if nn, err := db.GetNotesModifiedSince(notes[0].Book, since, notes[0].ID); err != nil {
+		t.Fatal(err)
+	} else if len(nn) != len(notes)-1 {
+		t.Fatalf("Expected %d notes, got %d", len(notes)-1, len(nn))
+	}
+
+	since = time.Date(2017, 3, 26, 0, 0, 0, 0, time.UTC)
+	if nn, err := db.GetNotesModifiedSince(nil, since, maxID); err != nil {
+		t.Fatal(err)
+	} else {
+		test.CheckNotes(t, nn, notes)
+	}
+
+	if ids, err := db.GetAllNoteIDs(nil); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != len(notes) || ids[0] != notes[0].ID {
+		t.Fatalf("Unexpected note IDs %v", ids)
+	}
+}
+
 func TestEditNoteSQLiteUnit(t *testing.T) {
 	db := openDatabase(t)
 	defer closeDatabase(db, t)
@@ -93,6 +182,126 @@ func TestEditNoteBookSQLiteUnit(t *testing.T) {
 	getNoteByID(t, db, n)
 }
 
+func TestEditNotesSQLiteUnit(t *testing.T) {
+	db := openDatabase(t)
+	defer closeDatabase(db, t)
+
+	bk := quicknote.NewBook()
+	bk.Name = "NewBook"
+
+	err := db.CreateBook(bk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notes := test.GetTestNotes()
+	saveNotes(t, db, notes)
+	oldBk := notes[0].Book
+
+	for i, n := range notes {
+		n.Title = fmt.Sprintf("New title %d", i)
+		n.Book = bk
+	}
+
+	if err := db.EditNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	getNotesByBook(t, db, notes)
+
+	if tags, err := db.GetAllBookTags(oldBk); err != nil {
+		t.Fatal(err)
+	} else if len(tags) != 0 {
+		t.Fatalf("Expected no tags in the old Book, got %v", tags)
+	}
+	if tags, err := db.GetAllBookTags(bk); err != nil {
+		t.Fatal(err)
+	} else if len(tags) == 0 {
+		t.Fatal("Expected the tags to move to the new Book")
+	}
+}
+
+func TestEditNotesCreateNamesSQLiteUnit(t *testing.T) {
+	db := openDatabase(t)
+	defer closeDatabase(db, t)
+
+	notes := test.GetTestNotes()
+	saveNotes(t, db, notes)
+
+	bk := quicknote.NewBook()
+	bk.Name = "Created"
+	tag := quicknote.NewTag()
+	tag.Name = "fresh"
+	notes[0].Book = bk
+	notes[0].Tags = append(notes[0].Tags, tag)
+
+	// A note with a tag that does not exist fails, the Book
+	// and tag created for the first note are rolled back
+	missing := quicknote.NewTag()
+	missing.ID = 9999
+	missing.Name = "missing"
+	tags := notes[1].Tags
+	notes[1].Tags = append(quicknote.Tags{missing}, tags...)
+	if err := db.EditNotes(notes); err == nil {
+		t.Fatal("Expected an error for a tag that does not exist")
+	} else if bk.ID != 0 || tag.ID != 0 {
+		t.Fatalf("Expected no IDs after the rollback, got %d and %d", bk.ID, tag.ID)
+	}
+	if b, err := db.GetBookByName(bk.Name); err != nil {
+		t.Fatal(err)
+	} else if b != nil {
+		t.Fatalf("Expected Book %s to be rolled back", bk.Name)
+	}
+	if tt, err := db.GetTagByName(tag.Name); err != nil {
+		t.Fatal(err)
+	} else if tt != nil {
+		t.Fatalf("Expected tag %s to be rolled back", tag.Name)
+	}
+
+	notes[1].Tags = tags
+	if err := db.EditNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := db.GetBookByName(bk.Name); err != nil {
+		t.Fatal(err)
+	} else if b == nil || b.ID != bk.ID {
+		t.Fatalf("Expected Book %s with ID %d, got %v", bk.Name, bk.ID, b)
+	}
+	if tt, err := db.GetTagByName(tag.Name); err != nil {
+		t.Fatal(err)
+	} else if tt == nil || tt.ID != tag.ID {
+		t.Fatalf("Expected tag %s with ID %d, got %v", tag.Name, tag.ID, tt)
+	}
+}
+
+func TestNoteDueTypeSQLiteUnit(t *testing.T) {
+	db := openDatabase(t)
+	defer closeDatabase(db, t)
+
+	notes := test.GetTestNotes()
+	due := time.Date(2017, 4, 1, 0, 0, 0, 0, time.UTC)
+	notes[0].Due = due
+	saveNotes(t, db, notes)
+
+	if n, err := db.GetNoteByID(notes[0].ID); err != nil {
+		t.Fatal(err)
+	} else if !n.Due.Equal(due) {
+		t.Fatalf("Expected due date %s, got %s", due, n.Due)
+	}
+
+	notes[0].Due = time.Time{}
+	notes[0].Type = quicknote.URL
+	if err := db.EditNote(notes[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if nn, err := db.GetNotesByIDs([]int64{notes[0].ID}); err != nil {
+		t.Fatal(err)
+	} else if len(nn) != 1 || !nn[0].Due.IsZero() || nn[0].Type != quicknote.URL {
+		t.Fatalf("Expected no due date and type %s, got %v", quicknote.URL, nn)
+	}
+}
+
 func TestDeleteNoteSQLiteUnit(t *testing.T) {
 	db := openDatabase(t)
 	defer closeDatabase(db, t)
@@ -0,0 +1,126 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/anmil/quicknote"
+)
+
+// GetAllSavedSearches returns all saved searches ordered by name
+func (d *Database) GetAllSavedSearches() (quicknote.SavedSearches, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	sqlStr := "SELECT id, created, modified, name, query FROM saved_searches ORDER BY name;"
+
+	rows, err := d.db.Query(sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := make(quicknote.SavedSearches, 0)
+	for rows.Next() {
+		s := quicknote.NewSavedSearch()
+		if err := rows.Scan(&s.ID, &s.Created, &s.Modified, &s.Name, &s.Query); err != nil {
+			return nil, err
+		}
+		searches = append(searches, s)
+	}
+
+	return searches, rows.Err()
+}
+
+// GetSavedSearchByName returns the saved search with the given name
+func (d *Database) GetSavedSearchByName(name string) (*quicknote.SavedSearch, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	return d.getSavedSearchByName(name)
+}
+
+func (d *Database) getSavedSearchByName(name string) (*quicknote.SavedSearch, error) {
+	sqlStr := "SELECT id, created, modified, name, query FROM saved_searches WHERE name = ?;"
+
+	stmt, err := d.db.Prepare(sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	s := quicknote.NewSavedSearch()
+	err = stmt.QueryRow(name).Scan(&s.ID, &s.Created, &s.Modified, &s.Name, &s.Query)
+	if err != nil && err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SaveSearch creates the saved search, or replaces the
+// query of the saved search with the same name
+func (d *Database) SaveSearch(s *quicknote.SavedSearch) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	existing, err := d.getSavedSearchByName(s.Name)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		sqlStr := "UPDATE saved_searches SET query = ?, modified = ? WHERE id = ?;"
+
+		s.ID = existing.ID
+		s.Created = existing.Created
+		s.Modified = time.Now()
+		_, err = d.db.Exec(sqlStr, s.Query, s.Modified, s.ID)
+		return err
+	}
+
+	if s.Created.IsZero() {
+		s.Created = time.Now()
+		s.Modified = s.Created
+	}
+
+	sqlStr := "INSERT INTO saved_searches (created, modified, name, query) VALUES (?,?,?,?);"
+
+	res, err := d.db.Exec(sqlStr, s.Created, s.Modified, s.Name, s.Query)
+	if err != nil {
+		return err
+	}
+
+	s.ID, err = res.LastInsertId()
+	return err
+}
+
+// DeleteSavedSearch deletes the saved search
+func (d *Database) DeleteSavedSearch(s *quicknote.SavedSearch) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	sqlStr := "DELETE FROM saved_searches WHERE id = ?;"
+
+	_, err := d.db.Exec(sqlStr, s.ID)
+	return err
+}
@@ -0,0 +1,74 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/anmil/quicknote"
+)
+
+func TestSavedSearchSQLiteUnit(t *testing.T) {
+	db := openDatabase(t)
+	defer closeDatabase(db, t)
+
+	s1 := &quicknote.SavedSearch{Name: "todo", Query: "tag:todo"}
+	if err := db.SaveSearch(s1); err != nil {
+		t.Fatal(err)
+	} else if s1.ID == 0 {
+		t.Fatal("Expected the saved search to have an ID")
+	}
+
+	s2 := &quicknote.SavedSearch{Name: "incidents", Query: "tag:incident -tag:closed"}
+	if err := db.SaveSearch(s2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving with the same name replaces the query
+	s3 := &quicknote.SavedSearch{Name: "todo", Query: "tag:todo book:Work"}
+	if err := db.SaveSearch(s3); err != nil {
+		t.Fatal(err)
+	} else if s3.ID != s1.ID {
+		t.Fatalf("Expected ID %d, got %d", s1.ID, s3.ID)
+	}
+
+	if s, err := db.GetSavedSearchByName("todo"); err != nil {
+		t.Fatal(err)
+	} else if s == nil {
+		t.Fatal("Expected saved search todo, got nil")
+	} else if s.Query != s3.Query {
+		t.Fatalf("Expected query %s, got %s", s3.Query, s.Query)
+	}
+
+	if searches, err := db.GetAllSavedSearches(); err != nil {
+		t.Fatal(err)
+	} else if len(searches) != 2 {
+		t.Fatalf("Expected 2 saved searches, got %d", len(searches))
+	} else if searches[0].Name != "incidents" || searches[1].Name != "todo" {
+		t.Fatalf("Expected saved searches in name order, got %s and %s", searches[0].Name, searches[1].Name)
+	}
+
+	if err := db.DeleteSavedSearch(s2); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := db.GetSavedSearchByName(s2.Name); err != nil {
+		t.Fatal(err)
+	} else if s != nil {
+		t.Fatal("Expected the saved search to be deleted")
+	}
+}
@@ -20,12 +20,14 @@ package sqlite
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"sync"
+	"time"
 
-	// go-sqlite3 must be imported for initialization
 	"github.com/anmil/quicknote"
-	_ "github.com/mattn/go-sqlite3"
+	sqlite3 "github.com/mattn/go-sqlite3"
 )
 
 var schema = `PRAGMA foreign_keys = ON;
@@ -80,11 +82,43 @@ CREATE TABLE IF NOT EXISTS note_book_tag (
 	bk_id   INTEGER REFERENCES books(id) ON DELETE CASCADE,
 	tag_id  INTEGER REFERENCES tags(id) ON DELETE CASCADE,
 	PRIMARY KEY (note_id, bk_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS people (
+	note_id INTEGER REFERENCES notes(id) ON DELETE CASCADE,
+	name    TEXT    NOT NULL,
+	PRIMARY KEY (note_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS index_people_name ON people (name);
+
+CREATE TABLE IF NOT EXISTS due_dates (
+	note_id INTEGER PRIMARY KEY REFERENCES notes(id) ON DELETE CASCADE,
+	due     TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS saved_searches (
+	id       INTEGER   PRIMARY KEY AUTOINCREMENT,
+	created  TIMESTAMP NOT NULL,
+	modified TIMESTAMP NOT NULL,
+	name     TEXT UNIQUE,
+	query    TEXT      NOT NULL
 );`
 
 // Maximum number of wild-card variables SQlite can parse
 const sqliteMaxVariableNumber = 999
 
+// dsnParams are added to the database path when opening the database.
+// WAL lets readers work while another process writes, the busy timeout
+// makes SQLite wait for a lock instead of failing right away, and
+// immediate transactions take the write lock at BEGIN so the busy
+// timeout covers the whole transaction.
+const dsnParams = "_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+
+// Number of times BEGIN is retried when the database is still
+// locked after the busy timeout
+const busyRetries = 5
+
 // ErrInvalidArguments invalid arguments were given
 var ErrInvalidArguments = errors.New("Invalid arguments given to SQLite database")
 
@@ -94,6 +128,7 @@ type Database struct {
 	mux    *sync.Mutex
 	DBPath string
 
+	cacheMux      *sync.RWMutex
 	tagNameCache  map[string]*quicknote.Tag
 	bookNameCache map[string]*quicknote.Book
 }
@@ -104,7 +139,7 @@ func NewDatabase(dbPath ...string) (*Database, error) {
 		return nil, ErrInvalidArguments
 	}
 
-	db, err := sql.Open("sqlite3", dbPath[0])
+	db, err := sql.Open("sqlite3", buildDSN(dbPath[0]))
 	if err != nil {
 		return nil, err
 	}
@@ -121,6 +156,7 @@ func NewDatabase(dbPath ...string) (*Database, error) {
 		db:            db,
 		mux:           &sync.Mutex{},
 		DBPath:        dbPath[0],
+		cacheMux:      &sync.RWMutex{},
 		tagNameCache:  make(map[string]*quicknote.Tag),
 		bookNameCache: make(map[string]*quicknote.Book),
 	}, nil
@@ -159,19 +195,83 @@ func (d *Database) Close() error {
 }
 
 func (d *Database) getTxStmt(sqlStmt string) (*sql.Tx, *sql.Stmt, error) {
-	tx, err := d.db.Begin()
+	tx, err := d.begin()
 	if err != nil {
 		return nil, nil, err
 	}
 
 	stmt, err := tx.Prepare(sqlStmt)
 	if err != nil {
+		tx.Rollback()
 		return nil, nil, err
 	}
 
 	return tx, stmt, nil
 }
 
+// begin starts a new transaction, retrying if another
+// process holds the write lock longer than the busy timeout
+func (d *Database) begin() (*sql.Tx, error) {
+	var tx *sql.Tx
+	var err error
+	for i := 0; i < busyRetries; i++ {
+		if tx, err = d.db.Begin(); err == nil || !isBusy(err) {
+			return tx, err
+		}
+		time.Sleep(time.Duration(i+1) * 100 * time.Millisecond)
+	}
+	return nil, err
+}
+
+// buildDSN adds dsnParams to the database path
+func buildDSN(dbPath string) string {
+	if strings.Contains(dbPath, "?") {
+		return dbPath + "&" + dsnParams
+	}
+	return dbPath + "?" + dsnParams
+}
+
+// isBusy returns true if err is SQLite's database is busy or locked error
+func isBusy(err error) bool {
+	if se, ok := err.(sqlite3.Error); ok {
+		return se.Code == sqlite3.ErrBusy || se.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+// isUniqueViolation returns true if err is SQLite's UNIQUE constraint error
+func isUniqueViolation(err error) bool {
+	if se, ok := err.(sqlite3.Error); ok {
+		return se.ExtendedCode == sqlite3.ErrConstraintUnique
+	}
+	return false
+}
+
+// sqliteTimeLayout is the time format given to SQLite's date functions
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// optionalBookID returns the ID used to limit a query
+// to the Book bk, 0 if bk is nil (all Books).
+func optionalBookID(bk *quicknote.Book) int64 {
+	if bk == nil {
+		return 0
+	}
+	return bk.ID
+}
+
+// sqliteTime formats t for SQLite's date functions, which expect UTC
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// countRows returns the number of rows in table, table must
+// never come from user input.
+func (d *Database) countRows(table string) (int64, error) {
+	var cnt int64
+	err := d.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s;", table)).Scan(&cnt)
+	return cnt, err
+}
+
 // splitSliceToChuck slice s into chucks containing the maximum number of
 // objects we can use in a statement.
 //
@@ -18,6 +18,10 @@
 package sqlite
 
 import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
 	"testing"
 
 	"github.com/anmil/quicknote/test"
@@ -25,9 +29,12 @@ import (
 
 var tableNames = []string{
 	"books",
+	"due_dates",
 	"note_book_tag",
 	"note_tag",
 	"notes",
+	"people",
+	"saved_searches",
 	"sqlite_sequence",
 	"tags",
 }
@@ -57,3 +64,59 @@ func TestCreateDatabaseSQLite(t *testing.T) {
 		t.Fatal("Database either has extra or is missing tables")
 	}
 }
+
+func TestConcurrentWritesSQLiteUnit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qnote-sqlite-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewDatabase(path.Join(dir, "notes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDatabase(db, t)
+
+	var mode string
+	if err := db.db.QueryRow("PRAGMA journal_mode;").Scan(&mode); err != nil {
+		t.Fatal(err)
+	} else if mode != "wal" {
+		t.Fatalf("Expected journal mode wal, got %s", mode)
+	}
+
+	// A second connection, like another process, creating
+	// the same Books and Tags at the same time
+	db2, err := NewDatabase(path.Join(dir, "notes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeDatabase(db2, t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(d *Database) {
+			defer wg.Done()
+			if _, err := d.GetOrCreateBookByName("concurrent"); err != nil {
+				errs <- err
+			}
+			if _, err := d.GetOrCreateTagByName("concurrent"); err != nil {
+				errs <- err
+			}
+		}([]*Database{db, db2}[i%2])
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if cnt, err := db.CountTags(); err != nil {
+		t.Fatal(err)
+	} else if cnt != 1 {
+		t.Fatalf("Expected 1 tag, got %d", cnt)
+	}
+}
@@ -80,6 +80,10 @@ func (d *Database) GetOrCreateTagByName(name string) (*quicknote.Tag, error) {
 			Name:     name,
 		}
 		err = d.CreateTag(t)
+		if isUniqueViolation(err) {
+			// Created by another goroutine or process since the lookup
+			t, err = d.GetTagByName(name)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -177,6 +181,65 @@ func (d *Database) CreateTag(t *quicknote.Tag) error {
 	return nil
 }
 
+// getOrCreateTagTx sets the ID of t to the ID of the tag with its
+// name, creating the tag in tx if it does not exist
+func (d *Database) getOrCreateTagTx(t *quicknote.Tag, tx *sql.Tx) error {
+	stmt, err := tx.Prepare("SELECT id FROM tags WHERE name = ?;")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(t.Name).Scan(&t.ID)
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	insStmt, err := tx.Prepare("INSERT INTO tags (created, modified, name) VALUES (?,?,?);")
+	if err != nil {
+		return err
+	}
+	defer insStmt.Close()
+
+	res, err := insStmt.Exec(t.Created, t.Modified, t.Name)
+	if err != nil {
+		return err
+	}
+
+	t.ID, err = res.LastInsertId()
+	return err
+}
+
+// InsertTag saves the tag to the database keeping its ID, Created, and Modified
+func (d *Database) InsertTag(t *quicknote.Tag) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	sqlStr := "INSERT INTO tags (id, created, modified, name) VALUES (?,?,?,?);"
+
+	tx, stmt, err := d.getTxStmt(sqlStr)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.Exec(t.ID, t.Created, t.Modified, t.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	d.addTagToCache(t)
+
+	return tx.Commit()
+}
+
+// CountTags returns the number of tags
+func (d *Database) CountTags() (int64, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.countRows("tags")
+}
+
 func (d *Database) loadTagsFromRows(rows *sql.Rows) (quicknote.Tags, error) {
 	tags := make(quicknote.Tags, 0)
 	for rows.Next() {
@@ -288,18 +351,26 @@ func (d *Database) deleteNoteNookTagsRel(n *quicknote.Note, tx *sql.Tx) error {
 }
 
 func (d *Database) addTagToCache(tag *quicknote.Tag) {
+	d.cacheMux.Lock()
+	defer d.cacheMux.Unlock()
 	d.tagNameCache[tag.Name] = tag
 }
 
 func (d *Database) delTagFromCache(tag *quicknote.Tag) {
+	d.cacheMux.Lock()
+	defer d.cacheMux.Unlock()
 	delete(d.tagNameCache, tag.Name)
 }
 
 func (d *Database) delTagFromCacheS(name string) {
+	d.cacheMux.Lock()
+	defer d.cacheMux.Unlock()
 	delete(d.tagNameCache, name)
 }
 
 func (d *Database) getFromTagCache(name string) *quicknote.Tag {
+	d.cacheMux.RLock()
+	defer d.cacheMux.RUnlock()
 	if tag, found := d.tagNameCache[name]; found {
 		return tag
 	}
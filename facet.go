@@ -0,0 +1,140 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package quicknote
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anmil/quicknote/query"
+)
+
+// Fields search results can be faceted on
+const (
+	FacetBook    = "book"
+	FacetTags    = "tags"
+	FacetType    = "type"
+	FacetCreated = "created"
+)
+
+// FacetFields are all the fields that can be faceted on
+var FacetFields = []string{FacetBook, FacetTags, FacetType, FacetCreated}
+
+// IsFacetField returns true if field can be faceted on
+func IsFacetField(field string) bool {
+	for _, f := range FacetFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// FacetMonthLayout is the layout of the terms in the created facet,
+// notes are counted by the month (UTC) they were created
+const FacetMonthLayout = "2006-01"
+
+// FacetTerm is the number of matching notes for a term
+type FacetTerm struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+// Facet is the term counts of a field over all the notes
+// that matched a search. Terms are ordered by count, except
+// for the created facet which is ordered by month.
+type Facet struct {
+	Field string       `json:"field"`
+	Terms []*FacetTerm `json:"terms"`
+}
+
+// FacetFilter limits a search to the notes with Term in Field
+type FacetFilter struct {
+	Field string
+	Term  string
+}
+
+// ParseFacetFilter parses a filter in the form field=term,
+// "tag" can be used for the tags field
+func ParseFacetFilter(s string) (*FacetFilter, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || len(strings.TrimSpace(parts[1])) == 0 {
+		return nil, fmt.Errorf("invalid facet filter %q, expected field=term", s)
+	}
+
+	field := strings.ToLower(strings.TrimSpace(parts[0]))
+	if field == "tag" {
+		field = FacetTags
+	}
+
+	f := &FacetFilter{Field: field, Term: strings.TrimSpace(parts[1])}
+	if !IsFacetField(f.Field) {
+		return nil, fmt.Errorf("can not filter on %q, must be one of %s", parts[0],
+			strings.Join(FacetFields, ", "))
+	} else if f.Field == FacetCreated {
+		if _, _, err := f.MonthRange(); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// MonthRange returns the start and end of the month of a created filter
+func (f *FacetFilter) MonthRange() (time.Time, time.Time, error) {
+	start, err := time.Parse(FacetMonthLayout, f.Term)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid month %q, expected YYYY-MM", f.Term)
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+func (f *FacetFilter) String() string {
+	return fmt.Sprintf("%s=%s", f.Field, f.Term)
+}
+
+// SearchOptions are the optional parts of a search
+type SearchOptions struct {
+	// Facets are the fields to count terms for, see FacetFields
+	Facets []string
+
+	// FacetSize is the max number of terms returned for each facet
+	FacetSize int
+
+	// Filters limit the search to notes matching all of them
+	Filters []*FacetFilter
+
+	// Query limits the search to the notes that also match
+	// it, such as the query of a saved search
+	Query query.Node
+
+	// Fuzzy lets SearchNotePhrase match words with a few
+	// typos, see Fuzziness
+	Fuzzy bool
+
+	// Sort orders the results, the most relevant are first when
+	// it is empty. The note ID is always the last sort field.
+	Sort []*SortField
+
+	// After is the Next cursor of the previous page of the search
+	After string
+
+	// Explain sets the Explanation of each SearchHit
+	Explain bool
+}
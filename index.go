@@ -17,12 +17,73 @@
 
 package quicknote
 
+import (
+	"errors"
+
+	"github.com/anmil/quicknote/query"
+)
+
+// ErrIndexOutdated is returned by searches of an index created with an
+// older mapping, the notes must be indexed again with the current one
+var ErrIndexOutdated = errors.New(`the search index was created by an older version of qnote, run "qnote search reindex" to rebuild it`)
+
 // Index interface for the index providers
 type Index interface {
 	IndexNote(n *Note) error
 	IndexNotes(notes Notes) error
-	SearchNote(query string, limit, offset int) ([]int64, uint64, error)
-	SearchNotePhrase(query string, bk *Book, sort string, limit, offset int) ([]int64, uint64, error)
+	SearchNote(q query.Node, limit int, opts *SearchOptions) (*SearchResult, error)
+	SearchNotePhrase(query string, bk *Book, limit int, opts *SearchOptions) (*SearchResult, error)
+	SimilarNotes(n *Note, limit int) (*SearchResult, error)
+	SuggestQuery(text string, limit int) ([]string, error)
+	CompleteTags(prefix string, limit int) ([]*Completion, error)
+	CompleteTitles(prefix string, limit int) ([]*Completion, error)
 	DeleteNote(n *Note) error
 	DeleteBook(bk *Book) error
+	NoteIDs(bk *Book) ([]int64, error)
+}
+
+// Resharder is implemented by index providers that split
+// notes across a number of shards they manage
+type Resharder interface {
+	Shards() int
+	Reshard(shards int) (int, error)
+}
+
+// Rebuilder is implemented by index providers that can build
+// a new index while the current one is still searched
+type Rebuilder interface {
+	// Rebuild calls build with a new empty index. When build returns
+	// without error the new index replaces the current one.
+	Rebuild(build func(idx Index) error) error
+}
+
+// LanguageIndex is implemented by index providers that analyze
+// the notes of each Book in its language
+type LanguageIndex interface {
+	SetLanguages(l *Languages)
+}
+
+// TagCaseIndex is implemented by index providers that lower case the
+// tags searched and completed, fold is false when tags keep their case
+type TagCaseIndex interface {
+	SetFoldTagCase(fold bool)
+}
+
+// OutdatedIndex is implemented by index providers that keep the version
+// of their mapping. Outdated is true when the index was created with an
+// older mapping, a full re-index builds it with the current one.
+type OutdatedIndex interface {
+	Outdated() bool
+}
+
+// Comparer is implemented by index providers that search more than
+// one index, the results of each can be compared
+type Comparer interface {
+	Compare(search func(idx Index) (*SearchResult, error)) ([]*ComparedResult, error)
+}
+
+// ComparedResult is the result of a search in the index of a provider
+type ComparedResult struct {
+	Name   string
+	Result *SearchResult
 }
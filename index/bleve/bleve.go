@@ -19,20 +19,32 @@ package bleve
 
 import (
 	"fmt"
-	"io/ioutil"
+	"html"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/query"
 
 	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/mapping"
+	bsearch "github.com/blevesearch/bleve/search"
 	bquery "github.com/blevesearch/bleve/search/query"
 )
 
+// facetFieldSuffix is added to the name of the fields that are also
+// indexed with the keyword analyzer, for faceting and filtering
+const facetFieldSuffix = "_facet"
+
+// peopleField are the people mentioned in a note
+const peopleField = "people"
+
 type indexNote struct {
 	ID       int64     `json:"id"`
 	Created  time.Time `json:"created"`
@@ -42,6 +54,18 @@ type indexNote struct {
 	Body     string    `json:"body"`
 	Book     string    `json:"book"`
 	Tags     []string  `json:"tags"`
+	People   []string  `json:"people"`
+
+	// Language of the Book, the title and body are copied
+	// to the stem fields analyzed in the language
+	Language  string `json:"language"`
+	TitleStem string `json:"title_stem"`
+	BodyStem  string `json:"body_stem"`
+}
+
+// BleveType picks the mapping of the note's language
+func (iN *indexNote) BleveType() string {
+	return iN.Language
 }
 
 type bIndex struct {
@@ -95,10 +119,7 @@ func (b *bIndex) DeleteBook(wg *sync.WaitGroup, bk *quicknote.Book) {
 }
 
 func (b *bIndex) getNextDeleteBatch(bk *quicknote.Book) ([]string, error) {
-	query := fmt.Sprintf("+book:%s", bk.Name)
-	q := bleve.NewQueryStringQuery(query)
-
-	search := bleve.NewSearchRequest(q)
+	search := bleve.NewSearchRequest(bookQuery(bk.Name))
 	search.Size = 1000
 
 	res, err := b.Index.Search(search)
@@ -118,223 +139,261 @@ func (b *bIndex) getNextDeleteBatch(bk *quicknote.Book) ([]string, error) {
 type Index struct {
 	db bleve.IndexAlias
 
+	// dataPath is the directory of all the generations
+	// and indexPath the directory of the current one
+	dataPath  string
+	indexPath string
+
+	languages *quicknote.Languages
+
+	// foldTagCase is set when the tags are lower case
+	foldTagCase bool
+
+	// outdated is set when the shards were created with
+	// an older mapping, see MappingVersion
+	outdated bool
+
 	shards  int
 	indexes []*bIndex
-
-	indexIdx     int
-	indexIdxFile string
 }
 
-// NewIndex returns a new Index
+// NewIndex returns a new Index. Notes are split across shards Bleve
+// indexes by their ID. An existing index keeps its number of shards,
+// use Reshard to change it.
 func NewIndex(indexPath string, shards int) (*Index, error) {
-	indexMapping := bleve.NewIndexMapping()
+	if shards < 1 {
+		return nil, ErrInvalidShardCount
+	}
 
-	bindexes := make([]*bIndex, shards)
-	indexes := make([]bleve.Index, shards)
+	gen, err := loadGeneration(indexPath)
+	if err != nil {
+		return nil, err
+	}
 
-	for i := 0; i < shards; i++ {
-		p := path.Join(indexPath, fmt.Sprintf("index-%d.bleve", i))
-		index, err := bleve.New(p, indexMapping)
-		if err == bleve.ErrorIndexPathExists {
-			index, err = bleve.Open(p)
-			if err != nil {
-				return nil, err
-			}
-		}
-		indexes[i] = index
-		bindexes[i] = &bIndex{Index: index}
+	// Left over from a rebuild that did not finish
+	if err = removeOldGenerations(indexPath, gen); err != nil {
+		return nil, err
 	}
 
-	indexAlias := bleve.NewIndexAlias(indexes...)
-	indexIdxFile := path.Join(indexPath, "current_index")
+	return openIndex(indexPath, path.Join(indexPath, gen), shards)
+}
 
-	idx := &Index{
-		db:           indexAlias,
-		shards:       shards,
-		indexes:      bindexes,
-		indexIdxFile: indexIdxFile,
-	}
+// openIndex opens the generation of the index in genPath, creating
+// it with shards shards if it does not exist
+func openIndex(dataPath, genPath string, shards int) (*Index, error) {
+	idx := &Index{dataPath: dataPath, indexPath: genPath, foldTagCase: true}
 
-	err := idx.loadIndexIdx()
+	cnt, err := idx.loadShardCount()
 	if err != nil {
 		return nil, err
 	}
 
-	return idx, nil
-}
+	// Shards are opened with the mapping they were created with
+	created := idx.countShardDirs() == 0
+	if !created {
+		version, err := idx.loadMappingVersion()
+		if err != nil {
+			return nil, err
+		}
+		idx.outdated = version < MappingVersion
+	}
 
-func (b *Index) getIndex() *bIndex {
-	b.indexIdx++
-	if b.indexIdx >= b.shards {
-		b.indexIdx = 0
+	// Indexes from before notes were routed by ID
+	// can have a note in any of the shards
+	legacy := false
+	if cnt == 0 {
+		cnt = idx.countShardDirs()
+		legacy = cnt > 0
 	}
-	err := b.saveIndexIdx()
-	if err != nil {
-		panic(err)
+	if cnt == 0 {
+		cnt = shards
 	}
-	return b.indexes[b.indexIdx]
-}
 
-func (b *Index) getDocIndex(id string) (*bIndex, error) {
-	for _, idx := range b.indexes {
-		if doc, err := idx.Index.Document(id); err != nil {
+	if err = idx.openShards(cnt); err != nil {
+		return nil, err
+	}
+	if created {
+		if err = idx.saveMappingVersion(); err != nil {
 			return nil, err
-		} else if doc != nil {
-			return idx, nil
 		}
 	}
-	return nil, nil
-}
 
-func (b *Index) loadIndexIdx() error {
-	if _, err := os.Stat(b.indexIdxFile); !os.IsNotExist(err) {
-		data, err := ioutil.ReadFile(b.indexIdxFile)
-		if err != nil {
-			return nil
+	if legacy {
+		if _, err = idx.Reshard(shards); err != nil {
+			return nil, err
 		}
-		b.indexIdx, err = strconv.Atoi(string(data))
-		return err
+		if err = os.Remove(path.Join(genPath, legacyIndexIdxFile)); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	} else if err = idx.saveShardCount(); err != nil {
+		return nil, err
 	}
 
-	b.indexIdx = 0
-	return nil
+	return idx, nil
 }
 
-func (b *Index) saveIndexIdx() error {
-	s := strconv.Itoa(b.indexIdx)
-	return ioutil.WriteFile(b.indexIdxFile, []byte(s), 0600)
+// MappingVersion is the version of newIndexMapping, increase it when the
+// mapping changes. Indexes with an older mapping can not be searched
+// until they are rebuilt, see Outdated.
+const MappingVersion = 1
+
+// Outdated returns whether the index was created with an older mapping.
+// Searches return quicknote.ErrIndexOutdated until it is rebuilt.
+func (b *Index) Outdated() bool {
+	return b.outdated
 }
 
-// IndexNote creates or updates a note in Bleve index
-func (b *Index) IndexNote(n *quicknote.Note) error {
-	iN := &indexNote{
-		ID:       n.ID,
-		Created:  n.Created,
-		Modified: n.Modified,
-		Type:     n.Type,
-		Title:    n.Title,
-		Body:     n.Body,
-		Book:     n.Book.Name,
-		Tags:     n.GetTagStringArray(),
+// checkMapping returns quicknote.ErrIndexOutdated if the index
+// was created with an older mapping
+func (b *Index) checkMapping() error {
+	if b.outdated {
+		return quicknote.ErrIndexOutdated
 	}
+	return nil
+}
 
-	idS := strconv.FormatInt(n.ID, 10)
-	idx, err := b.getDocIndex(idS)
-	if err != nil {
-		return err
+// newIndexMapping returns the mapping for new indexes. Notes are mapped
+// dynamically, book, tags and type are also indexed as whole terms.
+// Notes with a language use the mapping of the language.
+func newIndexMapping() (mapping.IndexMapping, error) {
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = newNoteMapping()
+	if err := addLanguageAnalyzers(indexMapping); err != nil {
+		return nil, err
+	}
+	if err := addSortAnalyzer(indexMapping); err != nil {
+		return nil, err
+	}
+	for _, lang := range quicknote.SupportedLanguages {
+		indexMapping.AddDocumentMapping(lang, newLanguageMapping(lang))
 	}
+	return indexMapping, nil
+}
 
-	if idx != nil {
-		err = idx.Index.Index(idS, iN)
-	} else {
-		err = b.getIndex().Index.Index(idS, iN)
+// newNoteMapping returns the mapping of notes without a language
+func newNoteMapping() *mapping.DocumentMapping {
+	noteMapping := bleve.NewDocumentMapping()
+	for _, field := range []string{quicknote.FacetBook, quicknote.FacetTags, quicknote.FacetType} {
+		facetMapping := bleve.NewTextFieldMapping()
+		facetMapping.Name = field + facetFieldSuffix
+		facetMapping.Analyzer = keyword.Name
+		facetMapping.Store = false
+		facetMapping.IncludeInAll = false
+		facetMapping.IncludeTermVectors = false
+
+		noteMapping.AddFieldMappingsAt(field, bleve.NewTextFieldMapping(), facetMapping)
 	}
+	noteMapping.AddFieldMappingsAt("title", bleve.NewTextFieldMapping(), newTitleSortMapping())
+
+	// People are matched as whole lower case names
+	peopleMapping := bleve.NewTextFieldMapping()
+	peopleMapping.Analyzer = sortAnalyzer
+	noteMapping.AddFieldMappingsAt(peopleField, peopleMapping)
+
+	languageMapping := bleve.NewTextFieldMapping()
+	languageMapping.Analyzer = keyword.Name
+	languageMapping.IncludeInAll = false
+	noteMapping.AddFieldMappingsAt(languageField, languageMapping)
+
+	return noteMapping
+}
 
-	return err
+// IndexNote creates or updates a note in Bleve index
+func (b *Index) IndexNote(n *quicknote.Note) error {
+	return b.shardFor(n.ID).Index.Index(strconv.FormatInt(n.ID, 10), b.newIndexNote(n))
 }
 
 // IndexNotes creates or updates a list of notes in Bleve index
 func (b *Index) IndexNotes(notes quicknote.Notes) error {
 	var wg sync.WaitGroup
-	var batchSize int64
 
-	index := b.getIndex()
-	bNotes := make([]*indexNote, 0)
+	batches := make([][]*indexNote, b.shards)
+	batchSizes := make([]int64, b.shards)
 	for _, n := range notes {
-		iN := &indexNote{
-			ID:       n.ID,
-			Created:  n.Created,
-			Modified: n.Modified,
-			Type:     n.Type,
-			Title:    n.Title,
-			Body:     n.Body,
-			Book:     n.Book.Name,
-			Tags:     n.GetTagStringArray(),
-		}
-
-		// Check if this note has been indexed already
-		// update it if so
-		idS := strconv.FormatInt(n.ID, 10)
-		if idx, err := b.getDocIndex(idS); err != nil {
-			return err
-		} else if idx != nil {
-			if err = idx.Index.Index(idS, iN); err != nil {
-				return err
-			}
-			continue
-		}
+		i := shardOf(strconv.FormatInt(n.ID, 10), b.shards)
 
-		batchSize += 16 + // timestamps are 8 bytes each
+		batchSizes[i] += 16 + // timestamps are 8 bytes each
 			int64(len(n.Type)) +
 			int64(len(n.Title)) +
 			int64(len(n.Body)) +
 			int64(len(n.Book.Name)) +
 			int64(len(n.GetTagStringArray()))
 
-		bNotes = append(bNotes, iN)
+		batches[i] = append(batches[i], b.newIndexNote(n))
 
 		// After some experimenting I've found that batch performance depends more
 		// on the byte size than the number of records. Using Bolt as the store
 		// provider. Some testing shows that around 64KB is the sweet spot.
-		if batchSize >= 65536 {
+		if batchSizes[i] >= 65536 {
 			wg.Add(1)
-			go index.BatchIndex(&wg, bNotes)
-			bNotes = make([]*indexNote, 0)
-
-			index = b.getIndex()
-			batchSize = 0
+			go b.indexes[i].BatchIndex(&wg, batches[i])
+			batches[i] = nil
+			batchSizes[i] = 0
 		}
 	}
 
-	if len(bNotes) > 0 {
-		wg.Add(1)
-		go index.BatchIndex(&wg, bNotes)
+	for i, batch := range batches {
+		if len(batch) > 0 {
+			wg.Add(1)
+			go b.indexes[i].BatchIndex(&wg, batch)
+		}
 	}
 
 	wg.Wait()
+	return nil
+}
 
-	nCnt := uint64(0)
-	for _, idx := range b.indexes {
-		cnt, _ := idx.Index.DocCount()
-		nCnt += cnt
+func (b *Index) newIndexNote(n *quicknote.Note) *indexNote {
+	iN := &indexNote{
+		ID:       n.ID,
+		Created:  n.Created,
+		Modified: n.Modified,
+		Type:     n.Type,
+		Title:    n.Title,
+		Body:     n.Body,
+		Book:     n.Book.Name,
+		Tags:     n.GetTagStringArray(),
+		People:   n.People,
+		Language: b.languages.Book(n.Book.Name),
 	}
-
-	return nil
+	iN.setStems()
+	return iN
 }
 
-// SearchNote sends a search query to Bleve using QueryStringQuery
-func (b *Index) SearchNote(query string, limit, offset int) ([]int64, uint64, error) {
-	q := bleve.NewQueryStringQuery(query)
-	search := bleve.NewSearchRequest(q)
-	search.Size = limit
-	search.From = offset
-	res, err := b.db.Search(search)
-	if err != nil {
-		return nil, 0, err
+// setStems copies the title and body to the stem fields
+// when the note has a language
+func (iN *indexNote) setStems() {
+	if iN.Language != "" {
+		iN.TitleStem = iN.Title
+		iN.BodyStem = iN.Body
 	}
+}
 
-	ids := make([]int64, 0, len(res.Hits))
-	for _, h := range res.Hits {
-		id, err := strconv.ParseInt(h.ID, 10, 64)
-		if err != nil {
-			return nil, 0, err
-		}
-		ids = append(ids, id)
+// SearchNote translates the query to Bleve's queries and searches the index
+func (b *Index) SearchNote(n query.Node, limit int, opts *quicknote.SearchOptions) (*quicknote.SearchResult, error) {
+	q, err := translateQuery(n, b.languages.Used())
+	if err != nil {
+		return nil, err
 	}
 
-	return ids, res.Total, err
+	return b.searchPage(q, limit, opts)
 }
 
 // SearchNotePhrase sends a search query to Bleve using Prefix query
 // If bk is given, only notes for that Book are queried.
-func (b *Index) SearchNotePhrase(query string, bk *quicknote.Book, sort string, limit, offset int) ([]int64, uint64, error) {
+func (b *Index) SearchNotePhrase(query string, bk *quicknote.Book, limit int, opts *quicknote.SearchOptions) (*quicknote.SearchResult, error) {
 	boolQuery := bleve.NewBooleanQuery()
 
 	// Bleve does not support phrase prefix query natively
 	// https://github.com/blevesearch/bleve/issues/377
 	var disquery bquery.Query
 	words := strings.Fields(query)
-	if len(words) == 1 {
+	if opts != nil && opts.Fuzzy {
+		var err error
+		if disquery, err = b.newFuzzyQuery(query); err != nil {
+			return nil, err
+		}
+	} else if len(words) == 1 {
 		disquery = bleve.NewDisjunctionQuery(
 			bleve.NewPrefixQuery(query),
 			bleve.NewMatchQuery(query),
@@ -355,60 +414,244 @@ func (b *Index) SearchNotePhrase(query string, bk *quicknote.Book, sort string,
 			),
 		)
 	}
+	if opts == nil || !opts.Fuzzy {
+		disquery = withStems(disquery, query, []string{titleStemField, bodyStemField}, b.languages.Search(bk))
+	}
 	boolQuery.AddMust(disquery)
 
 	// matchPrefixQuery := bleve.NewPrefixQuery(query)
 	// boolQuery.AddMust(matchPrefixQuery)
 
 	if bk != nil {
-		matchBookQuery := bleve.NewQueryStringQuery(fmt.Sprintf("+book:%s", bk.Name))
+		matchBookQuery := bookQuery(bk.Name)
 		boolQuery.AddMust(matchBookQuery)
 	}
 
-	search := bleve.NewSearchRequest(boolQuery)
-	search.Size = limit
-	search.From = offset
+	return b.searchPage(boolQuery, limit, opts)
+}
+
+// searchPage searches a page of limit notes matching q
+func (b *Index) searchPage(q bquery.Query, limit int, opts *quicknote.SearchOptions) (*quicknote.SearchResult, error) {
+	if err := b.checkMapping(); err != nil {
+		return nil, err
+	}
+
+	search, err := b.newSearchRequest(q, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = setPage(search, limit, opts); err != nil {
+		return nil, err
+	}
+	search.Highlight = newHighlight()
+	search.Explain = opts != nil && opts.Explain
 
 	res, err := b.db.Search(search)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+
+	result, err := getSearchResult(res, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Next, err = nextCursor(search.Sort, res, limit)
+	return result, err
+}
+
+// newHighlight returns the highlight request for the searched fields.
+// The html highlighter surrounds matched terms with <mark></mark>,
+// the same markers as quicknote.HighlightStart and HighlightEnd.
+func newHighlight() *bleve.HighlightRequest {
+	hl := bleve.NewHighlightWithStyle("html")
+	hl.AddField("title")
+	hl.AddField("body")
+	hl.AddField("tags")
+	return hl
+}
+
+// newSearchRequest returns a search request for q limited by the
+// filters and query in opts, with the requested facets
+func (b *Index) newSearchRequest(q bquery.Query, opts *quicknote.SearchOptions) (*bleve.SearchRequest, error) {
+	if opts == nil {
+		return bleve.NewSearchRequest(q), nil
+	}
+
+	if len(opts.Filters) > 0 || opts.Query != nil {
+		queries := []bquery.Query{q}
+		if opts.Query != nil {
+			oq, err := translateQuery(opts.Query, b.languages.Used())
+			if err != nil {
+				return nil, err
+			}
+			queries = append(queries, oq)
+		}
+
+		for _, f := range opts.Filters {
+			if f.Field == quicknote.FacetCreated {
+				start, end, err := f.MonthRange()
+				if err != nil {
+					return nil, err
+				}
+				dq := bleve.NewDateRangeQuery(start, end)
+				dq.SetField(quicknote.FacetCreated)
+				queries = append(queries, dq)
+			} else {
+				tq := bleve.NewTermQuery(f.Term)
+				tq.SetField(f.Field + facetFieldSuffix)
+				queries = append(queries, tq)
+			}
+		}
+		q = bleve.NewConjunctionQuery(queries...)
+	}
+
+	search := bleve.NewSearchRequest(q)
+	for _, field := range opts.Facets {
+		switch field {
+		case quicknote.FacetBook, quicknote.FacetTags, quicknote.FacetType:
+			search.AddFacet(field, bleve.NewFacetRequest(field+facetFieldSuffix, opts.FacetSize))
+		case quicknote.FacetCreated:
+			fr, err := b.newMonthFacetRequest(q, opts.FacetSize)
+			if err != nil {
+				return nil, err
+			}
+			if fr != nil {
+				search.AddFacet(field, fr)
+			}
+		default:
+			return nil, fmt.Errorf("can not facet on %q", field)
+		}
+	}
+
+	return search, nil
+}
+
+// newMonthFacetRequest returns a facet for the number of notes created in
+// each of the size months up to the newest note matching q. Bleve has no
+// date histogram, so the newest note is looked up to build the ranges.
+func (b *Index) newMonthFacetRequest(q bquery.Query, size int) (*bleve.FacetRequest, error) {
+	search := bleve.NewSearchRequest(q)
+	search.Size = 1
+	search.Fields = []string{quicknote.FacetCreated}
+	search.SortBy([]string{"-" + quicknote.FacetCreated})
+
+	res, err := b.db.Search(search)
+	if err != nil {
+		return nil, err
+	} else if len(res.Hits) == 0 {
+		return nil, nil
+	}
+
+	value, ok := res.Hits[0].Fields[quicknote.FacetCreated].(string)
+	if !ok {
+		return nil, nil
+	}
+	newest, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	newest = newest.UTC()
+	month := time.Date(newest.Year(), newest.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	fr := bleve.NewFacetRequest(quicknote.FacetCreated, size)
+	for i := 0; i < size; i++ {
+		start := month.AddDate(0, -i, 0)
+		fr.AddDateTimeRange(start.Format(quicknote.FacetMonthLayout), start, start.AddDate(0, 1, 0))
+	}
+	return fr, nil
+}
+
+func getSearchResult(res *bleve.SearchResult, opts *quicknote.SearchOptions) (*quicknote.SearchResult, error) {
+	result := &quicknote.SearchResult{
+		Hits:  make([]*quicknote.SearchHit, 0, len(res.Hits)),
+		Total: res.Total,
 	}
 
-	ids := make([]int64, 0, len(res.Hits))
 	for _, h := range res.Hits {
 		id, err := strconv.ParseInt(h.ID, 10, 64)
 		if err != nil {
-			return nil, 0, err
+			return nil, err
 		}
-		ids = append(ids, id)
-	}
 
-	// Go has no build in function for reversing a array, and Bleve does not have
-	// a simple way either. You can sort by field, but I could not figure out how
-	// to just reverse results
-	if sort == "asc" {
-		for i := 0; i < len(ids)/2; i++ {
-			j := len(ids) - i - 1
-			ids[i], ids[j] = ids[j], ids[i]
+		// The html highlighter escapes the text around the
+		// matched terms, Fragments are plain text
+		fragments := make(map[string][]string, len(h.Fragments))
+		for field, frags := range h.Fragments {
+			for _, f := range frags {
+				fragments[field] = append(fragments[field], html.UnescapeString(f))
+			}
 		}
+
+		result.Hits = append(result.Hits, &quicknote.SearchHit{
+			ID:          id,
+			Score:       h.Score,
+			Fragments:   fragments,
+			Explanation: getExplanation(h.Expl),
+		})
 	}
 
-	return ids, res.Total, err
+	if opts != nil {
+		result.Facets = getFacets(res.Facets, opts.Facets)
+	}
+
+	return result, nil
 }
 
-// DeleteNote deletes note from index
-func (b *Index) DeleteNote(n *quicknote.Note) error {
-	for _, i := range b.indexes {
-		err := i.Index.Delete(strconv.FormatInt(n.ID, 10))
-		if err != nil {
-			return err
+// getExplanation converts Bleve's score explanation
+func getExplanation(expl *bsearch.Explanation) *quicknote.Explanation {
+	if expl == nil {
+		return nil
+	}
+
+	e := &quicknote.Explanation{Value: expl.Value, Message: expl.Message}
+	for _, child := range expl.Children {
+		e.Children = append(e.Children, getExplanation(child))
+	}
+	return e
+}
+
+// getFacets converts Bleve's facet results in the order of fields
+func getFacets(res bsearch.FacetResults, fields []string) []*quicknote.Facet {
+	facets := make([]*quicknote.Facet, 0, len(fields))
+	for _, field := range fields {
+		facet := &quicknote.Facet{Field: field, Terms: make([]*quicknote.FacetTerm, 0)}
+		facets = append(facets, facet)
+
+		fr, found := res[field]
+		if !found {
+			continue
+		}
+
+		for _, t := range fr.Terms {
+			facet.Terms = append(facet.Terms, &quicknote.FacetTerm{Term: t.Term, Count: t.Count})
+		}
+
+		for _, dr := range fr.DateRanges {
+			if dr.Count > 0 {
+				facet.Terms = append(facet.Terms, &quicknote.FacetTerm{Term: dr.Name, Count: dr.Count})
+			}
+		}
+		if field == quicknote.FacetCreated {
+			sort.Slice(facet.Terms, func(i, j int) bool {
+				return facet.Terms[i].Term < facet.Terms[j].Term
+			})
 		}
 	}
-	return nil
+	return facets
+}
+
+// DeleteNote deletes note from index
+func (b *Index) DeleteNote(n *quicknote.Note) error {
+	return b.shardFor(n.ID).Index.Delete(strconv.FormatInt(n.ID, 10))
 }
 
 // DeleteBook deletes all notes in the index for the notebook
 func (b *Index) DeleteBook(bk *quicknote.Book) error {
+	if err := b.checkMapping(); err != nil {
+		return err
+	}
+
 	var wg sync.WaitGroup
 
 	for _, i := range b.indexes {
@@ -419,3 +662,54 @@ func (b *Index) DeleteBook(bk *quicknote.Book) error {
 	wg.Wait()
 	return nil
 }
+
+// NoteIDs returns the IDs of all notes in the index for the
+// Book bk, or all notes if bk is nil
+func (b *Index) NoteIDs(bk *quicknote.Book) ([]int64, error) {
+	if err := b.checkMapping(); err != nil {
+		return nil, err
+	}
+
+	var q bquery.Query
+	if bk != nil {
+		q = bookQuery(bk.Name)
+	} else {
+		q = bleve.NewMatchAllQuery()
+	}
+
+	ids := make([]int64, 0)
+	var after []string
+	for {
+		search := bleve.NewSearchRequest(q)
+		search.Size = 1000
+		search.SortBy([]string{"_id"})
+		if after != nil {
+			search.SetSearchAfter(after)
+		}
+
+		res, err := b.db.Search(search)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, h := range res.Hits {
+			id, err := strconv.ParseInt(h.ID, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+
+		if len(res.Hits) < search.Size {
+			break
+		}
+		after = []string{res.Hits[len(res.Hits)-1].ID}
+	}
+
+	return ids, nil
+}
+
+// bookQuery matches the notes in the Book with exactly the name
+func bookQuery(name string) bquery.Query {
+	return keywordQuery(query.FieldBook, name, false)
+}
@@ -18,11 +18,19 @@
 package bleve
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"os"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/query"
 	"github.com/anmil/quicknote/test"
 )
 
@@ -45,10 +53,74 @@ func TestIndexNoteBleveUnit(t *testing.T) {
 	t.Run("bleve-index-notes", testIndexNotes)
 	t.Run("bleve-search-note", testSearchNote)
 	t.Run("bleve-search-phrase-note", testSearchNotePhrase)
+	t.Run("bleve-search-facets", testSearchFacets)
+	t.Run("bleve-search-query", testSearchQuery)
+	t.Run("bleve-search-sort", testSearchSort)
+	t.Run("bleve-search-explain", testSearchExplain)
+	t.Run("bleve-complete", testComplete)
+	t.Run("bleve-complete-tag-case", testCompleteTagCase)
+	t.Run("bleve-similar-notes", testSimilarNotes)
+	t.Run("bleve-fuzzy-search", testFuzzySearch)
+	t.Run("bleve-languages", testLanguages)
+	t.Run("bleve-reshard", testReshard)
+	t.Run("bleve-rebuild", testRebuild)
+	t.Run("bleve-note-ids", testNoteIDs)
+	t.Run("bleve-note-ids-overlapping-books", testNoteIDsOverlappingBooks)
 	t.Run("bleve-delete-note", testDeleteNote)
 	t.Run("bleve-delete-book", testDeleteBook)
 }
 
+func TestOutdatedMappingBleveUnit(t *testing.T) {
+	dir := path.Join(os.TempDir(), "qnote-test-mapping")
+	os.RemoveAll(dir)
+	defer os.RemoveAll(dir)
+
+	idx, err := NewIndex(dir, shardCnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := test.GetTestNotes()
+	if err = idx.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	} else if idx.Outdated() {
+		t.Fatal("Expected a new index to have the current mapping")
+	}
+	idx.Close()
+
+	// An index from before the mapping version was kept
+	if err = os.Remove(path.Join(dir, mappingVersionFile)); err != nil {
+		t.Fatal(err)
+	}
+	idx, err = NewIndex(dir, shardCnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	if !idx.Outdated() {
+		t.Fatal("Expected the index to be outdated")
+	} else if _, err = idx.SearchNote(query.MustParse("book:test"), 10, nil); err != quicknote.ErrIndexOutdated {
+		t.Fatalf("Expected ErrIndexOutdated from a search, got %v", err)
+	} else if _, err = idx.NoteIDs(notes[0].Book); err != quicknote.ErrIndexOutdated {
+		t.Fatalf("Expected ErrIndexOutdated from NoteIDs, got %v", err)
+	} else if err = idx.DeleteBook(notes[0].Book); err != quicknote.ErrIndexOutdated {
+		t.Fatalf("Expected ErrIndexOutdated from DeleteBook, got %v", err)
+	}
+
+	err = idx.Rebuild(func(next quicknote.Index) error {
+		return next.IndexNotes(notes)
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if idx.Outdated() {
+		t.Fatal("Expected the rebuilt index to have the current mapping")
+	} else if res, err := idx.SearchNote(query.MustParse("book:test"), 10, nil); err != nil {
+		t.Fatal(err)
+	} else if int(res.Total) != len(notes) {
+		t.Fatalf("Expected %d results after the rebuild, got %d", len(notes), res.Total)
+	}
+}
+
 func testIndexNote(t *testing.T) {
 	n := test.GetTestNotes()[0]
 	if err := index.IndexNote(n); err != nil {
@@ -69,15 +141,15 @@ func testSearchNote(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	query := fmt.Sprintf("+id:%d", n.ID)
-	if ids, total, err := index.SearchNote(query, 10, 0); err != nil {
+	q := query.MustParse(fmt.Sprintf("id:%d", n.ID))
+	if res, err := index.SearchNote(q, 10, nil); err != nil {
 		t.Fatal(err)
-	} else if total != 1 {
-		t.Fatalf("Expected 1 results, got %d", total)
-	} else if len(ids) != 1 {
-		t.Fatalf("Expected 1 ID, got %d", len(ids))
-	} else if ids[0] != n.ID {
-		t.Fatalf("Expected ID %d, got %d", n.ID, ids[0])
+	} else if res.Total != 1 {
+		t.Fatalf("Expected 1 results, got %d", res.Total)
+	} else if len(res.Hits) != 1 {
+		t.Fatalf("Expected 1 ID, got %d", len(res.Hits))
+	} else if res.Hits[0].ID != n.ID {
+		t.Fatalf("Expected ID %d, got %d", n.ID, res.Hits[0].ID)
 	}
 }
 
@@ -88,14 +160,593 @@ func testSearchNotePhrase(t *testing.T) {
 	}
 
 	query := "This is test 1 of the basic par"
-	if ids, total, err := index.SearchNotePhrase(query, nil, "asc", 10, 0); err != nil {
+	if res, err := index.SearchNotePhrase(query, nil, 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 1 {
+		t.Fatalf("Expected 1 results, got %d", res.Total)
+	} else if len(res.Hits) != 1 {
+		t.Fatalf("Expected 1 ID, got %d", len(res.Hits))
+	} else if res.Hits[0].ID != n.ID {
+		t.Fatalf("Expected ID %d, got %d", n.ID, res.Hits[0].ID)
+	} else if frags := res.Hits[0].Fragments["title"]; len(frags) != 1 {
+		t.Fatalf("Expected 1 title fragment, got %d", len(frags))
+	} else if !strings.Contains(frags[0], quicknote.HighlightStart+"test"+quicknote.HighlightEnd) {
+		t.Fatalf("Expected test to be highlighted in %s", frags[0])
+	}
+}
+
+func testNoteIDs(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids, err := index.NoteIDs(notes[0].Book); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != len(notes) {
+		t.Fatalf("Expected %d IDs, got %d", len(notes), len(ids))
+	}
+
+	if ids, err := index.NoteIDs(nil); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != len(notes) {
+		t.Fatalf("Expected %d IDs, got %d", len(notes), len(ids))
+	}
+}
+
+func testNoteIDsOverlappingBooks(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	// Books whose names contain the name of the test Book
+	others := make(quicknote.Notes, 0, 2)
+	for i, name := range []string{notes[0].Book.Name + " notes", "my " + notes[0].Book.Name} {
+		bk := quicknote.NewBook()
+		bk.ID = int64(100 + i)
+		bk.Name = name
+
+		n := quicknote.NewNote()
+		n.ID = int64(100 + i)
+		n.Type = notes[0].Type
+		n.Title = notes[0].Title
+		n.Body = notes[0].Body
+		n.Book = bk
+		others = append(others, n)
+	}
+	if err := index.IndexNotes(others); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, n := range others {
+			index.DeleteNote(n)
+		}
+	}()
+
+	if ids, err := index.NoteIDs(notes[0].Book); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != len(notes) {
+		t.Fatalf("Expected %d IDs, got %d", len(notes), len(ids))
+	}
+
+	for _, n := range others {
+		if ids, err := index.NoteIDs(n.Book); err != nil {
+			t.Fatal(err)
+		} else if len(ids) != 1 || ids[0] != n.ID {
+			t.Fatalf("Expected ID %d for book %s, got %v", n.ID, n.Book.Name, ids)
+		}
+	}
+
+	if err := index.DeleteBook(notes[0].Book); err != nil {
+		t.Fatal(err)
+	} else if ids, err := index.NoteIDs(nil); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != len(others) {
+		t.Fatalf("Expected %d IDs after deleting book %s, got %v", len(others), notes[0].Book.Name, ids)
+	}
+}
+
+func testSearchFacets(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &quicknote.SearchOptions{Facets: quicknote.FacetFields, FacetSize: 5}
+	q := query.MustParse(fmt.Sprintf("book:%s", notes[0].Book.Name))
+
+	res, err := index.SearchNote(q, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Facets) != len(quicknote.FacetFields) {
+		t.Fatalf("Expected %d facets, got %d", len(quicknote.FacetFields), len(res.Facets))
+	}
+
+	expected := map[string]map[string]int{
+		quicknote.FacetBook:    {"test": 3},
+		quicknote.FacetTags:    {"basic": 3, "test": 3, "parser": 3, "quis": 1},
+		quicknote.FacetType:    {"basic": 3},
+		quicknote.FacetCreated: {"2017-03": 3},
+	}
+	for _, facet := range res.Facets {
+		terms := expected[facet.Field]
+		if len(facet.Terms) != len(terms) {
+			t.Fatalf("Expected %d %s terms, got %d", len(terms), facet.Field, len(facet.Terms))
+		}
+		for _, term := range facet.Terms {
+			if terms[term.Term] != term.Count {
+				t.Fatalf("Expected %s %s count %d, got %d", facet.Field, term.Term, terms[term.Term], term.Count)
+			}
+		}
+	}
+
+	opts.Filters = []*quicknote.FacetFilter{{Field: quicknote.FacetTags, Term: "quis"}}
+	if res, err := index.SearchNote(q, 10, opts); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 1 {
+		t.Fatalf("Expected 1 results, got %d", res.Total)
+	} else if res.Hits[0].ID != notes[2].ID {
+		t.Fatalf("Expected ID %d, got %d", notes[2].ID, res.Hits[0].ID)
+	}
+
+	opts.Filters = []*quicknote.FacetFilter{{Field: quicknote.FacetCreated, Term: "2017-04"}}
+	if res, err := index.SearchNote(q, 10, opts); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 0 {
+		t.Fatalf("Expected 0 results, got %d", res.Total)
+	}
+}
+
+func testSearchQuery(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		ids   []int64
+	}{
+		{"tag:quis", []int64{605}},
+		{`title:"test 1"`, []int64{603}},
+		{"basic -tag:quis", []int64{603, 604}},
+		{"id:604 OR id:605", []int64{604, 605}},
+		{"tag:(quis OR nothing)", []int64{605}},
+		{"pars*", []int64{603, 604, 605}},
+		{"book:test AND created:<2017-03-28", []int64{603, 604, 605}},
+		{"book:Test", []int64{}},
+		{`book:"test notes"`, []int64{}},
+		{"book:te*", []int64{603, 604, 605}},
+		{"type:basic", []int64{603, 604, 605}},
+		{"type:bas", []int64{}},
+		{"created:>2017-03-27", []int64{}},
+	}
+
+	for _, tt := range tests {
+		res, err := index.SearchNote(query.MustParse(tt.query), 10, nil)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.query, err)
+		}
+
+		ids := res.IDs()
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+			t.Fatalf("%s: expected %v, got %v", tt.query, tt.ids, ids)
+		}
+	}
+}
+
+func testSearchSort(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	// 604 and 605 have the same title, the ID breaks the tie
+	tests := []struct {
+		sort string
+		ids  []int64
+	}{
+		{"created:desc", []int64{605, 604, 603}},
+		{"title", []int64{604, 605, 603}},
+		{"title:desc", []int64{603, 604, 605}},
+		{"id:desc", []int64{605, 604, 603}},
+		{"modified:desc,title", []int64{605, 604, 603}},
+	}
+
+	for _, tt := range tests {
+		so, err := quicknote.ParseSort(tt.sort, "asc")
+		if err != nil {
+			t.Fatalf("%s: %s", tt.sort, err)
+		}
+
+		// Two notes a page, following the cursors
+		opts := &quicknote.SearchOptions{Sort: so}
+		ids := make([]int64, 0)
+		for page := 0; page <= len(tt.ids); page++ {
+			res, err := index.SearchNote(query.MustParse("book:test"), 2, opts)
+			if err != nil {
+				t.Fatalf("%s: %s", tt.sort, err)
+			}
+			ids = append(ids, res.IDs()...)
+
+			if res.Next == "" {
+				break
+			}
+			opts.After = res.Next
+		}
+
+		if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+			t.Fatalf("%s: expected %v, got %v", tt.sort, tt.ids, ids)
+		}
+	}
+
+	// Relevance pages follow the order of a single page
+	q := query.MustParse("tag:quis OR pars* OR test")
+	all, err := index.SearchNote(q, 10, nil)
+	if err != nil {
 		t.Fatal(err)
-	} else if total != 1 {
-		t.Fatalf("Expected 1 results, got %d", total)
-	} else if len(ids) != 1 {
-		t.Fatalf("Expected 1 ID, got %d", len(ids))
-	} else if ids[0] != n.ID {
-		t.Fatalf("Expected ID %d, got %d", n.ID, ids[0])
+	} else if len(all.Hits) != len(notes) {
+		t.Fatalf("Expected %d relevance results, got %d", len(notes), len(all.Hits))
+	}
+	opts := &quicknote.SearchOptions{}
+	ids := make([]int64, 0)
+	for page := 0; page <= len(notes); page++ {
+		res, err := index.SearchNote(q, 1, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, res.IDs()...)
+
+		if res.Next == "" {
+			break
+		}
+		opts.After = res.Next
+	}
+	if fmt.Sprint(ids) != fmt.Sprint(all.IDs()) {
+		t.Fatalf("relevance: expected %v, got %v", all.IDs(), ids)
+	}
+
+	// A cursor from a search with another number of sort fields
+	res, err := index.SearchNote(query.MustParse("book:test"), 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	so, _ := quicknote.ParseSort("created,title", "asc")
+	opts = &quicknote.SearchOptions{Sort: so, After: res.Next}
+	if _, err := index.SearchNote(query.MustParse("book:test"), 1, opts); err != quicknote.ErrInvalidCursor {
+		t.Fatalf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func testSearchExplain(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := index.SearchNote(query.MustParse("tag:quis"), 10, &quicknote.SearchOptions{Explain: true})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Hits) != 1 {
+		t.Fatalf("Expected 1 hit, got %d", len(res.Hits))
+	}
+
+	hit := res.Hits[0]
+	if hit.Explanation == nil {
+		t.Fatal("Expected an explanation")
+	} else if math.Abs(hit.Explanation.Value-hit.Score) > 0.0001 {
+		t.Fatalf("Expected the explanation value %f to be the score %f", hit.Explanation.Value, hit.Score)
+	} else if !explains(hit.Explanation, "quis") {
+		t.Fatal("Expected the explanation to have the term quis")
+	}
+
+	if res, err = index.SearchNote(query.MustParse("tag:quis"), 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Hits[0].Explanation != nil {
+		t.Fatal("Expected no explanation")
+	}
+}
+
+// explains returns true if a message of e or its children has s
+func explains(e *quicknote.Explanation, s string) bool {
+	if strings.Contains(e.Message, s) {
+		return true
+	}
+	for _, child := range e.Children {
+		if explains(child, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func testComplete(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	tags := []struct {
+		prefix string
+		limit  int
+		tags   string
+	}{
+		{"Pa", 10, "[parser:3]"},
+		{"", 2, "[basic:3 parser:3]"},
+		{"q", 10, "[quis:1]"},
+		{"nothing", 10, "[]"},
+	}
+	for _, tt := range tags {
+		completions, err := index.CompleteTags(tt.prefix, tt.limit)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.prefix, err)
+		}
+
+		found := make([]string, len(completions))
+		for i, c := range completions {
+			found[i] = fmt.Sprintf("%s:%d", c.Text, c.Count)
+		}
+		if fmt.Sprint(found) != tt.tags {
+			t.Fatalf("%s: expected %s, got %v", tt.prefix, tt.tags, found)
+		}
+	}
+
+	titles := []struct {
+		prefix string
+		ids    []int64
+	}{
+		{"this is #", []int64{604, 605}},
+		{"THIS IS TEST", []int64{603}},
+		{"", []int64{604, 605, 603}},
+		{"test", []int64{}},
+	}
+	for _, tt := range titles {
+		completions, err := index.CompleteTitles(tt.prefix, 10)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.prefix, err)
+		}
+
+		ids := make([]int64, len(completions))
+		for i, c := range completions {
+			ids[i] = c.ID
+			if c.Text != notes[c.ID-notes[0].ID].Title {
+				t.Fatalf("%s: expected title %q, got %q", tt.prefix, notes[c.ID-notes[0].ID].Title, c.Text)
+			}
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+			t.Fatalf("%s: expected %v, got %v", tt.prefix, tt.ids, ids)
+		}
+	}
+}
+
+func testCompleteTagCase(t *testing.T) {
+	tag := quicknote.NewTag()
+	tag.Name = "ProjectX"
+
+	n := test.GetTestNotes()[0]
+	n.ID = 700
+	n.Tags = quicknote.Tags{tag}
+	if err := index.IndexNote(n); err != nil {
+		t.Fatal(err)
+	}
+	defer index.DeleteNote(n)
+	defer index.SetFoldTagCase(true)
+
+	tests := []struct {
+		fold   bool
+		prefix string
+		tags   string
+	}{
+		{false, "Proj", "[ProjectX:1]"},
+		{false, "proj", "[]"},
+		{true, "Proj", "[]"},
+	}
+	for _, tt := range tests {
+		index.SetFoldTagCase(tt.fold)
+		completions, err := index.CompleteTags(tt.prefix, 10)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.prefix, err)
+		}
+
+		found := make([]string, len(completions))
+		for i, c := range completions {
+			found[i] = fmt.Sprintf("%s:%d", c.Text, c.Count)
+		}
+		if fmt.Sprint(found) != tt.tags {
+			t.Fatalf("%s (fold %t): expected %s, got %v", tt.prefix, tt.fold, tt.tags, found)
+		}
+	}
+
+	index.SetFoldTagCase(false)
+	if res, err := index.SearchNote(query.MustParse("tag:ProjectX"), 10, nil); err != nil {
+		t.Fatal(err)
+	} else if fmt.Sprint(res.IDs()) != "[700]" {
+		t.Fatalf("tag:ProjectX: expected [700], got %v", res.IDs())
+	}
+}
+
+func testSimilarNotes(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	n := notes[2]
+	res, err := index.SimilarNotes(n, 10)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Hits) != len(notes)-1 {
+		t.Fatalf("Expected %d similar notes, got %d", len(notes)-1, len(res.Hits))
+	}
+
+	for _, id := range res.IDs() {
+		if id == n.ID {
+			t.Fatalf("Expected note %d to not be similar to itself", n.ID)
+		}
+	}
+}
+
+func testFuzzySearch(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := index.SearchNotePhrase("tesst", nil, 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 0 {
+		t.Fatalf("Expected 0 results, got %d", res.Total)
+	}
+
+	opts := &quicknote.SearchOptions{Fuzzy: true}
+	if res, err := index.SearchNotePhrase("tesst", nil, 10, opts); err != nil {
+		t.Fatal(err)
+	} else if res.Total != uint64(len(notes)) {
+		t.Fatalf("Expected %d results, got %d", len(notes), res.Total)
+	}
+
+	suggestions, err := index.SuggestQuery("tesst parser", 3)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(suggestions) == 0 || suggestions[0] != "test parser" {
+		t.Fatalf("Expected suggestion test parser, got %v", suggestions)
+	}
+
+	if suggestions, err := index.SuggestQuery("test parser", 3); err != nil {
+		t.Fatal(err)
+	} else if len(suggestions) != 0 {
+		t.Fatalf("Expected no suggestions, got %v", suggestions)
+	}
+}
+
+func testReshard(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	// Put a note in the wrong shard, like the
+	// round robin indexes did
+	n := notes[0]
+	wrong := (shardOf(strconv.FormatInt(n.ID, 10), index.shards) + 1) % index.shards
+	if err := index.shardFor(n.ID).Index.Delete(strconv.FormatInt(n.ID, 10)); err != nil {
+		t.Fatal(err)
+	} else if err = index.indexes[wrong].Index.Index(strconv.FormatInt(n.ID, 10), index.newIndexNote(n)); err != nil {
+		t.Fatal(err)
+	}
+
+	if moved, err := index.Reshard(shardCnt); err != nil {
+		t.Fatal(err)
+	} else if moved != 1 {
+		t.Fatalf("Expected 1 note moved, got %d", moved)
+	}
+
+	for _, shards := range []int{shardCnt + 2, 1, shardCnt} {
+		if _, err := index.Reshard(shards); err != nil {
+			t.Fatal(err)
+		} else if index.Shards() != shards {
+			t.Fatalf("Expected %d shards, got %d", shards, index.Shards())
+		} else if cnt := index.countShardDirs(); cnt != shards {
+			t.Fatalf("Expected %d shard directories, got %d", shards, cnt)
+		}
+
+		res, err := index.SearchNote(query.MustParse("tag:quis"), 10, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if res.Total != 1 || res.Hits[0].ID != notes[2].ID {
+			t.Fatalf("Expected note %d after resharding to %d, got %v", notes[2].ID, shards, res.IDs())
+		}
+
+		for _, n := range notes {
+			if doc, err := index.shardFor(n.ID).Index.Document(strconv.FormatInt(n.ID, 10)); err != nil {
+				t.Fatal(err)
+			} else if doc == nil {
+				t.Fatalf("Expected note %d in its shard", n.ID)
+			}
+		}
+	}
+}
+
+func testRebuild(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	errBuild := errors.New("build failed")
+	err := index.Rebuild(func(idx quicknote.Index) error {
+		if err := idx.IndexNotes(notes[:1]); err != nil {
+			return err
+		}
+		return errBuild
+	})
+	if err != errBuild {
+		t.Fatalf("Expected %v, got %v", errBuild, err)
+	} else if ids, err := index.NoteIDs(nil); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != len(notes) {
+		t.Fatalf("Expected %d notes after failed rebuild, got %d", len(notes), len(ids))
+	}
+
+	prev := index.indexPath
+	err = index.Rebuild(func(idx quicknote.Index) error {
+		return idx.IndexNotes(notes[:1])
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if ids, err := index.NoteIDs(nil); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 1 || ids[0] != notes[0].ID {
+		t.Fatalf("Expected note %d after rebuild, got %v", notes[0].ID, ids)
+	}
+
+	if gen, err := loadGeneration(tempDir); err != nil {
+		t.Fatal(err)
+	} else if path.Join(tempDir, gen) != index.indexPath || index.indexPath == prev {
+		t.Fatalf("Expected generation %s to be used, got %s", gen, index.indexPath)
+	}
+
+	if _, err := os.Stat(path.Join(prev, "index-0.bleve")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the old generation to be deleted")
+	}
+
+	files, err := ioutil.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), generationPrefix) && path.Join(tempDir, f.Name()) != index.indexPath {
+			t.Fatalf("Expected generation %s to be deleted", f.Name())
+		}
+	}
+}
+
+func testLanguages(t *testing.T) {
+	langs, err := quicknote.NewLanguages("", map[string]string{"test": "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.SetLanguages(langs)
+	defer index.SetLanguages(nil)
+
+	n := test.GetTestNotes()[0]
+	n.ID = 700
+	n.Title = "Server notes"
+	n.Body = "The deployment of the new servers"
+	if err = index.IndexNote(n); err != nil {
+		t.Fatal(err)
+	}
+	defer index.DeleteNote(n)
+	if res, err := index.SearchNotePhrase("deploying", n.Book, 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Hit(n.ID) == nil {
+		t.Fatalf("Expected note %d for deploying, got %v", n.ID, res.IDs())
+	}
+
+	if res, err := index.SearchNote(query.MustParse("body:deploying"), 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 1 || res.Hits[0].ID != n.ID {
+		t.Fatalf("Expected note %d for body:deploying, got %v", n.ID, res.IDs())
 	}
 }
 
@@ -105,26 +756,26 @@ func testDeleteNote(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	query := fmt.Sprintf("+id:%d", n.ID)
-	if ids, total, err := index.SearchNote(query, 10, 0); err != nil {
+	q := query.MustParse(fmt.Sprintf("id:%d", n.ID))
+	if res, err := index.SearchNote(q, 10, nil); err != nil {
 		t.Fatal(err)
-	} else if total != 1 {
-		t.Fatalf("Expected 1 results, got %d", total)
-	} else if len(ids) != 1 {
-		t.Fatalf("Expected 1 ID, got %d", len(ids))
-	} else if ids[0] != n.ID {
-		t.Fatalf("Expected ID %d, got %d", n.ID, ids[0])
+	} else if res.Total != 1 {
+		t.Fatalf("Expected 1 results, got %d", res.Total)
+	} else if len(res.Hits) != 1 {
+		t.Fatalf("Expected 1 ID, got %d", len(res.Hits))
+	} else if res.Hits[0].ID != n.ID {
+		t.Fatalf("Expected ID %d, got %d", n.ID, res.Hits[0].ID)
 	}
 
 	if err := index.DeleteNote(n); err != nil {
 		t.Fatal(err)
 	}
 
-	query = fmt.Sprintf("+id:%d", n.ID)
-	if _, total, err := index.SearchNote(query, 10, 0); err != nil {
+	q = query.MustParse(fmt.Sprintf("id:%d", n.ID))
+	if res, err := index.SearchNote(q, 10, nil); err != nil {
 		t.Fatal(err)
-	} else if total != 0 {
-		t.Fatalf("Expected 0 results, got %d", total)
+	} else if res.Total != 0 {
+		t.Fatalf("Expected 0 results, got %d", res.Total)
 	}
 }
 
@@ -136,23 +787,23 @@ func testDeleteBook(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	query := fmt.Sprintf("+book:%s", n.Book.Name)
-	if ids, total, err := index.SearchNote(query, 10, 0); err != nil {
+	q := query.MustParse(fmt.Sprintf("book:%s", n.Book.Name))
+	if res, err := index.SearchNote(q, 10, nil); err != nil {
 		t.Fatal(err)
-	} else if int(total) != len(notes) {
-		t.Fatalf("Expected %d results, got %d", len(notes), total)
-	} else if len(ids) != len(notes) {
-		t.Fatalf("Expected %d ID, got %d", len(notes), len(ids))
+	} else if int(res.Total) != len(notes) {
+		t.Fatalf("Expected %d results, got %d", len(notes), res.Total)
+	} else if len(res.Hits) != len(notes) {
+		t.Fatalf("Expected %d ID, got %d", len(notes), len(res.Hits))
 	}
 
 	if err := index.DeleteBook(n.Book); err != nil {
 		t.Fatal(err)
 	}
 
-	query = fmt.Sprintf("book:%s", n.Book.Name)
-	if _, total, err := index.SearchNote(query, 10, 0); err != nil {
+	q = query.MustParse(fmt.Sprintf("book:%s", n.Book.Name))
+	if res, err := index.SearchNote(q, 10, nil); err != nil {
 		t.Fatal(err)
-	} else if total != 0 {
-		t.Fatalf("Expected 0 results, got %d", total)
+	} else if res.Total != 0 {
+		t.Fatalf("Expected 0 results, got %d", res.Total)
 	}
 }
@@ -0,0 +1,141 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bleve
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anmil/quicknote"
+
+	"github.com/blevesearch/bleve"
+	bindex "github.com/blevesearch/bleve/index"
+	bquery "github.com/blevesearch/bleve/search/query"
+)
+
+// SetFoldTagCase sets whether the tags are lower case, see
+// parser.TagRules. Completions then lower case the prefix.
+func (b *Index) SetFoldTagCase(fold bool) {
+	b.foldTagCase = fold
+}
+
+// CompleteTags returns up to limit tags starting with prefix,
+// the tags on the most notes first
+func (b *Index) CompleteTags(prefix string, limit int) ([]*quicknote.Completion, error) {
+	if err := b.checkMapping(); err != nil {
+		return nil, err
+	}
+
+	// Tags are lower case unless the parsers keep their case
+	if b.foldTagCase {
+		prefix = strings.ToLower(prefix)
+	}
+
+	counts := make(map[string]uint64)
+	for _, idx := range b.indexes {
+		i, _, err := idx.Index.Advanced()
+		if err != nil {
+			return nil, err
+		}
+
+		reader, err := i.Reader()
+		if err != nil {
+			return nil, err
+		}
+
+		// The prefix dictionary is empty for an empty prefix
+		var dict bindex.FieldDict
+		if prefix == "" {
+			dict, err = reader.FieldDict(quicknote.FacetTags + facetFieldSuffix)
+		} else {
+			dict, err = reader.FieldDictPrefix(quicknote.FacetTags+facetFieldSuffix, []byte(prefix))
+		}
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+
+		entry, err := dict.Next()
+		for err == nil && entry != nil {
+			counts[entry.Term] += entry.Count
+			entry, err = dict.Next()
+		}
+		dict.Close()
+		reader.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	completions := make([]*quicknote.Completion, 0, len(counts))
+	for tag, cnt := range counts {
+		// Terms of deleted notes can be left with no notes
+		if cnt > 0 {
+			completions = append(completions, &quicknote.Completion{Text: tag, Count: int(cnt)})
+		}
+	}
+	sort.Slice(completions, func(i, j int) bool {
+		if completions[i].Count != completions[j].Count {
+			return completions[i].Count > completions[j].Count
+		}
+		return completions[i].Text < completions[j].Text
+	})
+
+	if len(completions) > limit {
+		completions = completions[:limit]
+	}
+	return completions, nil
+}
+
+// CompleteTitles returns up to limit notes with a title
+// starting with prefix, ordered by title
+func (b *Index) CompleteTitles(prefix string, limit int) ([]*quicknote.Completion, error) {
+	if err := b.checkMapping(); err != nil {
+		return nil, err
+	}
+
+	var q bquery.Query = bleve.NewMatchAllQuery()
+	if prefix != "" {
+		pq := bleve.NewPrefixQuery(strings.ToLower(prefix))
+		pq.SetField(titleSortField)
+		q = pq
+	}
+
+	search := bleve.NewSearchRequest(q)
+	search.Size = limit
+	search.Fields = []string{"title"}
+	search.SortByCustom(newSortOrder([]*quicknote.SortField{{Field: quicknote.SortTitle}}))
+
+	res, err := b.db.Search(search)
+	if err != nil {
+		return nil, err
+	}
+
+	completions := make([]*quicknote.Completion, 0, len(res.Hits))
+	for _, h := range res.Hits {
+		id, err := strconv.ParseInt(h.ID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		title, _ := h.Fields["title"].(string)
+		completions = append(completions, &quicknote.Completion{Text: title, ID: id})
+	}
+	return completions, nil
+}
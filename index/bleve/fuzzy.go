@@ -0,0 +1,164 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bleve
+
+import (
+	"sort"
+
+	"github.com/anmil/quicknote"
+
+	"github.com/blevesearch/bleve"
+	bsearch "github.com/blevesearch/bleve/search"
+	bquery "github.com/blevesearch/bleve/search/query"
+)
+
+// newFuzzyQuery returns a query matching notes with all the words of
+// text allowing for typos, see quicknote.Fuzziness. The last word
+// also matches as a prefix like the phrase prefix query.
+func (b *Index) newFuzzyQuery(text string) (bquery.Query, error) {
+	tokens, err := b.analyze(text)
+	if err != nil {
+		return nil, err
+	}
+
+	conjunction := bleve.NewConjunctionQuery()
+	for idx, t := range tokens {
+		word := string(t.Term)
+
+		disjunction := bleve.NewDisjunctionQuery()
+		for _, field := range []string{"title", "body", "tags"} {
+			q := bleve.NewFuzzyQuery(word)
+			q.SetField(field)
+			q.SetFuzziness(quicknote.Fuzziness(word))
+			disjunction.AddQuery(q)
+
+			if idx == len(tokens)-1 {
+				pq := bleve.NewPrefixQuery(word)
+				pq.SetField(field)
+				disjunction.AddQuery(pq)
+			}
+		}
+		conjunction.AddQuery(disjunction)
+	}
+
+	if len(conjunction.Conjuncts) == 0 {
+		return bleve.NewMatchNoneQuery(), nil
+	}
+	return conjunction, nil
+}
+
+// SuggestQuery returns up to limit "did you mean" queries for text. Words
+// not in the index are replaced with the indexed terms closest to them,
+// the closest and most used first. Nothing is returned when all words
+// are in the index.
+func (b *Index) SuggestQuery(text string, limit int) ([]string, error) {
+	tokens, err := b.analyze(text)
+	if err != nil {
+		return nil, err
+	}
+
+	words := make([]string, len(tokens))
+	terms := make(map[string]int, len(tokens))
+	for idx, t := range tokens {
+		words[idx] = string(t.Term)
+		terms[words[idx]]++
+	}
+
+	_, docFreqs, err := b.termDocFreqs(terms)
+	if err != nil {
+		return nil, err
+	}
+
+	corrections := make([][]string, len(words))
+	for idx, word := range words {
+		fuzziness := quicknote.Fuzziness(word)
+		if docFreqs[word] > 0 || fuzziness == 0 {
+			continue
+		}
+
+		if corrections[idx], err = b.closestTerms(word, fuzziness, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	return quicknote.CombineSuggestions(words, corrections, limit), nil
+}
+
+// closestTerms returns up to limit terms from the term dictionary of all
+// shards within fuzziness edits of word. Terms with fewer edits come
+// first, then the terms in the most documents.
+func (b *Index) closestTerms(word string, fuzziness, limit int) ([]string, error) {
+	counts := make(map[string]uint64)
+	distances := make(map[string]int)
+
+	for _, idx := range b.indexes {
+		i, _, err := idx.Index.Advanced()
+		if err != nil {
+			return nil, err
+		}
+
+		reader, err := i.Reader()
+		if err != nil {
+			return nil, err
+		}
+
+		// Not all of Bleve's index types have a fuzzy term
+		// dictionary, the whole dictionary is checked instead
+		dict, err := reader.FieldDict(allField)
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+
+		entry, err := dict.Next()
+		for err == nil && entry != nil {
+			dist, exceeded := bsearch.LevenshteinDistanceMax(word, entry.Term, fuzziness)
+			if !exceeded && dist > 0 {
+				counts[entry.Term] += entry.Count
+				distances[entry.Term] = dist
+			}
+			entry, err = dict.Next()
+		}
+		dict.Close()
+		reader.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	terms := make([]string, 0, len(counts))
+	for term := range counts {
+		terms = append(terms, term)
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		ti, tj := terms[i], terms[j]
+		if distances[ti] != distances[tj] {
+			return distances[ti] < distances[tj]
+		} else if counts[ti] != counts[tj] {
+			return counts[ti] > counts[tj]
+		}
+		return ti < tj
+	})
+	if len(terms) > limit {
+		terms = terms[:limit]
+	}
+
+	return terms, nil
+}
@@ -0,0 +1,159 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bleve
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/anmil/quicknote"
+)
+
+const (
+	// generationFile points at the directory of the generation
+	// searched. Without it the shards are in the data directory,
+	// as they were before rebuilds.
+	generationFile = "generation"
+
+	// generationPrefix of the generation directories
+	generationPrefix = "gen-"
+)
+
+// loadGeneration returns the directory name of the
+// current generation, empty for the data directory
+func loadGeneration(dataPath string) (string, error) {
+	data, err := ioutil.ReadFile(path.Join(dataPath, generationFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// saveGeneration points the index at the generation gen. The file is
+// renamed into place so it always names a complete generation.
+func saveGeneration(dataPath, gen string) error {
+	p := path.Join(dataPath, generationFile)
+	if err := ioutil.WriteFile(p+".tmp", []byte(gen), 0600); err != nil {
+		return err
+	}
+	return os.Rename(p+".tmp", p)
+}
+
+// nextGeneration returns the directory name of the generation after gen
+func nextGeneration(gen string) string {
+	n, err := strconv.Atoi(strings.TrimPrefix(gen, generationPrefix))
+	if err != nil {
+		n = 0
+	}
+	return fmt.Sprintf("%s%d", generationPrefix, n+1)
+}
+
+// removeOldGenerations deletes the generation directories other than current
+func removeOldGenerations(dataPath, current string) error {
+	files, err := ioutil.ReadDir(dataPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() && strings.HasPrefix(f.Name(), generationPrefix) && f.Name() != current {
+			if err := os.RemoveAll(path.Join(dataPath, f.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Rebuild builds a new generation of the index with build, with the same
+// number of shards. Searches use the current generation until build
+// returns, then the new generation replaces it and the current one is
+// deleted. If build fails the new generation is deleted.
+func (b *Index) Rebuild(build func(idx quicknote.Index) error) error {
+	current, err := loadGeneration(b.dataPath)
+	if err != nil {
+		return err
+	}
+	if err = removeOldGenerations(b.dataPath, current); err != nil {
+		return err
+	}
+
+	gen := nextGeneration(current)
+	next, err := openIndex(b.dataPath, path.Join(b.dataPath, gen), b.shards)
+	if err != nil {
+		return err
+	}
+
+	next.languages = b.languages
+	next.foldTagCase = b.foldTagCase
+	if err = build(next); err != nil {
+		next.Close()
+		os.RemoveAll(next.indexPath)
+		return err
+	}
+
+	if err = saveGeneration(b.dataPath, gen); err != nil {
+		next.Close()
+		os.RemoveAll(next.indexPath)
+		return err
+	}
+
+	old := *b
+	*b = *next
+
+	if err = old.Close(); err != nil {
+		return err
+	}
+	return old.removeFiles()
+}
+
+// Close closes all the shards
+func (b *Index) Close() error {
+	for _, idx := range b.indexes {
+		if err := idx.Index.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeFiles deletes the shards of a closed index
+func (b *Index) removeFiles() error {
+	if b.indexPath != b.dataPath {
+		return os.RemoveAll(b.indexPath)
+	}
+
+	for i := range b.indexes {
+		if err := os.RemoveAll(b.shardPath(i)); err != nil {
+			return err
+		}
+	}
+	err := os.Remove(path.Join(b.indexPath, shardCountFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,136 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bleve
+
+import (
+	"github.com/anmil/quicknote"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/analysis/lang/en"
+	"github.com/blevesearch/bleve/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/analysis/tokenizer/unicode"
+	// Analyzers of quicknote.SupportedLanguages
+	_ "github.com/blevesearch/bleve/analysis/lang/de"
+	_ "github.com/blevesearch/bleve/analysis/lang/es"
+	_ "github.com/blevesearch/bleve/analysis/lang/fr"
+	_ "github.com/blevesearch/bleve/analysis/lang/it"
+	_ "github.com/blevesearch/bleve/analysis/lang/nl"
+	_ "github.com/blevesearch/bleve/analysis/lang/pt"
+	"github.com/blevesearch/bleve/mapping"
+	bquery "github.com/blevesearch/bleve/search/query"
+)
+
+// Fields of the language of a note and the copies of
+// its title and body analyzed in the language
+const (
+	languageField  = "language"
+	titleStemField = "title_stem"
+	bodyStemField  = "body_stem"
+)
+
+// englishAnalyzer is Bleve's en analyzer with the Snowball (Porter2)
+// stemmer, the Porter stemmer gives deploying and deployment different stems
+const englishAnalyzer = "qnote_en"
+
+// analyzers are the Bleve analyzers of quicknote.SupportedLanguages
+// that are not named after the language
+var analyzers = map[string]string{
+	"en": englishAnalyzer,
+}
+
+// languageAnalyzer returns the name of the Bleve analyzer for lang
+func languageAnalyzer(lang string) string {
+	if name, found := analyzers[lang]; found {
+		return name
+	}
+	return lang
+}
+
+// addLanguageAnalyzers adds the custom analyzers of the languages
+func addLanguageAnalyzers(m *mapping.IndexMappingImpl) error {
+	return m.AddCustomAnalyzer(englishAnalyzer, map[string]interface{}{
+		"type":      custom.Name,
+		"tokenizer": unicode.Name,
+		"token_filters": []string{
+			en.PossessiveName,
+			lowercase.Name,
+			en.StopName,
+			en.SnowballStemmerName,
+		},
+	})
+}
+
+// SetLanguages sets the languages of the Books. Notes are indexed in the
+// language of their Book, changing it requires re-indexing the notes.
+func (b *Index) SetLanguages(l *quicknote.Languages) {
+	b.languages = l
+}
+
+// newLanguageMapping returns the mapping of notes in lang, the stem
+// fields are analyzed with the language's analyzer
+func newLanguageMapping(lang string) *mapping.DocumentMapping {
+	noteMapping := newNoteMapping()
+	for _, field := range []string{titleStemField, bodyStemField} {
+		stemMapping := bleve.NewTextFieldMapping()
+		stemMapping.Analyzer = languageAnalyzer(lang)
+		stemMapping.Store = false
+		stemMapping.IncludeInAll = false
+		stemMapping.IncludeTermVectors = false
+		noteMapping.AddFieldMappingsAt(field, stemMapping)
+	}
+	return noteMapping
+}
+
+// stemFields returns the stem fields for a query language field
+func stemFields(field string) []string {
+	switch indexFields[field] {
+	case "":
+		return []string{titleStemField, bodyStemField}
+	case "title":
+		return []string{titleStemField}
+	case "body":
+		return []string{bodyStemField}
+	}
+	return nil
+}
+
+// withStems returns q or a note in one of langs with all the words of
+// text in fields, the text analyzed in the language of the note
+func withStems(q bquery.Query, text string, fields, langs []string) bquery.Query {
+	if len(fields) == 0 || len(langs) == 0 {
+		return q
+	}
+
+	disjunction := bleve.NewDisjunctionQuery(q)
+	for _, lang := range langs {
+		fieldsQuery := bleve.NewDisjunctionQuery()
+		for _, field := range fields {
+			mq := bleve.NewMatchQuery(text)
+			mq.SetField(field)
+			mq.Analyzer = languageAnalyzer(lang)
+			mq.SetOperator(bquery.MatchQueryOperatorAnd)
+			fieldsQuery.AddQuery(mq)
+		}
+
+		langQuery := bleve.NewTermQuery(lang)
+		langQuery.SetField(languageField)
+		disjunction.AddQuery(bleve.NewConjunctionQuery(langQuery, fieldsQuery))
+	}
+	return disjunction
+}
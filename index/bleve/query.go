@@ -0,0 +1,133 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bleve
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anmil/quicknote/query"
+
+	"github.com/blevesearch/bleve"
+	bquery "github.com/blevesearch/bleve/search/query"
+)
+
+// indexFields maps the query language fields to the indexed fields
+var indexFields = map[string]string{
+	query.FieldTitle:    "title",
+	query.FieldBody:     "body",
+	query.FieldTag:      "tags",
+	query.FieldMention:  peopleField,
+	query.FieldBook:     "book",
+	query.FieldType:     "type",
+	query.FieldCreated:  "created",
+	query.FieldModified: "modified",
+	query.FieldID:       "id",
+}
+
+// keywordFields are matched against their keyword facet field, exactly
+// and with the case given, like the keyword fields in ElasticSearch
+var keywordFields = map[string]bool{
+	query.FieldBook: true,
+	query.FieldType: true,
+}
+
+// translateQuery returns the Bleve query for a parsed qnote query.
+// Terms without a field search the _all field. Words in the title
+// and body also match notes in langs with the same stem.
+func translateQuery(n query.Node, langs []string) (bquery.Query, error) {
+	switch n := n.(type) {
+	case *query.And:
+		queries, err := translateQueries(n.Nodes, langs)
+		if err != nil {
+			return nil, err
+		}
+		return bleve.NewConjunctionQuery(queries...), nil
+	case *query.Or:
+		queries, err := translateQueries(n.Nodes, langs)
+		if err != nil {
+			return nil, err
+		}
+		return bleve.NewDisjunctionQuery(queries...), nil
+	case *query.Not:
+		q, err := translateQuery(n.Node, langs)
+		if err != nil {
+			return nil, err
+		}
+		boolQuery := bleve.NewBooleanQuery()
+		boolQuery.AddMust(bleve.NewMatchAllQuery())
+		boolQuery.AddMustNot(q)
+		return boolQuery, nil
+	case *query.Term:
+		if keywordFields[n.Field] {
+			return keywordQuery(n.Field, n.Value, n.Prefix), nil
+		}
+		if n.Prefix {
+			// Prefix queries are not analyzed, the indexed terms are lower case
+			q := bleve.NewPrefixQuery(strings.ToLower(n.Value))
+			q.SetField(indexFields[n.Field])
+			return q, nil
+		}
+		q := bleve.NewMatchQuery(n.Value)
+		q.SetField(indexFields[n.Field])
+		return withStems(q, n.Value, stemFields(n.Field), langs), nil
+	case *query.Phrase:
+		if keywordFields[n.Field] {
+			return keywordQuery(n.Field, n.Value, false), nil
+		}
+		q := bleve.NewMatchPhraseQuery(n.Value)
+		q.SetField(indexFields[n.Field])
+		return q, nil
+	case *query.DateRange:
+		q := bleve.NewDateRangeQuery(n.Start, n.End)
+		q.SetField(indexFields[n.Field])
+		return q, nil
+	case *query.ID:
+		id := float64(n.ID)
+		inclusive := true
+		q := bleve.NewNumericRangeInclusiveQuery(&id, &id, &inclusive, &inclusive)
+		q.SetField(indexFields[query.FieldID])
+		return q, nil
+	}
+	return nil, fmt.Errorf("unsupported query node %T", n)
+}
+
+// keywordQuery matches the whole value, or the start of it for a
+// prefix, of the keyword facet field for field
+func keywordQuery(field, value string, prefix bool) bquery.Query {
+	if prefix {
+		q := bleve.NewPrefixQuery(value)
+		q.SetField(indexFields[field] + facetFieldSuffix)
+		return q
+	}
+	q := bleve.NewTermQuery(value)
+	q.SetField(indexFields[field] + facetFieldSuffix)
+	return q
+}
+
+func translateQueries(nodes []query.Node, langs []string) ([]bquery.Query, error) {
+	queries := make([]bquery.Query, len(nodes))
+	for idx, n := range nodes {
+		q, err := translateQuery(n, langs)
+		if err != nil {
+			return nil, err
+		}
+		queries[idx] = q
+	}
+	return queries, nil
+}
@@ -0,0 +1,331 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bleve
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/document"
+)
+
+// ErrInvalidShardCount the number of shards must be at least one
+var ErrInvalidShardCount = errors.New("bleve: shard count must be at least 1")
+
+const (
+	// shardCountFile records the number of shards of the index
+	shardCountFile = "shards"
+
+	// mappingVersionFile records the MappingVersion the shards were
+	// created with. Without it they are from before it was kept.
+	mappingVersionFile = "mapping_version"
+
+	// legacyIndexIdxFile is where the round robin shard counter was
+	// kept, before notes were routed to a shard by their ID
+	legacyIndexIdxFile = "current_index"
+
+	// reshardBatchSize number of notes moved to a shard at a time
+	reshardBatchSize = 1000
+)
+
+// shardOf returns the shard the document with id is kept in
+func shardOf(id string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// shardFor returns the shard the note with id is kept in
+func (b *Index) shardFor(id int64) *bIndex {
+	return b.indexes[shardOf(strconv.FormatInt(id, 10), b.shards)]
+}
+
+func (b *Index) shardPath(i int) string {
+	return path.Join(b.indexPath, fmt.Sprintf("index-%d.bleve", i))
+}
+
+// countShardDirs returns the number of shards in the index path
+func (b *Index) countShardDirs() int {
+	cnt := 0
+	for {
+		if _, err := os.Stat(b.shardPath(cnt)); err != nil {
+			return cnt
+		}
+		cnt++
+	}
+}
+
+// openShards opens or creates the shards up to cnt
+func (b *Index) openShards(cnt int) error {
+	for i := len(b.indexes); i < cnt; i++ {
+		indexMapping, err := newIndexMapping()
+		if err != nil {
+			return err
+		}
+
+		p := b.shardPath(i)
+		index, err := bleve.New(p, indexMapping)
+		if err == bleve.ErrorIndexPathExists {
+			index, err = bleve.Open(p)
+		}
+		if err != nil {
+			return err
+		}
+		b.indexes = append(b.indexes, &bIndex{Index: index})
+	}
+
+	b.shards = cnt
+	b.resetAlias()
+	return nil
+}
+
+// resetAlias points the alias searches use at the current shards
+func (b *Index) resetAlias() {
+	indexes := make([]bleve.Index, len(b.indexes))
+	for i, idx := range b.indexes {
+		indexes[i] = idx.Index
+	}
+	b.db = bleve.NewIndexAlias(indexes...)
+}
+
+func (b *Index) loadShardCount() (int, error) {
+	data, err := ioutil.ReadFile(path.Join(b.indexPath, shardCountFile))
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func (b *Index) saveShardCount() error {
+	s := strconv.Itoa(b.shards)
+	return ioutil.WriteFile(path.Join(b.indexPath, shardCountFile), []byte(s), 0600)
+}
+
+func (b *Index) loadMappingVersion() (int, error) {
+	data, err := ioutil.ReadFile(path.Join(b.indexPath, mappingVersionFile))
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func (b *Index) saveMappingVersion() error {
+	s := strconv.Itoa(MappingVersion)
+	return ioutil.WriteFile(path.Join(b.indexPath, mappingVersionFile), []byte(s), 0600)
+}
+
+// Shards returns the number of shards notes are split across
+func (b *Index) Shards() int {
+	return b.shards
+}
+
+// Reshard splits the notes across shards indexes, moving the notes that
+// are not in the shard their ID routes to. Shards no longer used are
+// deleted. The number of notes moved is returned.
+func (b *Index) Reshard(shards int) (int, error) {
+	if shards < 1 {
+		return 0, ErrInvalidShardCount
+	}
+
+	if err := b.openShards(maxInt(shards, len(b.indexes))); err != nil {
+		return 0, err
+	}
+
+	moves := make([][]*indexNote, shards)
+	deletes := make([][]string, len(b.indexes))
+	for i, idx := range b.indexes {
+		ids, err := idx.docIDs()
+		if err != nil {
+			return 0, err
+		}
+
+		for _, id := range ids {
+			t := shardOf(id, shards)
+			if t == i {
+				continue
+			}
+
+			doc, err := idx.Index.Document(id)
+			if err != nil {
+				return 0, err
+			} else if doc == nil {
+				continue
+			}
+
+			iN, err := documentIndexNote(doc)
+			if err != nil {
+				return 0, err
+			}
+			moves[t] = append(moves[t], iN)
+			deletes[i] = append(deletes[i], id)
+		}
+	}
+
+	// Notes are added to their new shard before they are
+	// removed from the old one so none are lost on error
+	moved := 0
+	for t, notes := range moves {
+		for len(notes) > 0 {
+			end := minInt(reshardBatchSize, len(notes))
+			batch := b.indexes[t].Index.NewBatch()
+			for _, iN := range notes[:end] {
+				if err := batch.Index(strconv.FormatInt(iN.ID, 10), iN); err != nil {
+					return moved, err
+				}
+			}
+			if err := b.indexes[t].Index.Batch(batch); err != nil {
+				return moved, err
+			}
+			moved += end
+			notes = notes[end:]
+		}
+	}
+
+	for i, ids := range deletes {
+		batch := b.indexes[i].Index.NewBatch()
+		for _, id := range ids {
+			batch.Delete(id)
+		}
+		if err := b.indexes[i].Index.Batch(batch); err != nil {
+			return moved, err
+		}
+	}
+
+	for i := shards; i < len(b.indexes); i++ {
+		if err := b.indexes[i].Index.Close(); err != nil {
+			return moved, err
+		}
+		if err := os.RemoveAll(b.shardPath(i)); err != nil {
+			return moved, err
+		}
+	}
+
+	b.indexes = b.indexes[:shards]
+	b.shards = shards
+	b.resetAlias()
+
+	return moved, b.saveShardCount()
+}
+
+// docIDs returns the IDs of all documents in the shard
+func (b *bIndex) docIDs() ([]string, error) {
+	ids := make([]string, 0)
+	var after []string
+	for {
+		search := bleve.NewSearchRequest(bleve.NewMatchAllQuery())
+		search.Size = 1000
+		search.SortBy([]string{"_id"})
+		if after != nil {
+			search.SetSearchAfter(after)
+		}
+
+		res, err := b.Index.Search(search)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, h := range res.Hits {
+			ids = append(ids, h.ID)
+		}
+
+		if len(res.Hits) < search.Size {
+			break
+		}
+		after = []string{res.Hits[len(res.Hits)-1].ID}
+	}
+	return ids, nil
+}
+
+// documentIndexNote rebuilds the indexed note from the stored fields of doc
+func documentIndexNote(doc *document.Document) (*indexNote, error) {
+	iN := &indexNote{Tags: make([]string, 0)}
+
+	var err error
+	for _, f := range doc.Fields {
+		switch field := f.(type) {
+		case *document.NumericField:
+			if field.Name() == "id" {
+				var id float64
+				id, err = field.Number()
+				iN.ID = int64(id)
+			}
+		case *document.DateTimeField:
+			var t time.Time
+			t, err = field.DateTime()
+			switch field.Name() {
+			case "created":
+				iN.Created = t
+			case "modified":
+				iN.Modified = t
+			}
+		case *document.TextField:
+			value := string(field.Value())
+			switch field.Name() {
+			case "type":
+				iN.Type = value
+			case "title":
+				iN.Title = value
+			case "body":
+				iN.Body = value
+			case "book":
+				iN.Book = value
+			case "tags":
+				iN.Tags = append(iN.Tags, value)
+			case peopleField:
+				iN.People = append(iN.People, value)
+			case languageField:
+				iN.Language = value
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if iN.ID == 0 {
+		iN.ID, err = strconv.ParseInt(doc.ID, 10, 64)
+	}
+	iN.setStems()
+	return iN, err
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,191 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bleve
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anmil/quicknote"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis"
+)
+
+// Settings for SimilarNotes, they work like the settings
+// of ElasticSearch's more_like_this query
+var (
+	// SimilarMaxTerms is the number of the note's most
+	// important terms that are searched for
+	SimilarMaxTerms = 25
+
+	// SimilarMinWordLen terms shorter than this are ignored
+	SimilarMinWordLen = 3
+)
+
+// allField is Bleve's field with the terms of all fields
+const allField = "_all"
+
+type termWeight struct {
+	term   string
+	weight float64
+}
+
+// SimilarNotes returns up to limit notes with the most important terms
+// of n. Bleve has no more like this query, so n's title, body, and tags
+// are analyzed and its terms are weighted by tf-idf using the term
+// counts of the index. The top SimilarMaxTerms are searched for.
+func (b *Index) SimilarNotes(n *quicknote.Note, limit int) (*quicknote.SearchResult, error) {
+	if err := b.checkMapping(); err != nil {
+		return nil, err
+	}
+
+	terms, err := b.noteTermWeights(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(terms) == 0 {
+		return &quicknote.SearchResult{Hits: make([]*quicknote.SearchHit, 0)}, nil
+	}
+
+	termsQuery := bleve.NewDisjunctionQuery()
+	for _, t := range terms {
+		for _, field := range []string{"title", "body", "tags"} {
+			q := bleve.NewTermQuery(t.term)
+			q.SetField(field)
+			q.SetBoost(t.weight)
+			termsQuery.AddQuery(q)
+		}
+	}
+
+	boolQuery := bleve.NewBooleanQuery()
+	boolQuery.AddMust(termsQuery)
+	boolQuery.AddMustNot(bleve.NewDocIDQuery([]string{strconv.FormatInt(n.ID, 10)}))
+
+	search := bleve.NewSearchRequest(boolQuery)
+	search.Size = limit
+
+	res, err := b.db.Search(search)
+	if err != nil {
+		return nil, err
+	}
+
+	return getSearchResult(res, nil)
+}
+
+// noteTermWeights returns the SimilarMaxTerms terms of n with the
+// highest tf-idf. Terms no other note has are left out.
+func (b *Index) noteTermWeights(n *quicknote.Note) ([]*termWeight, error) {
+	text := strings.Join(append([]string{n.Title, n.Body}, n.GetTagStringArray()...), "\n")
+
+	tokens, err := b.analyze(text)
+	if err != nil {
+		return nil, err
+	}
+
+	freqs := make(map[string]int)
+	for _, t := range tokens {
+		if utf8.RuneCount(t.Term) >= SimilarMinWordLen {
+			freqs[string(t.Term)]++
+		}
+	}
+
+	docCount, docFreqs, err := b.termDocFreqs(freqs)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := make([]*termWeight, 0, len(freqs))
+	for term, freq := range freqs {
+		df := docFreqs[term]
+		if df < 2 {
+			continue
+		}
+
+		idf := 1 + math.Log(float64(docCount)/float64(df+1))
+		terms = append(terms, &termWeight{term: term, weight: float64(freq) * idf})
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].weight == terms[j].weight {
+			return terms[i].term < terms[j].term
+		}
+		return terms[i].weight > terms[j].weight
+	})
+	if len(terms) > SimilarMaxTerms {
+		terms = terms[:SimilarMaxTerms]
+	}
+
+	return terms, nil
+}
+
+// analyze returns the terms of text using the standard analyzer
+func (b *Index) analyze(text string) (analysis.TokenStream, error) {
+	analyzer := b.indexes[0].Index.Mapping().AnalyzerNamed("standard")
+	if analyzer == nil {
+		return nil, fmt.Errorf("bleve: analyzer standard not found")
+	}
+	return analyzer.Analyze([]byte(text)), nil
+}
+
+// termDocFreqs returns the number of documents in all the
+// shards and the number of documents with each term
+func (b *Index) termDocFreqs(terms map[string]int) (uint64, map[string]uint64, error) {
+	var docCount uint64
+	docFreqs := make(map[string]uint64, len(terms))
+
+	for _, idx := range b.indexes {
+		i, _, err := idx.Index.Advanced()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		reader, err := i.Reader()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		cnt, err := reader.DocCount()
+		if err != nil {
+			reader.Close()
+			return 0, nil, err
+		}
+		docCount += cnt
+
+		for term := range terms {
+			tfr, err := reader.TermFieldReader([]byte(term), allField, false, false, false)
+			if err != nil {
+				reader.Close()
+				return 0, nil, err
+			}
+			docFreqs[term] += tfr.Count()
+			tfr.Close()
+		}
+
+		if err = reader.Close(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return docCount, docFreqs, nil
+}
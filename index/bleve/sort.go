@@ -0,0 +1,126 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bleve
+
+import (
+	"strconv"
+
+	"github.com/anmil/quicknote"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/analysis/tokenizer/single"
+	"github.com/blevesearch/bleve/mapping"
+	bsearch "github.com/blevesearch/bleve/search"
+)
+
+// titleSortField is the title indexed as one lower case
+// term, the title field is analyzed into words
+const titleSortField = "title_sort"
+
+// sortAnalyzer indexes the whole text as one lower case term
+const sortAnalyzer = "qnote_sort"
+
+// addSortAnalyzer adds the analyzer of the sort fields
+func addSortAnalyzer(m *mapping.IndexMappingImpl) error {
+	return m.AddCustomAnalyzer(sortAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     single.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+}
+
+// newTitleSortMapping returns the mapping of titleSortField
+func newTitleSortMapping() *mapping.FieldMapping {
+	sortMapping := bleve.NewTextFieldMapping()
+	sortMapping.Name = titleSortField
+	sortMapping.Analyzer = sortAnalyzer
+	sortMapping.Store = false
+	sortMapping.IncludeInAll = false
+	sortMapping.IncludeTermVectors = false
+	return sortMapping
+}
+
+// newSortOrder returns Bleve's sort order for sort
+func newSortOrder(sort []*quicknote.SortField) bsearch.SortOrder {
+	sort = quicknote.SortWithTiebreaker(sort)
+
+	order := make(bsearch.SortOrder, 0, len(sort))
+	for _, s := range sort {
+		switch s.Field {
+		case quicknote.SortRelevance:
+			order = append(order, &bsearch.SortScore{Desc: s.Desc})
+		case quicknote.SortTitle:
+			order = append(order, &bsearch.SortField{Field: titleSortField, Desc: s.Desc, Type: bsearch.SortFieldAsString})
+		case quicknote.SortID:
+			order = append(order, &bsearch.SortField{Field: "id", Desc: s.Desc, Type: bsearch.SortFieldAsNumber})
+		default:
+			order = append(order, &bsearch.SortField{Field: s.Field, Desc: s.Desc, Type: bsearch.SortFieldAsDate})
+		}
+	}
+	return order
+}
+
+// setPage sets the sort order and page of search
+func setPage(search *bleve.SearchRequest, limit int, opts *quicknote.SearchOptions) error {
+	var sort []*quicknote.SortField
+	if opts != nil {
+		sort = opts.Sort
+	}
+	search.SortByCustom(newSortOrder(sort))
+	search.Size = limit
+
+	if opts == nil || opts.After == "" {
+		return nil
+	}
+
+	// Bleve's sort values are encoded terms, not text
+	var values [][]byte
+	if err := quicknote.DecodeCursor(opts.After, &values); err != nil {
+		return err
+	} else if len(values) != len(search.Sort) {
+		return quicknote.ErrInvalidCursor
+	}
+
+	after := make([]string, len(values))
+	for i, v := range values {
+		after[i] = string(v)
+	}
+	search.SetSearchAfter(after)
+	return nil
+}
+
+// nextCursor returns the cursor of the page after res sorted by order
+func nextCursor(order bsearch.SortOrder, res *bleve.SearchResult, limit int) (string, error) {
+	if limit <= 0 || len(res.Hits) < limit {
+		return "", nil
+	}
+
+	last := res.Hits[len(res.Hits)-1]
+	values := make([][]byte, len(last.Sort))
+	for i, v := range last.Sort {
+		// The sort value of the score is "_score", Bleve
+		// reads the score to search after from the cursor
+		if order[i].RequiresScoring() {
+			v = strconv.FormatFloat(last.Score, 'g', -1, 64)
+		}
+		values[i] = []byte(v)
+	}
+	return quicknote.EncodeCursor(values)
+}
@@ -0,0 +1,130 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anmil/quicknote"
+
+	elastic "gopkg.in/olivere/elastic.v5"
+)
+
+// SetFoldTagCase sets whether the tags are lower case, see
+// parser.TagRules. Tag searches and completions then lower case
+// the tag.
+func (b *Index) SetFoldTagCase(fold bool) {
+	b.foldTagCase = fold
+}
+
+// CompleteTags returns up to limit tags starting with prefix,
+// the tags on the most notes first
+func (b *Index) CompleteTags(prefix string, limit int) ([]*quicknote.Completion, error) {
+	ctx := context.Background()
+
+	// Tags are lower case unless the parsers keep their case
+	if b.foldTagCase {
+		prefix = strings.ToLower(prefix)
+	}
+	agg := elastic.NewTermsAggregation().
+		Field("tags").
+		Include(escapeRegexp(prefix) + ".*").
+		Size(limit)
+
+	searchResult, err := b.client.Search().
+		Index(b.indexName).
+		Query(elastic.NewPrefixQuery("tags", prefix)).
+		Aggregation("tags", agg).
+		Size(0).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	completions := make([]*quicknote.Completion, 0)
+	terms, found := searchResult.Aggregations.Terms("tags")
+	if !found {
+		return completions, nil
+	}
+	for _, bucket := range terms.Buckets {
+		completions = append(completions, &quicknote.Completion{
+			Text:  fmt.Sprint(bucket.Key),
+			Count: int(bucket.DocCount),
+		})
+	}
+	return completions, nil
+}
+
+// CompleteTitles returns up to limit notes with a title
+// starting with prefix, ordered by title
+func (b *Index) CompleteTitles(prefix string, limit int) ([]*quicknote.Completion, error) {
+	ctx := context.Background()
+
+	var query elastic.Query = elastic.NewMatchAllQuery()
+	if prefix != "" {
+		query = elastic.NewPrefixQuery("title.sort", strings.ToLower(prefix))
+	}
+
+	searchResult, err := b.client.Search().
+		Index(b.indexName).
+		Query(query).
+		FetchSourceContext(elastic.NewFetchSourceContext(true).Include("title")).
+		SortBy(newSorters([]*quicknote.SortField{{Field: quicknote.SortTitle}})...).
+		Size(limit).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	completions := make([]*quicknote.Completion, 0, len(searchResult.Hits.Hits))
+	for _, h := range searchResult.Hits.Hits {
+		id, err := strconv.ParseInt(h.Id, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc struct {
+			Title string `json:"title"`
+		}
+		if h.Source != nil {
+			if err = json.Unmarshal(*h.Source, &doc); err != nil {
+				return nil, err
+			}
+		}
+		completions = append(completions, &quicknote.Completion{Text: doc.Title, ID: id})
+	}
+	return completions, nil
+}
+
+// escapeRegexp escapes the characters of s that have
+// a meaning in ElasticSearch's regular expressions
+func escapeRegexp(s string) string {
+	var buf bytes.Buffer
+	for _, r := range s {
+		if strings.ContainsRune(`.?+*|{}[]()"\#@&<>~`, r) {
+			buf.WriteRune('\\')
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
@@ -19,11 +19,14 @@ package elastic
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/query"
 
 	elastic "gopkg.in/olivere/elastic.v5"
 )
@@ -48,12 +51,56 @@ var (
 	// Slop how much slop to give when matching the order and position of the words
 	// For more details see ElasticSearch's docs on "Query-Time Search-as-You-Type"
 	Slop = 20
+
+	// SimilarMaxTerms is the number of a note's most important
+	// terms that SimilarNotes searches for
+	SimilarMaxTerms = 25
+
+	// SimilarMinWordLen terms shorter than this are ignored by SimilarNotes
+	SimilarMinWordLen = 3
+
+	// BulkActions is the number of notes IndexNotes sends in a bulk request
+	BulkActions = 500
+
+	// BulkWorkers is the number of bulk requests IndexNotes sends at a time
+	BulkWorkers = 2
 )
 
+// IndexFailure is a note ElasticSearch failed to index
+type IndexFailure struct {
+	ID     int64
+	Reason string
+}
+
+// BulkError is returned by IndexNotes when some of the notes failed to index
+type BulkError struct {
+	Failures []*IndexFailure
+}
+
+func (e *BulkError) Error() string {
+	msg := fmt.Sprintf("ElasticSearch failed to index %d notes", len(e.Failures))
+	for idx, f := range e.Failures {
+		if idx == 5 {
+			msg += ", ..."
+			break
+		}
+		msg += fmt.Sprintf(", note %d: %s", f.ID, f.Reason)
+	}
+	return msg
+}
+
 // Index provides the interface to ElasticSearch
 type Index struct {
 	client    *elastic.Client
 	indexName string
+	languages *quicknote.Languages
+
+	// foldTagCase is set when the tags are lower case
+	foldTagCase bool
+
+	// outdated is set when the notes were copied from an
+	// older mapping, see upgradeMapping
+	outdated bool
 }
 
 // NewIndex returns a new Index
@@ -72,22 +119,33 @@ func NewIndex(host, idxName string) (*Index, error) {
 		return nil, err
 	}
 
-	// Make sure our index exists
+	idx := &Index{client: client, indexName: idxName, foldTagCase: true}
+
+	// Make sure our index exists, new indexes are
+	// an alias for the first generation, see Rebuild
 	exists, err := client.IndexExists(idxName).Do(ctx)
 	if err != nil {
 		return nil, err
 	}
 	if !exists {
-		createIndex, err := client.CreateIndex(idxName).Do(ctx)
-		if err != nil {
+		gen := generationName(idxName, 1)
+		if err = idx.createIndex(ctx, gen); err != nil {
 			return nil, err
 		}
-		if !createIndex.Acknowledged {
-			return nil, errors.New("ElasticSearch failed to acknowledged the new index")
+		if _, err = client.Alias().Add(gen, idxName).Do(ctx); err != nil {
+			return nil, err
 		}
+	} else if version, copied, err := idx.mappingVersion(ctx, idxName); err != nil {
+		return nil, err
+	} else if version < MappingVersion {
+		if err = idx.upgradeMapping(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		idx.outdated = copied
 	}
 
-	return &Index{client: client, indexName: idxName}, nil
+	return idx, nil
 }
 
 // IndexNote creates or updates a note in ElasticSearch index
@@ -98,7 +156,7 @@ func (b *Index) IndexNote(n *quicknote.Note) error {
 		Index(b.indexName).
 		Type("note").
 		Id(strconv.FormatInt(n.ID, 10)).
-		BodyJson(n).
+		BodyJson(b.noteDoc(n)).
 		Do(ctx)
 	if err != nil {
 		return err
@@ -108,90 +166,356 @@ func (b *Index) IndexNote(n *quicknote.Note) error {
 }
 
 // IndexNotes creates or updates a list of notes in ElasticSearch index
+// using the bulk API, see BulkActions and BulkWorkers. All notes are sent
+// even when some fail, the notes that failed are returned in a *BulkError.
 func (b *Index) IndexNotes(notes quicknote.Notes) error {
-	for _, n := range notes {
-		b.IndexNote(n)
+	ctx := context.Background()
+
+	var mux sync.Mutex
+	var reqErr error
+	bulkErr := &BulkError{}
+
+	proc, err := b.client.BulkProcessor().
+		Name("qnote-index-notes").
+		Workers(BulkWorkers).
+		BulkActions(BulkActions).
+		After(func(executionID int64, requests []elastic.BulkableRequest, res *elastic.BulkResponse, err error) {
+			mux.Lock()
+			defer mux.Unlock()
+
+			if err != nil && reqErr == nil {
+				reqErr = err
+			}
+			if res == nil {
+				return
+			}
+			for _, item := range res.Failed() {
+				id, _ := strconv.ParseInt(item.Id, 10, 64)
+				f := &IndexFailure{ID: id, Reason: "unknown error"}
+				if item.Error != nil {
+					f.Reason = item.Error.Reason
+				}
+				bulkErr.Failures = append(bulkErr.Failures, f)
+			}
+		}).
+		Do(ctx)
+	if err != nil {
+		return err
 	}
-	return nil
-}
 
-// SearchNote sends a search query to ElasticSearch using QueryStringQuery
-func (b *Index) SearchNote(query string, limit, offset int) ([]int64, uint64, error) {
-	ctx := context.Background()
+	for _, n := range notes {
+		proc.Add(elastic.NewBulkIndexRequest().
+			Index(b.indexName).
+			Type("note").
+			Id(strconv.FormatInt(n.ID, 10)).
+			Doc(b.noteDoc(n)))
+	}
 
-	stringQuery := elastic.NewQueryStringQuery(query)
-	stringQuery.FieldWithBoost("title", TitleBoost)
-	stringQuery.FieldWithBoost("tags", TagsBoost)
-	stringQuery.FieldWithBoost("body", BodyBoost)
+	// Close sends the notes left and waits for the workers
+	if err = proc.Close(); err != nil {
+		return err
+	}
 
-	scoreSort := elastic.NewScoreSort()
-	scoreSort.Asc()
+	if len(bulkErr.Failures) > 0 {
+		return bulkErr
+	}
+	return reqErr
+}
 
-	searchResult, err := b.client.Search().
-		Index(b.indexName).
-		SortBy(scoreSort).
-		Query(stringQuery).
-		From(offset).Size(limit).
-		Do(ctx)
+// SearchNote translates the query to ElasticSearch's queries and searches the index
+func (b *Index) SearchNote(n query.Node, limit int, opts *quicknote.SearchOptions) (*quicknote.SearchResult, error) {
+	q, err := translateQuery(n, b.languages.Used(), b.foldTagCase)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	return b.getNoteIDsFromResults(searchResult)
+	return b.searchPage(q, limit, opts)
 }
 
 // SearchNotePhrase sends a search query to ElasticSearch using Phrase Prefix query
 // If bk is given, only notes for that Book are queried.
-func (b *Index) SearchNotePhrase(query string, bk *quicknote.Book, sort string, limit, offset int) ([]int64, uint64, error) {
-	ctx := context.Background()
-
+func (b *Index) SearchNotePhrase(query string, bk *quicknote.Book, limit int, opts *quicknote.SearchOptions) (*quicknote.SearchResult, error) {
 	matchPhrasePrefixQuery := elastic.NewMultiMatchQuery(query)
-	matchPhrasePrefixQuery.Type("phrase_prefix")
 	matchPhrasePrefixQuery.FieldWithBoost("title", TitleBoost)
 	matchPhrasePrefixQuery.FieldWithBoost("tags", TagsBoost)
 	matchPhrasePrefixQuery.FieldWithBoost("body", BodyBoost)
-	matchPhrasePrefixQuery.MaxExpansions(MaxExpansions)
-	matchPhrasePrefixQuery.Slop(Slop)
+
+	// phrase_prefix does not support fuzziness, fuzzy
+	// searches match all the words in any order
+	if opts != nil && opts.Fuzzy {
+		matchPhrasePrefixQuery.Type("best_fields")
+		matchPhrasePrefixQuery.Operator("and")
+		matchPhrasePrefixQuery.Fuzziness("AUTO")
+	} else {
+		matchPhrasePrefixQuery.Type("phrase_prefix")
+		matchPhrasePrefixQuery.MaxExpansions(MaxExpansions)
+		matchPhrasePrefixQuery.Slop(Slop)
+	}
 
 	boolQuery := elastic.NewBoolQuery()
-	boolQuery.Must(matchPhrasePrefixQuery)
+	if opts != nil && opts.Fuzzy {
+		boolQuery.Must(matchPhrasePrefixQuery)
+	} else {
+		boolQuery.Must(withStems(matchPhrasePrefixQuery, query, []string{"title", "body"}, b.languages.Search(bk)))
+	}
 
 	if bk != nil {
 		notebookMatchQuery := elastic.NewMatchQuery("book", bk.Name)
 		boolQuery.Must(notebookMatchQuery)
 	}
 
-	scoreSort := elastic.NewScoreSort()
+	return b.searchPage(boolQuery, limit, opts)
+}
+
+// searchPage searches a page of limit notes matching q
+func (b *Index) searchPage(q elastic.Query, limit int, opts *quicknote.SearchOptions) (*quicknote.SearchResult, error) {
+	ctx := context.Background()
+
+	search := b.client.Search().
+		Index(b.indexName).
+		Highlight(newHighlight()).
+		Explain(opts != nil && opts.Explain)
+
+	search, err := setPage(search, limit, opts)
+	if err != nil {
+		return nil, err
+	}
+	search, err = b.applySearchOptions(search, q, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	searchResult, err := search.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := b.getSearchResult(searchResult, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Next, err = nextCursor(searchResult, limit)
+	return result, err
+}
+
+// SimilarNotes returns up to limit notes like n using
+// ElasticSearch's more_like_this query
+func (b *Index) SimilarNotes(n *quicknote.Note, limit int) (*quicknote.SearchResult, error) {
+	ctx := context.Background()
+
+	item := elastic.NewMoreLikeThisQueryItem().
+		Index(b.indexName).
+		Type("note").
+		Id(strconv.FormatInt(n.ID, 10))
+
+	mltQuery := elastic.NewMoreLikeThisQuery().
+		Field("title", "body", "tags").
+		LikeItems(item).
+		MinTermFreq(1).
+		MinDocFreq(2).
+		MaxQueryTerms(SimilarMaxTerms).
+		MinWordLen(SimilarMinWordLen)
 
-	if sort == "asc" {
-		scoreSort.Asc()
+	searchResult, err := b.client.Search().
+		Index(b.indexName).
+		Query(mltQuery).
+		Size(limit).
+		Do(ctx)
+	if err != nil {
+		return nil, err
 	}
 
+	return b.getSearchResult(searchResult, nil)
+}
+
+// SuggestQuery returns up to limit "did you mean" queries for
+// text using ElasticSearch's term suggester. Nothing is returned
+// when all the words are in the index.
+func (b *Index) SuggestQuery(text string, limit int) ([]string, error) {
+	ctx := context.Background()
+
+	suggester := elastic.NewTermSuggester("did-you-mean").
+		Text(text).
+		Field("_all").
+		SuggestMode("missing").
+		Sort("score").
+		Size(limit)
+
 	searchResult, err := b.client.Search().
 		Index(b.indexName).
-		SortBy(scoreSort).
-		Query(boolQuery).
-		From(offset).Size(limit).
+		Suggester(suggester).
+		Size(0).
 		Do(ctx)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	return b.getNoteIDsFromResults(searchResult)
+	entries := searchResult.Suggest["did-you-mean"]
+	words := make([]string, len(entries))
+	corrections := make([][]string, len(entries))
+	for idx, e := range entries {
+		words[idx] = e.Text
+		for _, o := range e.Options {
+			corrections[idx] = append(corrections[idx], o.Text)
+		}
+	}
+
+	return quicknote.CombineSuggestions(words, corrections, limit), nil
+}
+
+// newHighlight returns the highlight request for the searched fields
+// using quicknote's HighlightStart and HighlightEnd markers
+func newHighlight() *elastic.Highlight {
+	return elastic.NewHighlight().
+		Fields(
+			elastic.NewHighlighterField("title"),
+			elastic.NewHighlighterField("body"),
+			elastic.NewHighlighterField("tags"),
+		).
+		PreTags(quicknote.HighlightStart).
+		PostTags(quicknote.HighlightEnd)
 }
 
-func (b *Index) getNoteIDsFromResults(sr *elastic.SearchResult) ([]int64, uint64, error) {
-	total := uint64(sr.Hits.TotalHits)
-	ids := make([]int64, 0, len(sr.Hits.Hits))
+// applySearchOptions sets query on search limited by the filters and
+// query in opts, and adds an aggregation for each facet
+func (b *Index) applySearchOptions(search *elastic.SearchService, query elastic.Query, opts *quicknote.SearchOptions) (*elastic.SearchService, error) {
+	if opts == nil {
+		return search.Query(query), nil
+	}
+
+	if len(opts.Filters) > 0 || opts.Query != nil {
+		boolQuery := elastic.NewBoolQuery().Must(query)
+		if opts.Query != nil {
+			oq, err := translateQuery(opts.Query, b.languages.Used(), b.foldTagCase)
+			if err != nil {
+				return nil, err
+			}
+			boolQuery.Must(oq)
+		}
+
+		for _, f := range opts.Filters {
+			if f.Field == quicknote.FacetCreated {
+				start, end, err := f.MonthRange()
+				if err != nil {
+					return nil, err
+				}
+				boolQuery.Filter(elastic.NewRangeQuery(quicknote.FacetCreated).
+					Gte(start.Format(time.RFC3339)).
+					Lt(end.Format(time.RFC3339)))
+			} else {
+				boolQuery.Filter(elastic.NewTermQuery(f.Field, f.Term))
+			}
+		}
+		query = boolQuery
+	}
+	search = search.Query(query)
+
+	for _, field := range opts.Facets {
+		switch field {
+		case quicknote.FacetBook, quicknote.FacetTags, quicknote.FacetType:
+			agg := elastic.NewTermsAggregation().
+				Field(field).
+				Size(opts.FacetSize)
+			search = search.Aggregation(field, agg)
+		case quicknote.FacetCreated:
+			agg := elastic.NewDateHistogramAggregation().
+				Field(quicknote.FacetCreated).
+				Interval("month").
+				Format("yyyy-MM").
+				MinDocCount(1).
+				OrderByKeyDesc()
+			search = search.Aggregation(field, agg)
+		default:
+			return nil, fmt.Errorf("can not facet on %q", field)
+		}
+	}
+
+	return search, nil
+}
+
+// getFacets converts the aggregations in the order of fields. The
+// created facet has the size newest months, oldest first.
+func getFacets(aggs elastic.Aggregations, fields []string, size int) []*quicknote.Facet {
+	facets := make([]*quicknote.Facet, 0, len(fields))
+	for _, field := range fields {
+		facet := &quicknote.Facet{Field: field, Terms: make([]*quicknote.FacetTerm, 0)}
+		facets = append(facets, facet)
+
+		if field == quicknote.FacetCreated {
+			hist, found := aggs.DateHistogram(field)
+			if !found {
+				continue
+			}
+
+			buckets := hist.Buckets
+			if len(buckets) > size {
+				buckets = buckets[:size]
+			}
+			for i := len(buckets) - 1; i >= 0; i-- {
+				if buckets[i].KeyAsString == nil {
+					continue
+				}
+				facet.Terms = append(facet.Terms, &quicknote.FacetTerm{
+					Term:  *buckets[i].KeyAsString,
+					Count: int(buckets[i].DocCount),
+				})
+			}
+			continue
+		}
+
+		terms, found := aggs.Terms(field)
+		if !found {
+			continue
+		}
+		for _, bucket := range terms.Buckets {
+			facet.Terms = append(facet.Terms, &quicknote.FacetTerm{
+				Term:  fmt.Sprint(bucket.Key),
+				Count: int(bucket.DocCount),
+			})
+		}
+	}
+	return facets
+}
+
+func (b *Index) getSearchResult(sr *elastic.SearchResult, opts *quicknote.SearchOptions) (*quicknote.SearchResult, error) {
+	result := &quicknote.SearchResult{
+		Hits:  make([]*quicknote.SearchHit, 0, len(sr.Hits.Hits)),
+		Total: uint64(sr.Hits.TotalHits),
+	}
+
 	for _, h := range sr.Hits.Hits {
 		id, err := strconv.ParseInt(h.Id, 10, 64)
 		if err != nil {
-			return nil, 0, err
+			return nil, err
+		}
+
+		hit := &quicknote.SearchHit{
+			ID:        id,
+			Fragments: map[string][]string(h.Highlight),
 		}
-		ids = append(ids, id)
+		if h.Score != nil {
+			hit.Score = *h.Score
+		}
+		if h.Explanation != nil {
+			hit.Explanation = getExplanation(h.Explanation)
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+
+	if opts != nil {
+		result.Facets = getFacets(sr.Aggregations, opts.Facets, opts.FacetSize)
 	}
 
-	return ids, total, nil
+	return result, nil
+}
+
+// getExplanation converts ElasticSearch's score explanation
+func getExplanation(expl *elastic.SearchExplanation) *quicknote.Explanation {
+	e := &quicknote.Explanation{Value: expl.Value, Message: expl.Description}
+	for i := range expl.Details {
+		e.Children = append(e.Children, getExplanation(&expl.Details[i]))
+	}
+	return e
 }
 
 // DeleteNote deletes note from index
@@ -213,8 +537,7 @@ func (b *Index) DeleteNote(n *quicknote.Note) error {
 func (b *Index) DeleteBook(bk *quicknote.Book) error {
 	ctx := context.Background()
 
-	query := fmt.Sprintf("book:%s", bk.Name)
-	deleteQuery := elastic.NewQueryStringQuery(query)
+	deleteQuery := elastic.NewTermQuery("book", bk.Name)
 	_, err := b.client.DeleteByQuery(b.indexName).
 		Type("note").
 		Query(deleteQuery).
@@ -225,18 +548,61 @@ func (b *Index) DeleteBook(bk *quicknote.Book) error {
 	return nil
 }
 
-// DeleteIndex deletes this index
+// NoteIDs returns the IDs of all notes in the index for the
+// Book bk, or all notes if bk is nil
+func (b *Index) NoteIDs(bk *quicknote.Book) ([]int64, error) {
+	ctx := context.Background()
+
+	var query elastic.Query
+	if bk != nil {
+		query = elastic.NewMatchQuery("book", bk.Name)
+	} else {
+		query = elastic.NewMatchAllQuery()
+	}
+
+	scroll := b.client.Scroll(b.indexName).
+		Type("note").
+		Query(query).
+		FetchSource(false).
+		Size(1000)
+	defer scroll.Clear(ctx)
+
+	ids := make([]int64, 0)
+	for {
+		res, err := scroll.Do(ctx)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, h := range res.Hits.Hits {
+			id, err := strconv.ParseInt(h.Id, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// DeleteIndex deletes this index and all its generations
 func (b *Index) DeleteIndex() error {
 	ctx := context.Background()
 
-	deleteIndex, err := b.client.DeleteIndex(b.indexName).Do(ctx)
+	current, concrete, err := b.generations(ctx)
 	if err != nil {
 		return err
 	}
-	if !deleteIndex.Acknowledged {
-		return errors.New("Delete Index was not acknowledged")
+	if concrete {
+		current = append(current, b.indexName)
 	}
-	return nil
+	if err = b.deleteIndexes(ctx, current...); err != nil {
+		return err
+	}
+	return b.removeOldGenerations(ctx, nil)
 }
 
 // Flush tell elasticsearch to flush any pending changes
@@ -18,10 +18,19 @@
 package elastic
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/query"
 	"github.com/anmil/quicknote/test"
+
+	elastic "gopkg.in/olivere/elastic.v5"
 )
 
 var indexName = "qnote-test"
@@ -43,6 +52,17 @@ func TestIndexNoteElasticSearchIntegration(t *testing.T) {
 	t.Run("elasticsearch-index-notes", testIndexNotes)
 	t.Run("elasticsearch-search-note", testSearchNote)
 	t.Run("elasticsearch-search-phrase-note", testSearchNotePhrase)
+	t.Run("elasticsearch-search-facets", testSearchFacets)
+	t.Run("elasticsearch-search-query", testSearchQuery)
+	t.Run("elasticsearch-search-sort", testSearchSort)
+	t.Run("elasticsearch-search-explain", testSearchExplain)
+	t.Run("elasticsearch-complete", testComplete)
+	t.Run("elasticsearch-complete-tag-case", testCompleteTagCase)
+	t.Run("elasticsearch-similar-notes", testSimilarNotes)
+	t.Run("elasticsearch-fuzzy-search", testFuzzySearch)
+	t.Run("elasticsearch-languages", testLanguages)
+	t.Run("elasticsearch-rebuild", testRebuild)
+	t.Run("elasticsearch-note-ids", testNoteIDs)
 	t.Run("elasticsearch-delete-note", testDeleteNote)
 	t.Run("elasticsearch-delete-book", testDeleteBook)
 
@@ -51,6 +71,67 @@ func TestIndexNoteElasticSearchIntegration(t *testing.T) {
 	}
 }
 
+func TestMappingUpgradeElasticSearchIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestMappingUpgradeElasticSearchIntegration in short mode")
+	}
+
+	ctx := context.Background()
+	client, err := elastic.NewClient(elastic.SetURL(indexHost))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An index from before the mapping, with a dynamically mapped note
+	name := indexName + "-upgrade"
+	if _, err = client.CreateIndex(name).Do(ctx); err != nil {
+		t.Fatal(err)
+	}
+	n := test.GetTestNotes()[0]
+	_, err = client.Index().Index(name).Type("note").Id(strconv.FormatInt(n.ID, 10)).BodyJson(n).Refresh("true").Do(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := NewIndex(indexHost, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.DeleteIndex()
+
+	if version, copied, err := idx.mappingVersion(ctx, name); err != nil {
+		t.Fatal(err)
+	} else if version != MappingVersion || !copied {
+		t.Fatalf("Expected copied mapping version %d, got %d copied %t", MappingVersion, version, copied)
+	} else if !idx.Outdated() {
+		t.Fatal("Expected the copied index to be outdated")
+	}
+
+	q := query.MustParse(fmt.Sprintf("book:%s", n.Book.Name))
+	opts := &quicknote.SearchOptions{Facets: []string{quicknote.FacetBook}, FacetSize: 5}
+	if res, err := idx.SearchNote(q, 10, opts); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 1 || res.Hits[0].ID != n.ID {
+		t.Fatalf("Expected note %d after the upgrade, got %v", n.ID, res.IDs())
+	} else if res.Facets[0].Terms[0].Term != n.Book.Name {
+		t.Fatalf("Expected book facet %s, got %s", n.Book.Name, res.Facets[0].Terms[0].Term)
+	}
+
+	// Indexing the notes again clears the copied mark
+	err = idx.Rebuild(func(next quicknote.Index) error {
+		return next.IndexNote(n)
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if idx.Outdated() {
+		t.Fatal("Expected the rebuilt index to be current")
+	} else if _, copied, err := idx.mappingVersion(ctx, name); err != nil {
+		t.Fatal(err)
+	} else if copied {
+		t.Fatal("Expected the rebuilt index not to be marked as copied")
+	}
+}
+
 func testIndexNote(t *testing.T) {
 	n := test.GetTestNotes()[0]
 	if err := index.IndexNote(n); err != nil {
@@ -73,15 +154,15 @@ func testSearchNote(t *testing.T) {
 
 	index.Flush()
 
-	query := fmt.Sprintf("id:%d", n.ID)
-	if ids, total, err := index.SearchNote(query, 10, 0); err != nil {
+	q := query.MustParse(fmt.Sprintf("id:%d", n.ID))
+	if res, err := index.SearchNote(q, 10, nil); err != nil {
 		t.Fatal(err)
-	} else if total != 1 {
-		t.Fatalf("Expected 1 results, got %d", total)
-	} else if len(ids) != 1 {
-		t.Fatalf("Expected 1 ID, got %d", len(ids))
-	} else if ids[0] != n.ID {
-		t.Fatalf("Expected ID %d, got %d", n.ID, ids[0])
+	} else if res.Total != 1 {
+		t.Fatalf("Expected 1 results, got %d", res.Total)
+	} else if len(res.Hits) != 1 {
+		t.Fatalf("Expected 1 ID, got %d", len(res.Hits))
+	} else if res.Hits[0].ID != n.ID {
+		t.Fatalf("Expected ID %d, got %d", n.ID, res.Hits[0].ID)
 	}
 }
 
@@ -94,14 +175,450 @@ func testSearchNotePhrase(t *testing.T) {
 	index.Flush()
 
 	query := "This is test 1 of the basic par"
-	if ids, total, err := index.SearchNotePhrase(query, nil, "asc", 10, 0); err != nil {
+	if res, err := index.SearchNotePhrase(query, nil, 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 1 {
+		t.Fatalf("Expected 1 results, got %d", res.Total)
+	} else if len(res.Hits) != 1 {
+		t.Fatalf("Expected 1 ID, got %d", len(res.Hits))
+	} else if res.Hits[0].ID != n.ID {
+		t.Fatalf("Expected ID %d, got %d", n.ID, res.Hits[0].ID)
+	}
+}
+
+func testNoteIDs(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+
+	index.Flush()
+
+	if ids, err := index.NoteIDs(notes[0].Book); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != len(notes) {
+		t.Fatalf("Expected %d IDs, got %d", len(notes), len(ids))
+	}
+
+	if ids, err := index.NoteIDs(nil); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != len(notes) {
+		t.Fatalf("Expected %d IDs, got %d", len(notes), len(ids))
+	}
+}
+
+func testSearchFacets(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	index.Flush()
+
+	opts := &quicknote.SearchOptions{Facets: quicknote.FacetFields, FacetSize: 5}
+	q := query.MustParse(fmt.Sprintf("book:%s", notes[0].Book.Name))
+
+	res, err := index.SearchNote(q, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Facets) != len(quicknote.FacetFields) {
+		t.Fatalf("Expected %d facets, got %d", len(quicknote.FacetFields), len(res.Facets))
+	}
+
+	expected := map[string]map[string]int{
+		quicknote.FacetBook:    {"test": 3},
+		quicknote.FacetTags:    {"basic": 3, "test": 3, "parser": 3, "quis": 1},
+		quicknote.FacetType:    {"basic": 3},
+		quicknote.FacetCreated: {"2017-03": 3},
+	}
+	for _, facet := range res.Facets {
+		terms := expected[facet.Field]
+		if len(facet.Terms) != len(terms) {
+			t.Fatalf("Expected %d %s terms, got %d", len(terms), facet.Field, len(facet.Terms))
+		}
+		for _, term := range facet.Terms {
+			if terms[term.Term] != term.Count {
+				t.Fatalf("Expected %s %s count %d, got %d", facet.Field, term.Term, terms[term.Term], term.Count)
+			}
+		}
+	}
+
+	opts.Filters = []*quicknote.FacetFilter{{Field: quicknote.FacetTags, Term: "quis"}}
+	if res, err := index.SearchNote(q, 10, opts); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 1 {
+		t.Fatalf("Expected 1 results, got %d", res.Total)
+	} else if res.Hits[0].ID != notes[2].ID {
+		t.Fatalf("Expected ID %d, got %d", notes[2].ID, res.Hits[0].ID)
+	}
+
+	opts.Filters = []*quicknote.FacetFilter{{Field: quicknote.FacetCreated, Term: "2017-04"}}
+	if res, err := index.SearchNote(q, 10, opts); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 0 {
+		t.Fatalf("Expected 0 results, got %d", res.Total)
+	}
+}
+
+func testSearchQuery(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	index.Flush()
+
+	tests := []struct {
+		query string
+		ids   []int64
+	}{
+		{"tag:quis", []int64{605}},
+		{`title:"test 1"`, []int64{603}},
+		{"basic -tag:quis", []int64{603, 604}},
+		{"id:604 OR id:605", []int64{604, 605}},
+		{"tag:(quis OR nothing)", []int64{605}},
+		{"pars*", []int64{603, 604, 605}},
+		{"book:test AND created:<2017-03-28", []int64{603, 604, 605}},
+		{"book:Test", []int64{}},
+		{`book:"test notes"`, []int64{}},
+		{"book:te*", []int64{603, 604, 605}},
+		{"type:basic", []int64{603, 604, 605}},
+		{"type:bas", []int64{}},
+		{"created:>2017-03-27", []int64{}},
+	}
+
+	for _, tt := range tests {
+		res, err := index.SearchNote(query.MustParse(tt.query), 10, nil)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.query, err)
+		}
+
+		ids := res.IDs()
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+			t.Fatalf("%s: expected %v, got %v", tt.query, tt.ids, ids)
+		}
+	}
+}
+
+func testSearchSort(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	index.Flush()
+
+	// 604 and 605 have the same title, the ID breaks the tie
+	tests := []struct {
+		sort string
+		ids  []int64
+	}{
+		{"created:desc", []int64{605, 604, 603}},
+		{"title", []int64{604, 605, 603}},
+		{"title:desc", []int64{603, 604, 605}},
+		{"id:desc", []int64{605, 604, 603}},
+		{"modified:desc,title", []int64{605, 604, 603}},
+	}
+
+	for _, tt := range tests {
+		so, err := quicknote.ParseSort(tt.sort, "asc")
+		if err != nil {
+			t.Fatalf("%s: %s", tt.sort, err)
+		}
+
+		// Two notes a page, following the cursors
+		opts := &quicknote.SearchOptions{Sort: so}
+		ids := make([]int64, 0)
+		for page := 0; page <= len(tt.ids); page++ {
+			res, err := index.SearchNote(query.MustParse("book:test"), 2, opts)
+			if err != nil {
+				t.Fatalf("%s: %s", tt.sort, err)
+			}
+			ids = append(ids, res.IDs()...)
+
+			if res.Next == "" {
+				break
+			}
+			opts.After = res.Next
+		}
+
+		if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+			t.Fatalf("%s: expected %v, got %v", tt.sort, tt.ids, ids)
+		}
+	}
+
+	// A cursor from a search with another number of sort fields
+	res, err := index.SearchNote(query.MustParse("book:test"), 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	so, _ := quicknote.ParseSort("created,title", "asc")
+	opts := &quicknote.SearchOptions{Sort: so, After: res.Next}
+	if _, err := index.SearchNote(query.MustParse("book:test"), 1, opts); err != quicknote.ErrInvalidCursor {
+		t.Fatalf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func testSearchExplain(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	index.Flush()
+
+	res, err := index.SearchNote(query.MustParse("tag:quis"), 10, &quicknote.SearchOptions{Explain: true})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Hits) != 1 {
+		t.Fatalf("Expected 1 hit, got %d", len(res.Hits))
+	}
+
+	hit := res.Hits[0]
+	if hit.Explanation == nil {
+		t.Fatal("Expected an explanation")
+	} else if math.Abs(hit.Explanation.Value-hit.Score) > 0.0001 {
+		t.Fatalf("Expected the explanation value %f to be the score %f", hit.Explanation.Value, hit.Score)
+	} else if !explains(hit.Explanation, "quis") {
+		t.Fatal("Expected the explanation to have the term quis")
+	}
+
+	if res, err = index.SearchNote(query.MustParse("tag:quis"), 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Hits[0].Explanation != nil {
+		t.Fatal("Expected no explanation")
+	}
+}
+
+// explains returns true if a message of e or its children has s
+func explains(e *quicknote.Explanation, s string) bool {
+	if strings.Contains(e.Message, s) {
+		return true
+	}
+	for _, child := range e.Children {
+		if explains(child, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func testComplete(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	index.Flush()
+
+	tags := []struct {
+		prefix string
+		limit  int
+		tags   string
+	}{
+		{"Pa", 10, "[parser:3]"},
+		{"", 2, "[basic:3 parser:3]"},
+		{"q", 10, "[quis:1]"},
+		{"nothing", 10, "[]"},
+	}
+	for _, tt := range tags {
+		completions, err := index.CompleteTags(tt.prefix, tt.limit)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.prefix, err)
+		}
+
+		found := make([]string, len(completions))
+		for i, c := range completions {
+			found[i] = fmt.Sprintf("%s:%d", c.Text, c.Count)
+		}
+		if fmt.Sprint(found) != tt.tags {
+			t.Fatalf("%s: expected %s, got %v", tt.prefix, tt.tags, found)
+		}
+	}
+
+	titles := []struct {
+		prefix string
+		ids    []int64
+	}{
+		{"this is #", []int64{604, 605}},
+		{"THIS IS TEST", []int64{603}},
+		{"", []int64{604, 605, 603}},
+		{"test", []int64{}},
+	}
+	for _, tt := range titles {
+		completions, err := index.CompleteTitles(tt.prefix, 10)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.prefix, err)
+		}
+
+		ids := make([]int64, len(completions))
+		for i, c := range completions {
+			ids[i] = c.ID
+			if c.Text != notes[c.ID-notes[0].ID].Title {
+				t.Fatalf("%s: expected title %q, got %q", tt.prefix, notes[c.ID-notes[0].ID].Title, c.Text)
+			}
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.ids) {
+			t.Fatalf("%s: expected %v, got %v", tt.prefix, tt.ids, ids)
+		}
+	}
+}
+
+func testCompleteTagCase(t *testing.T) {
+	tag := quicknote.NewTag()
+	tag.Name = "ProjectX"
+
+	n := test.GetTestNotes()[0]
+	n.ID = 700
+	n.Tags = quicknote.Tags{tag}
+	if err := index.IndexNote(n); err != nil {
 		t.Fatal(err)
-	} else if total != 1 {
-		t.Fatalf("Expected 1 results, got %d", total)
-	} else if len(ids) != 1 {
-		t.Fatalf("Expected 1 ID, got %d", len(ids))
-	} else if ids[0] != n.ID {
-		t.Fatalf("Expected ID %d, got %d", n.ID, ids[0])
+	}
+	index.Flush()
+	defer func() {
+		index.DeleteNote(n)
+		index.Flush()
+	}()
+	defer index.SetFoldTagCase(true)
+
+	tests := []struct {
+		fold   bool
+		prefix string
+		tags   string
+	}{
+		{false, "Proj", "[ProjectX:1]"},
+		{false, "proj", "[]"},
+		{true, "Proj", "[]"},
+	}
+	for _, tt := range tests {
+		index.SetFoldTagCase(tt.fold)
+		completions, err := index.CompleteTags(tt.prefix, 10)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.prefix, err)
+		}
+
+		found := make([]string, len(completions))
+		for i, c := range completions {
+			found[i] = fmt.Sprintf("%s:%d", c.Text, c.Count)
+		}
+		if fmt.Sprint(found) != tt.tags {
+			t.Fatalf("%s (fold %t): expected %s, got %v", tt.prefix, tt.fold, tt.tags, found)
+		}
+	}
+
+	index.SetFoldTagCase(false)
+	if res, err := index.SearchNote(query.MustParse("tag:ProjectX"), 10, nil); err != nil {
+		t.Fatal(err)
+	} else if fmt.Sprint(res.IDs()) != "[700]" {
+		t.Fatalf("tag:ProjectX: expected [700], got %v", res.IDs())
+	}
+}
+
+func testSimilarNotes(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	index.Flush()
+	n := notes[2]
+	res, err := index.SimilarNotes(n, 10)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Hits) != len(notes)-1 {
+		t.Fatalf("Expected %d similar notes, got %d", len(notes)-1, len(res.Hits))
+	}
+
+	for _, id := range res.IDs() {
+		if id == n.ID {
+			t.Fatalf("Expected note %d to not be similar to itself", n.ID)
+		}
+	}
+}
+
+func testFuzzySearch(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	index.Flush()
+	if res, err := index.SearchNotePhrase("tesst", nil, 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 0 {
+		t.Fatalf("Expected 0 results, got %d", res.Total)
+	}
+
+	opts := &quicknote.SearchOptions{Fuzzy: true}
+	if res, err := index.SearchNotePhrase("tesst", nil, 10, opts); err != nil {
+		t.Fatal(err)
+	} else if res.Total != uint64(len(notes)) {
+		t.Fatalf("Expected %d results, got %d", len(notes), res.Total)
+	}
+
+	suggestions, err := index.SuggestQuery("tesst parser", 3)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(suggestions) == 0 || suggestions[0] != "test parser" {
+		t.Fatalf("Expected suggestion test parser, got %v", suggestions)
+	}
+
+	if suggestions, err := index.SuggestQuery("test parser", 3); err != nil {
+		t.Fatal(err)
+	} else if len(suggestions) != 0 {
+		t.Fatalf("Expected no suggestions, got %v", suggestions)
+	}
+}
+
+func testRebuild(t *testing.T) {
+	notes := test.GetTestNotes()
+	if err := index.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	index.Flush()
+
+	err := index.Rebuild(func(idx quicknote.Index) error {
+		return idx.IndexNotes(notes[:1])
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Flush()
+
+	if ids, err := index.NoteIDs(nil); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 1 || ids[0] != notes[0].ID {
+		t.Fatalf("Expected note %d after rebuild, got %v", notes[0].ID, ids)
+	}
+
+	if current, _, err := index.generations(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if len(current) != 1 || current[0] != generationName(indexName, 2) {
+		t.Fatalf("Expected generation %s, got %v", generationName(indexName, 2), current)
+	}
+}
+
+func testLanguages(t *testing.T) {
+	langs, err := quicknote.NewLanguages("", map[string]string{"test": "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.SetLanguages(langs)
+	defer index.SetLanguages(nil)
+
+	n := test.GetTestNotes()[0]
+	n.ID = 700
+	n.Title = "Server notes"
+	n.Body = "The deployment of the new servers"
+	if err = index.IndexNote(n); err != nil {
+		t.Fatal(err)
+	}
+	defer index.DeleteNote(n)
+	index.Flush()
+	if res, err := index.SearchNotePhrase("deploying", n.Book, 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Hit(n.ID) == nil {
+		t.Fatalf("Expected note %d for deploying, got %v", n.ID, res.IDs())
+	}
+
+	if res, err := index.SearchNote(query.MustParse("body:deploying"), 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 1 || res.Hits[0].ID != n.ID {
+		t.Fatalf("Expected note %d for body:deploying, got %v", n.ID, res.IDs())
 	}
 }
 
@@ -113,15 +630,15 @@ func testDeleteNote(t *testing.T) {
 
 	index.Flush()
 
-	query := fmt.Sprintf("id:%d", n.ID)
-	if ids, total, err := index.SearchNote(query, 10, 0); err != nil {
+	q := query.MustParse(fmt.Sprintf("id:%d", n.ID))
+	if res, err := index.SearchNote(q, 10, nil); err != nil {
 		t.Fatal(err)
-	} else if total != 1 {
-		t.Fatalf("Expected 1 results, got %d", total)
-	} else if len(ids) != 1 {
-		t.Fatalf("Expected 1 ID, got %d", len(ids))
-	} else if ids[0] != n.ID {
-		t.Fatalf("Expected ID %d, got %d", n.ID, ids[0])
+	} else if res.Total != 1 {
+		t.Fatalf("Expected 1 results, got %d", res.Total)
+	} else if len(res.Hits) != 1 {
+		t.Fatalf("Expected 1 ID, got %d", len(res.Hits))
+	} else if res.Hits[0].ID != n.ID {
+		t.Fatalf("Expected ID %d, got %d", n.ID, res.Hits[0].ID)
 	}
 
 	if err := index.DeleteNote(n); err != nil {
@@ -130,11 +647,11 @@ func testDeleteNote(t *testing.T) {
 
 	index.Flush()
 
-	query = fmt.Sprintf("id:%d", n.ID)
-	if _, total, err := index.SearchNote(query, 10, 0); err != nil {
+	q = query.MustParse(fmt.Sprintf("id:%d", n.ID))
+	if res, err := index.SearchNote(q, 10, nil); err != nil {
 		t.Fatal(err)
-	} else if total != 0 {
-		t.Fatalf("Expected 0 results, got %d", total)
+	} else if res.Total != 0 {
+		t.Fatalf("Expected 0 results, got %d", res.Total)
 	}
 }
 
@@ -148,13 +665,13 @@ func testDeleteBook(t *testing.T) {
 
 	index.Flush()
 
-	query := fmt.Sprintf("book:%s", n.Book.Name)
-	if ids, total, err := index.SearchNote(query, 10, 0); err != nil {
+	q := query.MustParse(fmt.Sprintf("book:%s", n.Book.Name))
+	if res, err := index.SearchNote(q, 10, nil); err != nil {
 		t.Fatal(err)
-	} else if int(total) != len(notes) {
-		t.Fatalf("Expected %d results, got %d", len(notes), total)
-	} else if len(ids) != len(notes) {
-		t.Fatalf("Expected %d ID, got %d", len(notes), len(ids))
+	} else if int(res.Total) != len(notes) {
+		t.Fatalf("Expected %d results, got %d", len(notes), res.Total)
+	} else if len(res.Hits) != len(notes) {
+		t.Fatalf("Expected %d ID, got %d", len(notes), len(res.Hits))
 	}
 
 	if err := index.DeleteBook(n.Book); err != nil {
@@ -163,10 +680,10 @@ func testDeleteBook(t *testing.T) {
 
 	index.Flush()
 
-	query = fmt.Sprintf("book:%s", n.Book.Name)
-	if _, total, err := index.SearchNote(query, 10, 0); err != nil {
+	q = query.MustParse(fmt.Sprintf("book:%s", n.Book.Name))
+	if res, err := index.SearchNote(q, 10, nil); err != nil {
 		t.Fatal(err)
-	} else if total != 0 {
-		t.Fatalf("Expected 0 results, got %d", total)
+	} else if res.Total != 0 {
+		t.Fatalf("Expected 0 results, got %d", res.Total)
 	}
 }
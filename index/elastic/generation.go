@@ -0,0 +1,168 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package elastic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anmil/quicknote"
+)
+
+// generationName returns the name of the index for generation
+// gen, the index name given to NewIndex is an alias for it
+func generationName(alias string, gen int) string {
+	return fmt.Sprintf("%s%d", generationPrefix(alias), gen)
+}
+
+func generationPrefix(alias string) string {
+	return alias + "-gen-"
+}
+
+// generations returns the indexes behind the alias, and if the alias is
+// instead an index, as it was before rebuilds, or does not exist
+func (b *Index) generations(ctx context.Context) ([]string, bool, error) {
+	res, err := b.client.Aliases().Do(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, concrete := res.Indices[b.indexName]
+	return res.IndicesByAlias(b.indexName), concrete, nil
+}
+
+// nextGeneration returns the name of the index after the current ones
+func (b *Index) nextGeneration(current []string) string {
+	prefix := generationPrefix(b.indexName)
+
+	gen := 0
+	for _, name := range current {
+		if n, err := strconv.Atoi(strings.TrimPrefix(name, prefix)); err == nil && n > gen {
+			gen = n
+		}
+	}
+	return generationName(b.indexName, gen+1)
+}
+
+// removeOldGenerations deletes the generation indexes
+// not behind the alias left over from failed rebuilds
+func (b *Index) removeOldGenerations(ctx context.Context, current []string) error {
+	names, err := b.client.IndexNames()
+	if err != nil {
+		return err
+	}
+
+	prefix := generationPrefix(b.indexName)
+
+	old := make([]string, 0)
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !containsString(current, name) {
+			old = append(old, name)
+		}
+	}
+	return b.deleteIndexes(ctx, old...)
+}
+
+// createIndex creates the index name with newIndexMapping
+func (b *Index) createIndex(ctx context.Context, name string) error {
+	createIndex, err := b.client.CreateIndex(name).BodyJson(newIndexMapping()).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if !createIndex.Acknowledged {
+		return errors.New("ElasticSearch failed to acknowledged the new index")
+	}
+	return nil
+}
+
+func (b *Index) deleteIndexes(ctx context.Context, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	deleteIndex, err := b.client.DeleteIndex(names...).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if !deleteIndex.Acknowledged {
+		return errors.New("Delete Index was not acknowledged")
+	}
+	return nil
+}
+
+// Rebuild builds a new index with build, searches use the current index
+// until build returns. Then the alias is moved to the new index in one
+// step and the old indexes are deleted. If build fails the new index is
+// deleted.
+//
+// An index from before rebuilds is not an alias. It has to be deleted
+// before the alias is added, so it is briefly missing the first time.
+func (b *Index) Rebuild(build func(idx quicknote.Index) error) error {
+	ctx := context.Background()
+
+	current, concrete, err := b.generations(ctx)
+	if err != nil {
+		return err
+	}
+	if err = b.removeOldGenerations(ctx, current); err != nil {
+		return err
+	}
+
+	gen := b.nextGeneration(current)
+	if err = b.createIndex(ctx, gen); err != nil {
+		return err
+	}
+
+	next := &Index{client: b.client, indexName: gen, languages: b.languages, foldTagCase: b.foldTagCase}
+	if err = build(next); err == nil {
+		err = next.Flush()
+	}
+	if err != nil {
+		b.deleteIndexes(ctx, gen)
+		return err
+	}
+
+	if concrete {
+		if err = b.deleteIndexes(ctx, b.indexName); err != nil {
+			return err
+		}
+	}
+
+	alias := b.client.Alias().Add(gen, b.indexName)
+	for _, name := range current {
+		alias.Remove(name, b.indexName)
+	}
+	if _, err = alias.Do(ctx); err != nil {
+		return err
+	}
+	b.outdated = false
+
+	return b.deleteIndexes(ctx, current...)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package elastic
+
+import (
+	"fmt"
+
+	"github.com/anmil/quicknote"
+
+	elastic "gopkg.in/olivere/elastic.v5"
+)
+
+// SetLanguages sets the languages of the Books. Notes are indexed in the
+// language of their Book, changing it requires re-indexing the notes.
+func (b *Index) SetLanguages(l *quicknote.Languages) {
+	b.languages = l
+}
+
+// languageField returns the field with field analyzed in lang
+func languageField(field, lang string) string {
+	return fmt.Sprintf("%s_%s", field, lang)
+}
+
+// noteDoc returns the document indexed for n, the same as the note's
+// JSON with its title and body in the fields of its Book's language
+func (b *Index) noteDoc(n *quicknote.Note) map[string]interface{} {
+	doc := map[string]interface{}{
+		"id":       n.ID,
+		"created":  n.Created,
+		"modified": n.Modified,
+		"type":     n.Type,
+		"title":    n.Title,
+		"body":     n.Body,
+		"book":     n.Book.Name,
+		"tags":     n.GetTagStringArray(),
+		"people":   n.People,
+	}
+
+	if lang := b.languages.Book(n.Book.Name); lang != "" {
+		doc["language"] = lang
+		doc[languageField("title", lang)] = n.Title
+		doc[languageField("body", lang)] = n.Body
+	}
+	return doc
+}
+
+// stemFields returns the indexed fields for a query language field
+func stemFields(field string) []string {
+	switch indexFields[field] {
+	case "":
+		return []string{"title", "body"}
+	case "title", "body":
+		return []string{indexFields[field]}
+	}
+	return nil
+}
+
+// withStems returns q or a note in one of langs with all the words of
+// text in fields, the text is analyzed by the language's fields
+func withStems(q elastic.Query, text string, fields, langs []string) elastic.Query {
+	if len(fields) == 0 || len(langs) == 0 {
+		return q
+	}
+
+	boolQuery := elastic.NewBoolQuery().Should(q).MinimumNumberShouldMatch(1)
+	for _, lang := range langs {
+		mq := elastic.NewMultiMatchQuery(text).Operator("and")
+		for _, field := range fields {
+			mq.Field(languageField(field, lang))
+		}
+		boolQuery.Should(mq)
+	}
+	return boolQuery
+}
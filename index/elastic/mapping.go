@@ -0,0 +1,189 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package elastic
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anmil/quicknote"
+)
+
+// MappingVersion is the version of newIndexMapping, increase it when
+// the mapping changes. Indexes with an older mapping are copied to
+// the new one when they are opened, see upgradeMapping.
+const MappingVersion = 4
+
+// analyzers are ElasticSearch's analyzers of quicknote.SupportedLanguages.
+// qnote_en is the english analyzer with the Porter2 stemmer, the english
+// stemmer gives deploying and deployment different stems.
+var analyzers = map[string]string{
+	"de": "german",
+	"en": "qnote_en",
+	"es": "spanish",
+	"fr": "french",
+	"it": "italian",
+	"nl": "dutch",
+	"pt": "portuguese",
+}
+
+// newIndexMapping returns the settings and mapping new indexes are created
+// with. Book, tags, people and type are matched as whole terms, title and body are
+// analyzed with qnote_text so accented letters match without them. The
+// title and body of notes in a language are also in the language's fields.
+// title.sort is the whole title as one lower case term for sorting.
+func newIndexMapping() map[string]interface{} {
+	properties := map[string]interface{}{
+		"id":       map[string]string{"type": "long"},
+		"created":  map[string]string{"type": "date"},
+		"modified": map[string]string{"type": "date"},
+		"type":     map[string]string{"type": "keyword"},
+		"book":     map[string]string{"type": "keyword"},
+		"tags":     map[string]string{"type": "keyword"},
+		"people":   map[string]string{"type": "keyword", "normalizer": "qnote_sort"},
+		"language": map[string]string{"type": "keyword"},
+		"title": map[string]interface{}{
+			"type":     "text",
+			"analyzer": "qnote_text",
+			"fields": map[string]interface{}{
+				"sort": map[string]string{"type": "keyword", "normalizer": "qnote_sort"},
+			},
+		},
+		"body": map[string]string{"type": "text", "analyzer": "qnote_text"},
+	}
+	for _, lang := range quicknote.SupportedLanguages {
+		for _, field := range []string{"title", "body"} {
+			properties[languageField(field, lang)] = map[string]interface{}{
+				"type":           "text",
+				"analyzer":       analyzers[lang],
+				"include_in_all": false,
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"settings": map[string]interface{}{
+			"analysis": map[string]interface{}{
+				"analyzer": map[string]interface{}{
+					"qnote_text": map[string]interface{}{
+						"type":      "custom",
+						"tokenizer": "standard",
+						"filter":    []string{"lowercase", "asciifolding"},
+					},
+					"qnote_en": map[string]interface{}{
+						"type":      "custom",
+						"tokenizer": "standard",
+						"filter":    []string{"qnote_en_possessive", "lowercase", "qnote_en_stop", "qnote_en_stemmer"},
+					},
+				},
+				"normalizer": map[string]interface{}{
+					"qnote_sort": map[string]interface{}{
+						"type":   "custom",
+						"filter": []string{"lowercase", "asciifolding"},
+					},
+				},
+				"filter": map[string]interface{}{
+					"qnote_en_possessive": map[string]string{"type": "stemmer", "language": "possessive_english"},
+					"qnote_en_stop":       map[string]string{"type": "stop", "stopwords": "_english_"},
+					"qnote_en_stemmer":    map[string]string{"type": "stemmer", "language": "porter2"},
+				},
+			},
+		},
+		"mappings": map[string]interface{}{
+			"note": map[string]interface{}{
+				"_meta":      map[string]int{"version": MappingVersion},
+				"properties": properties,
+			},
+		},
+	}
+}
+
+// mappingVersion returns the lowest mapping version of the indexes
+// behind name, indexes created without a version are version 0.
+// copied is set when one of them was copied from an older mapping.
+func (b *Index) mappingVersion(ctx context.Context, name string) (version int, copied bool, err error) {
+	res, err := b.client.GetMapping().Index(name).Type("note").Do(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	version = MappingVersion
+	for _, idx := range res {
+		v := 0
+		if m, ok := idx.(map[string]interface{}); ok {
+			mappings, _ := m["mappings"].(map[string]interface{})
+			note, _ := mappings["note"].(map[string]interface{})
+			meta, _ := note["_meta"].(map[string]interface{})
+			if f, ok := meta["version"].(float64); ok {
+				v = int(f)
+			}
+			if c, ok := meta["copied"].(bool); ok && c {
+				copied = true
+			}
+		}
+		if v < version {
+			version = v
+		}
+	}
+	return version, copied, nil
+}
+
+// upgradeMapping rebuilds the index with the current mapping by copying
+// the notes to a new generation inside ElasticSearch. The copies do not
+// have the fields added since, like the language fields and people, so
+// the new generation is marked as copied and the index is Outdated
+// until the notes are indexed again from the database.
+func (b *Index) upgradeMapping(ctx context.Context) error {
+	err := b.Rebuild(func(idx quicknote.Index) error {
+		next := idx.(*Index)
+
+		res, err := b.client.Reindex().
+			SourceIndex(b.indexName).
+			DestinationIndex(next.indexName).
+			Refresh("true").
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+		if len(res.Failures) > 0 {
+			return fmt.Errorf("ElasticSearch failed to copy %d notes to the new mapping, first note %s",
+				len(res.Failures), res.Failures[0].Id)
+		}
+
+		meta := map[string]interface{}{"version": MappingVersion, "copied": true}
+		_, err = b.client.PutMapping().
+			Index(next.indexName).
+			Type("note").
+			BodyJson(map[string]interface{}{"_meta": meta}).
+			Do(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	b.outdated = true
+	return nil
+}
+
+// Outdated returns whether the notes were copied from an index with an
+// older mapping, they are missing the fields added since. Searches still
+// work, but do not use the missing fields until the notes are re-indexed.
+func (b *Index) Outdated() bool {
+	return b.outdated
+}
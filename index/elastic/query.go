@@ -0,0 +1,148 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package elastic
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anmil/quicknote/query"
+
+	elastic "gopkg.in/olivere/elastic.v5"
+)
+
+// indexFields maps the query language fields to the indexed fields
+var indexFields = map[string]string{
+	query.FieldTitle:    "title",
+	query.FieldBody:     "body",
+	query.FieldTag:      "tags",
+	query.FieldMention:  "people",
+	query.FieldBook:     "book",
+	query.FieldType:     "type",
+	query.FieldCreated:  "created",
+	query.FieldModified: "modified",
+	query.FieldID:       "id",
+}
+
+// lowerCaseFields are the fields with lower case terms, the title and
+// body are analyzed and tags and people are lower cased by the parser.
+// Tags keep their case when the parser does not fold it, see
+// lowerCase. Book and type are keywords matched as given.
+var lowerCaseFields = map[string]bool{
+	query.FieldTitle:   true,
+	query.FieldBody:    true,
+	query.FieldTag:     true,
+	query.FieldMention: true,
+}
+
+// lowerCase returns whether the terms of field are lower case,
+// foldTags is set when the tags are lower case
+func lowerCase(field string, foldTags bool) bool {
+	if field == query.FieldTag {
+		return foldTags
+	}
+	return lowerCaseFields[field]
+}
+
+// translateQuery returns the ElasticSearch query for a parsed qnote
+// query. Terms without a field search title, tags and body. Words in
+// the title and body also match notes in langs with the same stem.
+// Tags are lower cased when foldTags is set.
+func translateQuery(n query.Node, langs []string, foldTags bool) (elastic.Query, error) {
+	switch n := n.(type) {
+	case *query.And:
+		queries, err := translateQueries(n.Nodes, langs, foldTags)
+		if err != nil {
+			return nil, err
+		}
+		return elastic.NewBoolQuery().Must(queries...), nil
+	case *query.Or:
+		queries, err := translateQueries(n.Nodes, langs, foldTags)
+		if err != nil {
+			return nil, err
+		}
+		return elastic.NewBoolQuery().Should(queries...).MinimumNumberShouldMatch(1), nil
+	case *query.Not:
+		q, err := translateQuery(n.Node, langs, foldTags)
+		if err != nil {
+			return nil, err
+		}
+		return elastic.NewBoolQuery().MustNot(q), nil
+	case *query.Term:
+		if len(n.Field) == 0 {
+			mq := newMultiMatchQuery(n.Value)
+			if n.Prefix {
+				mq.Type("phrase_prefix").MaxExpansions(MaxExpansions)
+				return mq, nil
+			}
+			return withStems(mq, n.Value, stemFields(n.Field), langs), nil
+		}
+		// Prefix queries and keyword fields are not analyzed
+		value := n.Value
+		if lowerCase(n.Field, foldTags) {
+			value = strings.ToLower(value)
+		}
+		if n.Prefix {
+			return elastic.NewPrefixQuery(indexFields[n.Field], value), nil
+		}
+		return withStems(elastic.NewMatchQuery(indexFields[n.Field], value), n.Value, stemFields(n.Field), langs), nil
+	case *query.Phrase:
+		if len(n.Field) == 0 {
+			return newMultiMatchQuery(n.Value).Type("phrase"), nil
+		}
+		value := n.Value
+		if lowerCase(n.Field, foldTags) {
+			value = strings.ToLower(value)
+		}
+		return elastic.NewMatchPhraseQuery(indexFields[n.Field], value), nil
+	case *query.DateRange:
+		q := elastic.NewRangeQuery(indexFields[n.Field])
+		if !n.Start.IsZero() {
+			q.Gte(n.Start.Format(time.RFC3339Nano))
+		}
+		if !n.End.IsZero() {
+			q.Lt(n.End.Format(time.RFC3339Nano))
+		}
+		return q, nil
+	case *query.ID:
+		return elastic.NewTermQuery(indexFields[query.FieldID], n.ID), nil
+	}
+	return nil, fmt.Errorf("unsupported query node %T", n)
+}
+
+func translateQueries(nodes []query.Node, langs []string, foldTags bool) ([]elastic.Query, error) {
+	queries := make([]elastic.Query, len(nodes))
+	for idx, n := range nodes {
+		q, err := translateQuery(n, langs, foldTags)
+		if err != nil {
+			return nil, err
+		}
+		queries[idx] = q
+	}
+	return queries, nil
+}
+
+// newMultiMatchQuery returns a query for text in the title, tags and body
+func newMultiMatchQuery(text string) *elastic.MultiMatchQuery {
+	mq := elastic.NewMultiMatchQuery(text)
+	mq.FieldWithBoost("title", TitleBoost)
+	mq.FieldWithBoost("tags", TagsBoost)
+	mq.FieldWithBoost("body", BodyBoost)
+	return mq
+}
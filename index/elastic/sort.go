@@ -0,0 +1,73 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package elastic
+
+import (
+	"github.com/anmil/quicknote"
+
+	elastic "gopkg.in/olivere/elastic.v5"
+)
+
+// newSorters returns ElasticSearch's sorters for sort
+func newSorters(sort []*quicknote.SortField) []elastic.Sorter {
+	sort = quicknote.SortWithTiebreaker(sort)
+
+	sorters := make([]elastic.Sorter, 0, len(sort))
+	for _, s := range sort {
+		switch s.Field {
+		case quicknote.SortRelevance:
+			sorters = append(sorters, elastic.NewScoreSort().Order(!s.Desc))
+		case quicknote.SortTitle:
+			sorters = append(sorters, elastic.NewFieldSort("title.sort").Order(!s.Desc))
+		default:
+			sorters = append(sorters, elastic.NewFieldSort(s.Field).Order(!s.Desc))
+		}
+	}
+	return sorters
+}
+
+// setPage sets the sort order and page of search
+func setPage(search *elastic.SearchService, limit int, opts *quicknote.SearchOptions) (*elastic.SearchService, error) {
+	var sort []*quicknote.SortField
+	if opts != nil {
+		sort = opts.Sort
+	}
+	sorters := newSorters(sort)
+	// Scores are only computed when sorted by relevance without TrackScores
+	search = search.SortBy(sorters...).TrackScores(true).Size(limit)
+
+	if opts == nil || opts.After == "" {
+		return search, nil
+	}
+
+	var values []interface{}
+	if err := quicknote.DecodeCursor(opts.After, &values); err != nil {
+		return nil, err
+	} else if len(values) != len(sorters) {
+		return nil, quicknote.ErrInvalidCursor
+	}
+	return search.SearchAfter(values...), nil
+}
+
+// nextCursor returns the cursor of the page after sr
+func nextCursor(sr *elastic.SearchResult, limit int) (string, error) {
+	if limit <= 0 || len(sr.Hits.Hits) < limit {
+		return "", nil
+	}
+	return quicknote.EncodeCursor(sr.Hits.Hits[len(sr.Hits.Hits)-1].Sort)
+}
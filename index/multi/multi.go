@@ -0,0 +1,199 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package multi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/query"
+)
+
+// ErrNoIndexes is returned by NewIndex when it is given no indexes
+var ErrNoIndexes = errors.New("multi index needs at least one index")
+
+// Index writes notes to all of its indexes and searches the primary.
+// It is used to move to another index provider while searching the
+// current one.
+type Index struct {
+	names   []string
+	indexes []quicknote.Index
+	primary int
+}
+
+// NewIndex returns an Index for indexes, names are the names of the
+// providers of the indexes and primary the name of the one searched
+func NewIndex(names []string, indexes []quicknote.Index, primary string) (*Index, error) {
+	if len(indexes) == 0 {
+		return nil, ErrNoIndexes
+	} else if len(names) != len(indexes) {
+		return nil, errors.New("multi index needs a name for each index")
+	}
+
+	for i, name := range names {
+		if name == primary {
+			return &Index{names: names, indexes: indexes, primary: i}, nil
+		}
+	}
+	return nil, fmt.Errorf("multi index primary %q is not one of its indexes", primary)
+}
+
+// Primary returns the name of the provider that is searched
+func (m *Index) Primary() string {
+	return m.names[m.primary]
+}
+
+// each calls f for all the indexes, the indexes after a failed one are
+// still written to. The first error is returned with the provider name.
+func (m *Index) each(f func(idx quicknote.Index) error) error {
+	var firstErr error
+	for i, idx := range m.indexes {
+		if err := f(idx); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %s", m.names[i], err)
+		}
+	}
+	return firstErr
+}
+
+// IndexNote creates or updates a note in all the indexes
+func (m *Index) IndexNote(n *quicknote.Note) error {
+	return m.each(func(idx quicknote.Index) error { return idx.IndexNote(n) })
+}
+
+// IndexNotes creates or updates a list of notes in all the indexes
+func (m *Index) IndexNotes(notes quicknote.Notes) error {
+	return m.each(func(idx quicknote.Index) error { return idx.IndexNotes(notes) })
+}
+
+// SearchNote searches the primary index
+func (m *Index) SearchNote(q query.Node, limit int, opts *quicknote.SearchOptions) (*quicknote.SearchResult, error) {
+	return m.indexes[m.primary].SearchNote(q, limit, opts)
+}
+
+// SearchNotePhrase searches the primary index
+func (m *Index) SearchNotePhrase(query string, bk *quicknote.Book, limit int, opts *quicknote.SearchOptions) (*quicknote.SearchResult, error) {
+	return m.indexes[m.primary].SearchNotePhrase(query, bk, limit, opts)
+}
+
+// SimilarNotes returns the notes like n in the primary index
+func (m *Index) SimilarNotes(n *quicknote.Note, limit int) (*quicknote.SearchResult, error) {
+	return m.indexes[m.primary].SimilarNotes(n, limit)
+}
+
+// SuggestQuery returns the suggestions of the primary index
+func (m *Index) SuggestQuery(text string, limit int) ([]string, error) {
+	return m.indexes[m.primary].SuggestQuery(text, limit)
+}
+
+// CompleteTags returns the tag completions of the primary index
+func (m *Index) CompleteTags(prefix string, limit int) ([]*quicknote.Completion, error) {
+	return m.indexes[m.primary].CompleteTags(prefix, limit)
+}
+
+// CompleteTitles returns the title completions of the primary index
+func (m *Index) CompleteTitles(prefix string, limit int) ([]*quicknote.Completion, error) {
+	return m.indexes[m.primary].CompleteTitles(prefix, limit)
+}
+
+// DeleteNote deletes note from all the indexes
+func (m *Index) DeleteNote(n *quicknote.Note) error {
+	return m.each(func(idx quicknote.Index) error { return idx.DeleteNote(n) })
+}
+
+// DeleteBook deletes all notes in the Book from all the indexes
+func (m *Index) DeleteBook(bk *quicknote.Book) error {
+	return m.each(func(idx quicknote.Index) error { return idx.DeleteBook(bk) })
+}
+
+// NoteIDs returns the IDs of the notes in the primary index
+func (m *Index) NoteIDs(bk *quicknote.Book) ([]int64, error) {
+	return m.indexes[m.primary].NoteIDs(bk)
+}
+
+// Close closes the indexes whose providers keep files open
+func (m *Index) Close() error {
+	return m.each(func(idx quicknote.Index) error {
+		if c, ok := idx.(io.Closer); ok {
+			return c.Close()
+		}
+		return nil
+	})
+}
+
+// Rebuild rebuilds all the indexes. Indexes that can not be built next to
+// the current one are built in place, build is called with the index.
+func (m *Index) Rebuild(build func(idx quicknote.Index) error) error {
+	return m.each(func(idx quicknote.Index) error {
+		if r, ok := idx.(quicknote.Rebuilder); ok {
+			return r.Rebuild(build)
+		}
+		return build(idx)
+	})
+}
+
+// SetLanguages sets the languages of the indexes that use them
+func (m *Index) SetLanguages(l *quicknote.Languages) {
+	for _, idx := range m.indexes {
+		if li, ok := idx.(quicknote.LanguageIndex); ok {
+			li.SetLanguages(l)
+		}
+	}
+}
+
+// Outdated returns whether one of the indexes was
+// created with an older mapping
+func (m *Index) Outdated() bool {
+	for _, idx := range m.indexes {
+		if oi, ok := idx.(quicknote.OutdatedIndex); ok && oi.Outdated() {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFoldTagCase sets whether the tags are lower case
+// in the indexes that lower case them
+func (m *Index) SetFoldTagCase(fold bool) {
+	for _, idx := range m.indexes {
+		if ti, ok := idx.(quicknote.TagCaseIndex); ok {
+			ti.SetFoldTagCase(fold)
+		}
+	}
+}
+
+// Compare runs search on each index, the primary first
+func (m *Index) Compare(search func(idx quicknote.Index) (*quicknote.SearchResult, error)) ([]*quicknote.ComparedResult, error) {
+	order := []int{m.primary}
+	for i := range m.indexes {
+		if i != m.primary {
+			order = append(order, i)
+		}
+	}
+
+	compared := make([]*quicknote.ComparedResult, 0, len(m.indexes))
+	for _, i := range order {
+		res, err := search(m.indexes[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", m.names[i], err)
+		}
+		compared = append(compared, &quicknote.ComparedResult{Name: m.names[i], Result: res})
+	}
+	return compared, nil
+}
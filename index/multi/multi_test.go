@@ -0,0 +1,127 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package multi
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/anmil/quicknote"
+	"github.com/anmil/quicknote/index/bleve"
+	"github.com/anmil/quicknote/query"
+	"github.com/anmil/quicknote/test"
+)
+
+func TestMultiIndexUnit(t *testing.T) {
+	dir1, err := ioutil.TempDir("", "qnote-multi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir1)
+	dir2, err := ioutil.TempDir("", "qnote-multi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir2)
+
+	idx1, err := bleve.NewIndex(dir1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx2, err := bleve.NewIndex(dir2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"one", "two"}
+	indexes := []quicknote.Index{idx1, idx2}
+	if _, err = NewIndex(names, indexes, "three"); err == nil {
+		t.Fatal("Expected an error for an unknown primary")
+	} else if _, err = NewIndex(nil, nil, "one"); err != ErrNoIndexes {
+		t.Fatalf("Expected ErrNoIndexes, got %v", err)
+	}
+
+	m, err := NewIndex(names, indexes, "two")
+	if err != nil {
+		t.Fatal(err)
+	} else if m.Primary() != "two" {
+		t.Fatalf("Expected primary two, got %s", m.Primary())
+	}
+
+	// Writes go to all the indexes
+	notes := test.GetTestNotes()
+	if err = m.IndexNotes(notes); err != nil {
+		t.Fatal(err)
+	}
+	for i, idx := range indexes {
+		if ids, err := idx.NoteIDs(nil); err != nil {
+			t.Fatal(err)
+		} else if len(ids) != len(notes) {
+			t.Fatalf("%s: expected %d notes, got %d", names[i], len(notes), len(ids))
+		}
+	}
+
+	// Searches only read the primary
+	if err = idx2.DeleteNote(notes[2]); err != nil {
+		t.Fatal(err)
+	}
+	q := query.MustParse("tag:quis")
+	if res, err := m.SearchNote(q, 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 0 {
+		t.Fatalf("Expected 0 results from the primary, got %d", res.Total)
+	}
+
+	compared, err := m.Compare(func(idx quicknote.Index) (*quicknote.SearchResult, error) {
+		return idx.SearchNote(q, 10, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(compared) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(compared))
+	} else if compared[0].Name != "two" || compared[0].Result.Total != 0 {
+		t.Fatalf("Expected no results from two first, got %s with %d", compared[0].Name, compared[0].Result.Total)
+	} else if compared[1].Name != "one" || compared[1].Result.Total != 1 {
+		t.Fatalf("Expected 1 result from one, got %s with %d", compared[1].Name, compared[1].Result.Total)
+	}
+
+	// Rebuilding fills all the indexes again
+	err = m.Rebuild(func(idx quicknote.Index) error {
+		return idx.IndexNotes(notes)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err := m.SearchNote(q, 10, nil); err != nil {
+		t.Fatal(err)
+	} else if res.Total != 1 {
+		t.Fatalf("Expected 1 result after rebuilding, got %d", res.Total)
+	}
+
+	if err = m.DeleteBook(notes[0].Book); err != nil {
+		t.Fatal(err)
+	}
+	for i, idx := range indexes {
+		if ids, err := idx.NoteIDs(nil); err != nil {
+			t.Fatal(err)
+		} else if len(ids) != 0 {
+			t.Fatalf("%s: expected no notes, got %d", names[i], len(ids))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package quicknote
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SupportedLanguages are the ISO 639-1 codes of the languages
+// the title and body of notes can be analyzed in
+var SupportedLanguages = []string{"de", "en", "es", "fr", "it", "nl", "pt"}
+
+// IsSupportedLanguage returns true if lang is in SupportedLanguages
+func IsSupportedLanguage(lang string) bool {
+	for _, l := range SupportedLanguages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Languages are the languages of the notes in each Book. The index
+// providers stem the words of notes in a language, so searching for
+// "deploying" also finds "deployment".
+type Languages struct {
+	// Default is the language of the Books not in Books,
+	// empty to not analyze them in a language
+	Default string
+
+	// Books maps lower case Book names to their language
+	Books map[string]string
+}
+
+// NewLanguages returns Languages, an error is returned
+// if a language is not in SupportedLanguages
+func NewLanguages(def string, books map[string]string) (*Languages, error) {
+	l := &Languages{Default: def, Books: make(map[string]string, len(books))}
+	if def != "" && !IsSupportedLanguage(def) {
+		return nil, fmt.Errorf("unsupported language %q, use one of %s", def, strings.Join(SupportedLanguages, ", "))
+	}
+
+	for name, lang := range books {
+		if lang != "" && !IsSupportedLanguage(lang) {
+			return nil, fmt.Errorf("unsupported language %q for Book %s, use one of %s",
+				lang, name, strings.Join(SupportedLanguages, ", "))
+		}
+		l.Books[strings.ToLower(name)] = lang
+	}
+	return l, nil
+}
+
+// Book returns the language of the notes in the Book name
+func (l *Languages) Book(name string) string {
+	if l == nil {
+		return ""
+	}
+	if lang, found := l.Books[strings.ToLower(name)]; found {
+		return lang
+	}
+	return l.Default
+}
+
+// Used returns the languages of all Books
+func (l *Languages) Used() []string {
+	if l == nil {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	if l.Default != "" {
+		used[l.Default] = true
+	}
+	for _, lang := range l.Books {
+		if lang != "" {
+			used[lang] = true
+		}
+	}
+
+	langs := make([]string, 0, len(used))
+	for lang := range used {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Search returns the languages to search in, the language
+// of bk or the languages of all Books if bk is nil
+func (l *Languages) Search(bk *Book) []string {
+	if bk == nil {
+		return l.Used()
+	} else if lang := l.Book(bk.Name); lang != "" {
+		return []string{lang}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package quicknote
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLanguagesUnit(t *testing.T) {
+	l, err := NewLanguages("en", map[string]string{"Arbeit": "de", "Mixed": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{"arbeit": "de", "ARBEIT": "de", "Mixed": "", "General": "en"}
+	for book, expected := range tests {
+		if lang := l.Book(book); lang != expected {
+			t.Fatalf("%s: expected %q, got %q", book, expected, lang)
+		}
+	}
+
+	if used := l.Used(); fmt.Sprint(used) != "[de en]" {
+		t.Fatalf("Expected [de en], got %v", used)
+	}
+	if langs := l.Search(&Book{Name: "Arbeit"}); fmt.Sprint(langs) != "[de]" {
+		t.Fatalf("Expected [de], got %v", langs)
+	}
+	if langs := l.Search(&Book{Name: "Mixed"}); len(langs) != 0 {
+		t.Fatalf("Expected no languages, got %v", langs)
+	}
+
+	if _, err := NewLanguages("xx", nil); err == nil {
+		t.Fatal("Expected an error for an unsupported language")
+	}
+	if _, err := NewLanguages("", map[string]string{"Work": "klingon"}); err == nil {
+		t.Fatal("Expected an error for an unsupported Book language")
+	}
+
+	var none *Languages
+	if lang := none.Book("Work"); lang != "" {
+		t.Fatalf("Expected no language, got %q", lang)
+	}
+}
@@ -20,6 +20,7 @@ package quicknote
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
@@ -51,6 +52,12 @@ type Note struct {
 
 	Book *Book
 	Tags []*Tag
+
+	// People are the lower case names mentioned as @name
+	People []string
+
+	// Due is when the note is due, zero if it has no due date
+	Due time.Time
 }
 
 // NewNote returns a new Note
@@ -94,15 +101,22 @@ func (n *Note) MarshalJSON() ([]byte, error) {
 		tags[idx] = tag.Name
 	}
 
+	var due *time.Time
+	if !n.Due.IsZero() {
+		due = &n.Due
+	}
+
 	return json.Marshal(&struct {
-		ID       int64     `json:"id"`
-		Created  time.Time `json:"created"`
-		Modified time.Time `json:"modified"`
-		Type     string    `json:"type"`
-		Title    string    `json:"title"`
-		Body     string    `json:"body"`
-		Book     string    `json:"book"`
-		Tags     []string  `json:"tags"`
+		ID       int64      `json:"id"`
+		Created  time.Time  `json:"created"`
+		Modified time.Time  `json:"modified"`
+		Type     string     `json:"type"`
+		Title    string     `json:"title"`
+		Body     string     `json:"body"`
+		Book     string     `json:"book"`
+		Tags     []string   `json:"tags"`
+		People   []string   `json:"people,omitempty"`
+		Due      *time.Time `json:"due,omitempty"`
 	}{
 		ID:       n.ID,
 		Created:  n.Created,
@@ -112,6 +126,8 @@ func (n *Note) MarshalJSON() ([]byte, error) {
 		Body:     n.Body,
 		Book:     n.Book.Name,
 		Tags:     tags,
+		People:   n.People,
+		Due:      due,
 	})
 }
 
@@ -128,3 +144,26 @@ func (n Notes) Less(i, j int) bool {
 func (n Notes) Swap(i, j int) {
 	n[i], n[j] = n[j], n[i]
 }
+
+// SortNotes sorts notes by id, created, modified or title
+// in asc or desc order, as the DB providers do
+func SortNotes(notes Notes, sortBy, order string) {
+	var less func(a, b *Note) bool
+	switch sortBy {
+	case "created":
+		less = func(a, b *Note) bool { return a.Created.Before(b.Created) }
+	case "modified":
+		less = func(a, b *Note) bool { return a.Modified.Before(b.Modified) }
+	case "title":
+		less = func(a, b *Note) bool { return a.Title < b.Title }
+	default:
+		less = func(a, b *Note) bool { return a.ID < b.ID }
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		if order == "desc" {
+			return less(notes[j], notes[i])
+		}
+		return less(notes[i], notes[j])
+	})
+}
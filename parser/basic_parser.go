@@ -18,8 +18,8 @@
 package parser
 
 import (
+	"fmt"
 	"strings"
-	"unicode"
 )
 
 // BasicParser Is the default parser for notes. The first sentence
@@ -27,9 +27,15 @@ import (
 // used as the note's body. Any word starting with `#` is parsed as
 // a tag.
 type BasicParser struct {
-	title string
-	tags  []string
-	body  string
+	title     string
+	tags      []string
+	body      string
+	extracted *Extracted
+	rules     *TagRules
+}
+
+func init() {
+	Register(Basic, func() Parser { return &BasicParser{} })
 }
 
 // Title returns the parsed title
@@ -50,7 +56,23 @@ func (p *BasicParser) Body() string {
 // Parse parses the text for the note's title, tags, and body
 func (p *BasicParser) Parse(text string) {
 	p.title, p.body = splitTitleBody(text)
-	p.tags = getTags(text)
+	p.tags = tagRules(p.rules).Tags(text)
+	p.extracted = extract(text)
+}
+
+// Extracted returns the links, dates and mentions of the parsed text
+func (p *BasicParser) Extracted() *Extracted {
+	return p.extracted
+}
+
+// SetTagRules sets the rules for the parsed tags
+func (p *BasicParser) SetTagRules(r *TagRules) {
+	p.rules = r
+}
+
+// Text returns the text that parses to the title and body
+func (p *BasicParser) Text(title, body string) string {
+	return fmt.Sprintf("%s\n%s", title, body)
 }
 
 func splitTitleBody(text string) (string, string) {
@@ -68,73 +90,3 @@ func splitTitleBody(text string) (string, string) {
 
 	return strings.TrimSpace(title), strings.TrimSpace(body)
 }
-
-func getTags(text string) []string {
-	tagCount := 0
-
-	// Using a map as a makeshift Set
-	tags := make(map[string]bool)
-
-	tStart := 0
-	tEnd := 0
-
-	// A tag is any word that starts with the '#'
-	// character. Any '#' character that does not have
-	// a whitespace preceding it is ignored except when
-	// its the first character of the string.
-	//
-	// Duplicate tags are ignored.
-	for i := 0; i < len(text); {
-		tStart = nextTagIndex(text, tEnd)
-		if tStart == -1 {
-			break
-		}
-
-		tEnd = getTagEndIndex(text, tStart+1)
-		tag := text[tStart+1 : tEnd]
-
-		if unicode.IsPunct(rune(tag[len(tag)-1])) {
-			tag = tag[:len(tag)-1]
-		}
-
-		if _, found := tags[tag]; !found && len(tag) > 1 {
-			tags[strings.ToLower(tag)] = true
-			tagCount++
-		}
-
-		i = tEnd
-	}
-
-	// Get all the map's keys, which are our tags
-	keys := make([]string, 0, tagCount)
-	for k := range tags {
-		keys = append(keys, k)
-	}
-	return keys
-}
-
-func nextTagIndex(text string, start int) int {
-	for i := start; i < len(text); i++ {
-		if i == 0 && isTag(text[i]) {
-			return i
-		}
-		if i > 0 && (unicode.IsSpace(rune(text[i-1])) && isTag(text[i])) {
-			return i
-		}
-	}
-	return -1
-}
-
-func getTagEndIndex(text string, start int) int {
-	i := start
-	for ; i < len(text); i++ {
-		if text[i] == ',' || unicode.IsSpace(rune(text[i])) {
-			return i
-		}
-	}
-	return i
-}
-
-func isTag(c byte) bool {
-	return c == '#'
-}
@@ -0,0 +1,91 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of the dates extracted from notes
+const DateLayout = "2006-01-02"
+
+var (
+	linkRegexp    = regexp.MustCompile(`\bhttps?://[^\s<>()\[\]"'` + "`" + `]+`)
+	dateRegexp    = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+	mentionRegexp = regexp.MustCompile(`(?:^|[^\w@])@(\w(?:[\w.-]*\w)?)`)
+)
+
+// Extracted is the data a parser found in a note
+// besides its title, tags and body
+type Extracted struct {
+	// Links are the http and https URLs
+	Links []string
+
+	// Dates are the dates written as 2006-01-02
+	Dates []time.Time
+
+	// Mentions are the lower case names written as @name
+	Mentions []string
+
+	// Fields are named values, such as the keys of front matter
+	Fields map[string]string
+}
+
+// Extractor is implemented by parsers that extract more
+// than the title, tags and body of the text they parsed
+type Extractor interface {
+	Extracted() *Extracted
+}
+
+// extract returns the links, dates and mentions of text
+func extract(text string) *Extracted {
+	e := &Extracted{Fields: make(map[string]string)}
+
+	seen := make(map[string]bool)
+	for _, link := range linkRegexp.FindAllString(text, -1) {
+		link = strings.TrimRight(link, ".,;:!?")
+		if !seen[link] {
+			seen[link] = true
+			e.Links = append(e.Links, link)
+		}
+	}
+
+	seen = make(map[string]bool)
+	for _, s := range dateRegexp.FindAllString(text, -1) {
+		d, err := time.Parse(DateLayout, s)
+		if err == nil && !seen[s] {
+			seen[s] = true
+			e.Dates = append(e.Dates, d)
+		}
+	}
+	sort.Slice(e.Dates, func(i, j int) bool { return e.Dates[i].Before(e.Dates[j]) })
+
+	seen = make(map[string]bool)
+	for _, m := range mentionRegexp.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(m[1])
+		if !seen[name] {
+			seen[name] = true
+			e.Mentions = append(e.Mentions, name)
+		}
+	}
+	sort.Strings(e.Mentions)
+	return e
+}
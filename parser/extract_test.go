@@ -0,0 +1,66 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/anmil/quicknote/test"
+)
+
+func TestExtractUnit(t *testing.T) {
+	e := extract("Meeting with @Alice and @bob.smith on 2017-03-02, then 2017-02-30 and 2017-01-15.\n" +
+		"Mail bob@example.com, see https://example.com/a?b=c. and (http://example.org/x)\n" +
+		"@alice again on 2017-03-02 at https://example.com/a?b=c")
+
+	links := []string{"https://example.com/a?b=c", "http://example.org/x"}
+	if !test.StringSliceEq(e.Links, links) {
+		t.Errorf("expected links %v, got %v", links, e.Links)
+	}
+
+	mentions := []string{"alice", "bob.smith"}
+	if !test.StringSliceEq(e.Mentions, mentions) {
+		t.Errorf("expected mentions %v, got %v", mentions, e.Mentions)
+	}
+
+	dates := []string{"2017-01-15", "2017-03-02"}
+	if len(e.Dates) != len(dates) {
+		t.Fatalf("expected dates %v, got %v", dates, e.Dates)
+	}
+	for i, d := range e.Dates {
+		if d.Format(DateLayout) != dates[i] {
+			t.Errorf("expected dates %v, got %v", dates, e.Dates)
+		}
+	}
+}
+
+func TestMarkdownExtractUnit(t *testing.T) {
+	p := &MarkdownParser{}
+	p.Parse("---\ndue: 2017-04-01\n---\n# Plan\n\nAsk @carol `@notme` about http://example.com\n\n```\nhttp://code.example.com\n```")
+
+	e := p.Extracted()
+	if !test.StringSliceEq(e.Mentions, []string{"carol"}) {
+		t.Errorf("expected mentions [carol], got %v", e.Mentions)
+	}
+	if !test.StringSliceEq(e.Links, []string{"http://example.com"}) {
+		t.Errorf("expected links [http://example.com], got %v", e.Links)
+	}
+	if e.Fields["due"] != "2017-04-01" {
+		t.Errorf("expected due field 2017-04-01, got %q", e.Fields["due"])
+	}
+}
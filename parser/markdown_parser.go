@@ -0,0 +1,282 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// frontMatterDelim is the line before and after YAML front matter
+const frontMatterDelim = "---"
+
+// headingRegexp matches an ATX heading, the text is the first group
+var headingRegexp = regexp.MustCompile(`^ {0,3}#{1,6}[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+
+// MarkdownParser parses notes written in Markdown. The title is the title
+// in the YAML front matter or the first heading, or else the first line.
+// Tags are read from the front matter and words starting with `#` that are
+// not in code spans or fenced code blocks. The other front matter keys
+// are the note's fields. The front matter is kept at the top of the body.
+type MarkdownParser struct {
+	title     string
+	tags      []string
+	body      string
+	extracted *Extracted
+	rules     *TagRules
+}
+
+func init() {
+	Register(Markdown, func() Parser { return &MarkdownParser{} })
+}
+
+// Title returns the parsed title
+func (p *MarkdownParser) Title() string {
+	return p.title
+}
+
+// Tags returns the parsed tags
+func (p *MarkdownParser) Tags() []string {
+	return p.tags
+}
+
+// Body returns the parsed body
+func (p *MarkdownParser) Body() string {
+	return p.body
+}
+
+// Extracted returns the links, dates and mentions outside of code and
+// the front matter keys other than title and tags as fields
+func (p *MarkdownParser) Extracted() *Extracted {
+	return p.extracted
+}
+
+// SetTagRules sets the rules for the parsed tags
+func (p *MarkdownParser) SetTagRules(r *TagRules) {
+	p.rules = r
+}
+
+// Parse parses the text for the note's title, tags, fields and body
+func (p *MarkdownParser) Parse(text string) {
+	text = strings.TrimSpace(strings.Replace(text, "\r\n", "\n", -1))
+	block, content, values := splitFrontMatter(text)
+
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	text = textOutsideCode(lines)
+
+	rules := tagRules(p.rules)
+	p.title = ""
+	p.extracted = extract(text)
+	tags := make(map[string]bool)
+	for key, value := range values {
+		switch strings.ToLower(key) {
+		case "title":
+			p.title = strings.TrimSpace(fmt.Sprint(value))
+		case "tags":
+			for _, t := range frontMatterTags(value) {
+				if t = rules.Tag(t); t != "" {
+					tags[t] = true
+				}
+			}
+		default:
+			p.extracted.Fields[key] = fmt.Sprint(value)
+		}
+	}
+
+	for _, t := range rules.Tags(text) {
+		tags[t] = true
+	}
+
+	if p.title == "" {
+		var idx int
+		if p.title, idx = findTitle(lines); idx >= 0 {
+			end := idx + 1
+			// Do not leave two blank lines where the title was
+			if end < len(lines) && strings.TrimSpace(lines[end]) == "" &&
+				(idx == 0 || strings.TrimSpace(lines[idx-1]) == "") {
+				end++
+			}
+			lines = append(lines[:idx:idx], lines[end:]...)
+		}
+	}
+
+	p.body = strings.TrimSpace(strings.Join(lines, "\n"))
+	if block != "" {
+		p.body = strings.TrimSpace(block + "\n\n" + p.body)
+	}
+
+	p.tags = make([]string, 0, len(tags))
+	for t := range tags {
+		p.tags = append(p.tags, t)
+	}
+	sort.Strings(p.tags)
+}
+
+// Text returns the text that parses to the title and body, the
+// title is put in a heading after the front matter
+func (p *MarkdownParser) Text(title, body string) string {
+	block, content, values := splitFrontMatter(body)
+	for key := range values {
+		if strings.ToLower(key) == "title" {
+			return body
+		}
+	}
+
+	if block != "" {
+		return strings.TrimSpace(fmt.Sprintf("%s\n\n# %s\n\n%s", block, title, strings.TrimSpace(content)))
+	}
+	return strings.TrimSpace(fmt.Sprintf("# %s\n\n%s", title, body))
+}
+
+// splitFrontMatter returns the YAML front matter block at the start
+// of text, the text after it and its values. Text that does not
+// start with valid front matter is returned as the content.
+func splitFrontMatter(text string) (string, string, map[string]interface{}) {
+	lines := strings.Split(text, "\n")
+	if strings.TrimSpace(lines[0]) != frontMatterDelim {
+		return "", text, nil
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != frontMatterDelim {
+			continue
+		}
+
+		values := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(strings.Join(lines[1:i], "\n")), &values); err != nil {
+			return "", text, nil
+		}
+		return strings.Join(lines[:i+1], "\n"), strings.Join(lines[i+1:], "\n"), values
+	}
+	return "", text, nil
+}
+
+// frontMatterTags returns the words of a list or of a
+// string with the words separated by commas or spaces
+func frontMatterTags(value interface{}) []string {
+	var words []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, w := range v {
+			words = append(words, fmt.Sprint(w))
+		}
+	case string:
+		words = strings.FieldsFunc(v, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+	return words
+}
+
+// findTitle returns the text of the first heading and its line, or
+// the first line with text when there are no headings
+func findTitle(lines []string) (string, int) {
+	first := -1
+	fence := ""
+	for idx, line := range lines {
+		if marker := fenceMarker(line); marker != "" {
+			if fence == "" {
+				fence = marker
+			} else if marker == fence {
+				fence = ""
+			}
+		} else if fence != "" {
+			continue
+		} else if m := headingRegexp.FindStringSubmatch(line); m != nil && m[1] != "" {
+			return m[1], idx
+		}
+
+		if first < 0 && strings.TrimSpace(line) != "" {
+			first = idx
+		}
+	}
+
+	if first < 0 {
+		return "", -1
+	}
+	return strings.TrimSpace(lines[first]), first
+}
+
+// textOutsideCode returns the lines without fenced code blocks,
+// code spans and the `#` of headings
+func textOutsideCode(lines []string) string {
+	var buf bytes.Buffer
+	fence := ""
+	for _, line := range lines {
+		if marker := fenceMarker(line); marker != "" {
+			if fence == "" {
+				fence = marker
+			} else if marker == fence {
+				fence = ""
+			}
+			continue
+		} else if fence != "" {
+			continue
+		}
+
+		if m := headingRegexp.FindStringSubmatch(line); m != nil {
+			line = m[1]
+		}
+		buf.WriteString(stripCodeSpans(line))
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// fenceMarker returns ``` or ~~~ if the line starts or ends a fenced code block
+func fenceMarker(line string) string {
+	line = strings.TrimLeft(line, " ")
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, marker) {
+			return marker
+		}
+	}
+	return ""
+}
+
+// stripCodeSpans replaces the code spans of line with a space
+func stripCodeSpans(line string) string {
+	var buf bytes.Buffer
+	for {
+		start := strings.Index(line, "`")
+		if start < 0 {
+			break
+		}
+
+		n := 1
+		for start+n < len(line) && line[start+n] == '`' {
+			n++
+		}
+		ticks := line[start : start+n]
+
+		end := strings.Index(line[start+n:], ticks)
+		if end < 0 {
+			break
+		}
+		buf.WriteString(line[:start])
+		buf.WriteByte(' ')
+		line = line[start+n+end+n:]
+	}
+	buf.WriteString(line)
+	return buf.String()
+}
@@ -0,0 +1,131 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/anmil/quicknote/test"
+)
+
+var mpText1 = "Some intro\n\n## The #markdown parser ##\n\n" +
+	"Tags in `#code` spans and ``#double `code` spans`` are ignored, #this is not.\n\n" +
+	"```bash\n# a comment #notatag\necho ok\n```\n\n" +
+	"~~~\n#nottag\n~~~\n#end"
+
+var mpText1Title = "The #markdown parser"
+var mpText1Tags = []string{"end", "markdown", "this"}
+var mpText1Body = "Some intro\n\n" +
+	"Tags in `#code` spans and ``#double `code` spans`` are ignored, #this is not.\n\n" +
+	"```bash\n# a comment #notatag\necho ok\n```\n\n" +
+	"~~~\n#nottag\n~~~\n#end"
+
+var mpText2 = `---
+title: Front matter title
+tags: [Deploy, "#ops"]
+status: draft
+---
+
+# Heading
+
+Some #text`
+
+var mpText2Tags = []string{"deploy", "ops", "text"}
+var mpText2Body = `---
+title: Front matter title
+tags: [Deploy, "#ops"]
+status: draft
+---
+
+# Heading
+
+Some #text`
+
+func TestMarkdownParserUnit(t *testing.T) {
+	p := &MarkdownParser{}
+	p.Parse(mpText1)
+
+	if p.Title() != mpText1Title {
+		t.Errorf("Parser returned incorrect title %q", p.Title())
+	}
+	if !test.StringSliceEq(p.Tags(), mpText1Tags) {
+		t.Errorf("Parser returned incorrect tags %v", p.Tags())
+	}
+	if p.Body() != mpText1Body {
+		t.Errorf("Parser returned incorrect body %q", p.Body())
+	}
+
+	p = &MarkdownParser{}
+	p.Parse(mpText2)
+
+	if p.Title() != "Front matter title" {
+		t.Errorf("Parser returned incorrect title %q", p.Title())
+	}
+	if !test.StringSliceEq(p.Tags(), mpText2Tags) {
+		t.Errorf("Parser returned incorrect tags %v", p.Tags())
+	}
+	if p.Body() != mpText2Body {
+		t.Errorf("Parser returned incorrect body %q", p.Body())
+	}
+	if f := p.Extracted().Fields; len(f) != 1 || f["status"] != "draft" {
+		t.Errorf("Parser returned incorrect fields %v", f)
+	}
+}
+
+func TestMarkdownParserFrontMatterUnit(t *testing.T) {
+	tests := []struct {
+		text  string
+		title string
+		tags  []string
+		body  string
+	}{
+		{"---\ntags: ab, Bc  cd\n---\nTitle\nbody", "Title", []string{"ab", "bc", "cd"}, "---\ntags: ab, Bc  cd\n---\n\nbody"},
+		{"---\nnot: [valid\n---\nbody", "---", []string{}, "not: [valid\n---\nbody"},
+		{"---\ntitle: T\n", "---", []string{}, "title: T"},
+		{"Only a title", "Only a title", []string{}, ""},
+	}
+
+	for _, tt := range tests {
+		p := &MarkdownParser{}
+		p.Parse(tt.text)
+		if p.Title() != tt.title {
+			t.Errorf("%q: expected title %q, got %q", tt.text, tt.title, p.Title())
+		}
+		if !test.StringSliceEq(p.Tags(), tt.tags) {
+			t.Errorf("%q: expected tags %v, got %v", tt.text, tt.tags, p.Tags())
+		}
+		if p.Body() != tt.body {
+			t.Errorf("%q: expected body %q, got %q", tt.text, tt.body, p.Body())
+		}
+	}
+}
+
+func TestMarkdownParserTextUnit(t *testing.T) {
+	texts := []string{mpText1, mpText2, "---\nstatus: done\n---\n# Title\n\nbody", "Plain title\nplain body"}
+	for _, text := range texts {
+		p := &MarkdownParser{}
+		p.Parse(text)
+		title, body := p.Title(), p.Body()
+
+		p.Parse(p.Text(title, body))
+		if p.Title() != title || p.Body() != body {
+			t.Errorf("%q: Text did not parse to the title %q and body %q, got %q and %q",
+				text, title, body, p.Title(), p.Body())
+		}
+	}
+}
@@ -17,25 +17,79 @@
 
 package parser
 
-import "errors"
+import (
+	"errors"
+	"sort"
+	"sync"
+)
 
 // ErrParserNotSupported an unknown parser type was given
 var ErrParserNotSupported = errors.New("Unsupported parser")
 
+// Names of the parsers registered by this package
+const (
+	Basic    = "basic"
+	Markdown = "markdown"
+)
+
 // Parser interface for a note parser
 type Parser interface {
 	Parse(text string)
 	Title() string
 	Tags() []string
 	Body() string
+
+	// Text returns the text to edit a note with, it
+	// parses to the same title and body
+	Text(title, body string) string
+}
+
+// Factory returns a new Parser
+type Factory func() Parser
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register makes a parser available by name to NewParser and to the Book
+// and type parsers of the config file, such as an org-mode parser. It
+// panics if name is empty, factory is nil or name is already registered.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if name == "" {
+		panic("parser: Register name is empty")
+	} else if factory == nil {
+		panic("parser: Register factory is nil for " + name)
+	} else if _, found := factories[name]; found {
+		panic("parser: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// Registered returns the sorted names of the registered parsers
+func Registered() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // NewParser returns a new parser for the type given
 func NewParser(ptype string) (Parser, error) {
-	switch ptype {
-	case "basic":
-		return &BasicParser{}, nil
-	default:
+	factoriesMu.RLock()
+	factory, found := factories[ptype]
+	factoriesMu.RUnlock()
+
+	if !found {
 		return nil, ErrParserNotSupported
 	}
+	return factory(), nil
 }
@@ -0,0 +1,59 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/anmil/quicknote/test"
+)
+
+func TestRegisterUnit(t *testing.T) {
+	Register("test-register", func() Parser { return &BasicParser{} })
+	defer func() {
+		factoriesMu.Lock()
+		delete(factories, "test-register")
+		factoriesMu.Unlock()
+	}()
+
+	if p, err := NewParser("test-register"); err != nil {
+		t.Fatal(err)
+	} else if _, ok := p.(*BasicParser); !ok {
+		t.Errorf("expected a BasicParser, got %T", p)
+	}
+
+	names := []string{Basic, Markdown, "test-register"}
+	if !test.StringSliceEq(Registered(), names) {
+		t.Errorf("expected registered parsers %v, got %v", names, Registered())
+	}
+
+	if _, err := NewParser("unknown"); err != ErrParserNotSupported {
+		t.Errorf("expected ErrParserNotSupported, got %v", err)
+	}
+
+	for _, name := range []string{"", Basic} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected Register(%q) to panic", name)
+				}
+			}()
+			Register(name, func() Parser { return &BasicParser{} })
+		}()
+	}
+}
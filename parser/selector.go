@@ -0,0 +1,98 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Selector picks the parser of a note by its Book or type
+type Selector struct {
+	// Default is the parser of notes whose Book and type are not set
+	Default string
+
+	// Books maps lower case Book names to their parser
+	Books map[string]string
+
+	// Types maps note types to their parser
+	Types map[string]string
+
+	// Rules are the tag rules of the parsers, nil for the default rules
+	Rules *TagRules
+}
+
+// NewSelector returns a Selector, an error is returned
+// if a parser name is not registered
+func NewSelector(def string, books, types map[string]string) (*Selector, error) {
+	if !isParser(def) {
+		return nil, fmt.Errorf("unsupported parser %q, use one of %s", def, strings.Join(Registered(), ", "))
+	}
+
+	s := &Selector{
+		Default: def,
+		Books:   make(map[string]string, len(books)),
+		Types:   make(map[string]string, len(types)),
+	}
+	for name, p := range books {
+		if !isParser(p) {
+			return nil, fmt.Errorf("unsupported parser %q for Book %s, use one of %s",
+				p, name, strings.Join(Registered(), ", "))
+		}
+		s.Books[strings.ToLower(name)] = p
+	}
+	for typ, p := range types {
+		if !isParser(p) {
+			return nil, fmt.Errorf("unsupported parser %q for type %s, use one of %s",
+				p, typ, strings.Join(Registered(), ", "))
+		}
+		s.Types[strings.ToLower(typ)] = p
+	}
+	return s, nil
+}
+
+// Name returns the name of the parser for a note in the Book
+// book of type typ, the Book's parser is used before the type's
+func (s *Selector) Name(book, typ string) string {
+	if p, found := s.Books[strings.ToLower(book)]; found {
+		return p
+	} else if p, found := s.Types[strings.ToLower(typ)]; found {
+		return p
+	}
+	return s.Default
+}
+
+// Parser returns a new parser for a note in the Book book of type typ
+func (s *Selector) Parser(book, typ string) (Parser, error) {
+	p, err := NewParser(s.Name(book, typ))
+	if err != nil {
+		return nil, err
+	}
+
+	if r, ok := p.(TagRuler); ok && s.Rules != nil {
+		r.SetTagRules(s.Rules)
+	}
+	return p, nil
+}
+
+func isParser(name string) bool {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	_, found := factories[name]
+	return found
+}
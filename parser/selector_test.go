@@ -0,0 +1,60 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import "testing"
+
+func TestSelectorUnit(t *testing.T) {
+	s, err := NewSelector(Basic, map[string]string{"Wiki": Markdown}, map[string]string{"url": Basic, "basic": Markdown})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		book string
+		typ  string
+		name string
+	}{
+		{"wiki", "url", Markdown},
+		{"General", "url", Basic},
+		{"General", "basic", Markdown},
+		{"General", "other", Basic},
+	}
+	for _, tt := range tests {
+		if name := s.Name(tt.book, tt.typ); name != tt.name {
+			t.Errorf("%s/%s: expected parser %s, got %s", tt.book, tt.typ, tt.name, name)
+		}
+	}
+
+	p, err := s.Parser("wiki", "basic")
+	if err != nil {
+		t.Fatal(err)
+	} else if _, ok := p.(*MarkdownParser); !ok {
+		t.Errorf("expected a MarkdownParser, got %T", p)
+	}
+
+	if _, err := NewSelector("rst", nil, nil); err == nil {
+		t.Error("expected an error for an unsupported default parser")
+	}
+	if _, err := NewSelector(Basic, map[string]string{"Wiki": "rst"}, nil); err == nil {
+		t.Error("expected an error for an unsupported Book parser")
+	}
+	if _, err := NewSelector(Basic, nil, map[string]string{"url": "rst"}); err == nil {
+		t.Error("expected an error for an unsupported type parser")
+	}
+}
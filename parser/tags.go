@@ -0,0 +1,172 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTagPunctuation are the punctuation characters allowed in tags by default
+const DefaultTagPunctuation = "_-./"
+
+// TagRules are the rules for the words parsed as tags. A tag is a word
+// starting with '#' at the start of the text or after a space, up to the
+// first character that is not a letter, mark, number or allowed punctuation.
+type TagRules struct {
+	// Punctuation are the punctuation characters allowed in tags,
+	// punctuation at the end of a tag is removed
+	Punctuation string
+
+	// MinLength is the minimum number of characters of a tag
+	MinLength int
+
+	// FoldCase lower cases tags
+	FoldCase bool
+}
+
+// DefaultTagRules returns the rules used when none are set
+func DefaultTagRules() *TagRules {
+	return &TagRules{Punctuation: DefaultTagPunctuation, MinLength: 2, FoldCase: true}
+}
+
+// NewTagRules returns TagRules, an error is returned if minLength is less than 1
+func NewTagRules(punctuation string, minLength int, foldCase bool) (*TagRules, error) {
+	if minLength < 1 {
+		return nil, errors.New("the minimum tag length must be at least 1")
+	}
+	return &TagRules{Punctuation: punctuation, MinLength: minLength, FoldCase: foldCase}, nil
+}
+
+// TagRuler is implemented by parsers whose tags follow TagRules
+type TagRuler interface {
+	SetTagRules(r *TagRules)
+}
+
+// Tags returns the sorted tags of text without duplicates
+func (r *TagRules) Tags(text string) []string {
+	tags := make(map[string]bool)
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		// Any '#' that does not have a whitespace preceding
+		// it is ignored, except at the start of the text
+		if runes[i] != '#' || (i > 0 && !unicode.IsSpace(runes[i-1])) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && r.isTagRune(runes[end]) {
+			end++
+		}
+		if tag := r.Tag(string(runes[i+1 : end])); tag != "" {
+			tags[tag] = true
+		}
+		i = end - 1
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Tag returns word as a tag, without a leading '#' and trailing
+// punctuation. An empty string is returned if it is too short.
+func (r *TagRules) Tag(word string) string {
+	tag := strings.TrimRightFunc(strings.TrimPrefix(strings.TrimSpace(word), "#"), unicode.IsPunct)
+	if r.FoldCase {
+		tag = strings.ToLower(tag)
+	}
+	if utf8.RuneCountInString(tag) < r.MinLength {
+		return ""
+	}
+	return tag
+}
+
+// ExtraTags returns the tags that p does not find in text and that are
+// not written in it, the tags added to a note besides its text. A tag
+// is written in text when a word starting with '#' starts with the tag,
+// so tags parsed with other tag rules are not extra.
+func ExtraTags(p Parser, text string, tags []string) []string {
+	p.Parse(text)
+
+	words := hashWords(text)
+	extra := make([]string, 0)
+	for _, t := range tags {
+		if !inStrings(t, p.Tags()) && !writtenTag(t, words) {
+			extra = append(extra, t)
+		}
+	}
+	return extra
+}
+
+// hashWords returns the lower case words of text starting with '#'
+// at the start of the text or after a space, without the '#'
+func hashWords(text string) []string {
+	words := make([]string, 0)
+	for _, w := range strings.Fields(text) {
+		if len(w) > 1 && w[0] == '#' {
+			words = append(words, strings.ToLower(w[1:]))
+		}
+	}
+	return words
+}
+
+// writtenTag returns whether one of words is tag, or starts with
+// tag followed by a character that is not a letter, mark or number
+func writtenTag(tag string, words []string) bool {
+	tag = strings.ToLower(tag)
+	for _, w := range words {
+		if !strings.HasPrefix(w, tag) {
+			continue
+		}
+		next, _ := utf8.DecodeRuneInString(w[len(tag):])
+		if len(w) == len(tag) || !(unicode.IsLetter(next) || unicode.IsMark(next) || unicode.IsNumber(next)) {
+			return true
+		}
+	}
+	return false
+}
+
+func inStrings(s string, slice []string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *TagRules) isTagRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsMark(c) || unicode.IsNumber(c) ||
+		strings.ContainsRune(r.Punctuation, c)
+}
+
+// tagRules returns r or the default rules if r is nil
+func tagRules(r *TagRules) *TagRules {
+	if r == nil {
+		return DefaultTagRules()
+	}
+	return r
+}
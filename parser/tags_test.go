@@ -0,0 +1,96 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/anmil/quicknote/test"
+)
+
+func TestTagRulesUnit(t *testing.T) {
+	tests := []struct {
+		rules *TagRules
+		text  string
+		tags  []string
+	}{
+		{DefaultTagRules(), "#café and #日本語 tags", []string{"café", "日本語"}},
+		{DefaultTagRules(), "after\u00a0#nbsp and\u3000#tab", []string{"nbsp", "tab"}},
+		{DefaultTagRules(), "#end。 #wow！ #done.", []string{"done", "end", "wow"}},
+		{DefaultTagRules(), "#Go, #go and #GO", []string{"go"}},
+		{DefaultTagRules(), "#a #b2 # #", []string{"b2"}},
+		{DefaultTagRules(), "word#nottag #x_y #a-b/c.d #stop!here", []string{"a-b/c.d", "stop", "x_y"}},
+		{DefaultTagRules(), "#Ünïcödé", []string{"ünïcödé"}},
+		{&TagRules{MinLength: 1, FoldCase: false}, "#A #Go #x-y", []string{"A", "Go", "x"}},
+		{&TagRules{Punctuation: "+", MinLength: 3, FoldCase: true}, "#C++ #go #rust", []string{"c++", "rust"}},
+	}
+
+	for _, tt := range tests {
+		if tags := tt.rules.Tags(tt.text); !test.StringSliceEq(tags, tt.tags) {
+			t.Errorf("%q: expected tags %v, got %v", tt.text, tt.tags, tags)
+		}
+	}
+
+	if _, err := NewTagRules("", 0, true); err == nil {
+		t.Error("expected an error for a minimum tag length of 0")
+	}
+}
+
+func TestSelectorTagRulesUnit(t *testing.T) {
+	s, err := NewSelector(Basic, nil, map[string]string{"url": Markdown})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Rules = &TagRules{MinLength: 1}
+
+	for _, typ := range []string{"basic", "url"} {
+		p, err := s.Parser("General", typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p.Parse("Title #A #b\nbody")
+		if !test.StringSliceEq(p.Tags(), []string{"A", "b"}) {
+			t.Errorf("%s: expected tags [A b], got %v", typ, p.Tags())
+		}
+	}
+}
+
+func TestExtraTagsUnit(t *testing.T) {
+	tests := []struct {
+		rules *TagRules
+		text  string
+		tags  []string
+		extra []string
+	}{
+		{DefaultTagRules(), "Trip #travel", []string{"travel", "extra"}, []string{"extra"}},
+		{DefaultTagRules(), "Trip #tripod", []string{"trip"}, []string{"trip"}},
+		// Parsed from the text with other rules
+		{DefaultTagRules(), "Notes #a #Go", []string{"a", "Go"}, []string{}},
+		{DefaultTagRules(), "Release #v1.2, #foo+bar", []string{"v1.2", "foo+bar", "foo"}, []string{}},
+		{&TagRules{MinLength: 1}, "Release #v1.2", []string{"v1.2", "v1"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		p := &BasicParser{}
+		p.SetTagRules(tt.rules)
+		if extra := ExtraTags(p, tt.text, tt.tags); !test.StringSliceEq(extra, tt.extra) {
+			t.Errorf("%q %v: expected extra tags %v, got %v", tt.text, tt.tags, tt.extra, extra)
+		}
+	}
+}
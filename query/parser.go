@@ -0,0 +1,400 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package query
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrEmptyQuery the query has no terms
+var ErrEmptyQuery = errors.New("Empty query")
+
+// SyntaxError is an error in a query at Pos (in runes)
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid query at %d: %s", e.Pos+1, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tWord
+	tPhrase
+	tField
+	tLParen
+	tRParen
+	tAnd
+	tOr
+	tNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lexer splits a query into tokens
+type lexer struct {
+	input []rune
+	pos   int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) && unicode.IsSpace(l.input[l.pos]) {
+		l.pos++
+	}
+	if l.pos >= len(l.input) {
+		return token{kind: tEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	switch r := l.input[l.pos]; {
+	case r == '(':
+		l.pos++
+		return token{kind: tLParen, text: "(", pos: start}, nil
+	case r == ')':
+		l.pos++
+		return token{kind: tRParen, text: ")", pos: start}, nil
+	case r == '"':
+		return l.phrase()
+	case r == '-' && l.pos+1 < len(l.input) && !unicode.IsSpace(l.input[l.pos+1]):
+		l.pos++
+		return token{kind: tNot, text: "-", pos: start}, nil
+	}
+
+	for l.pos < len(l.input) {
+		r := l.input[l.pos]
+		if unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' {
+			break
+		}
+
+		// A known field name followed by a colon starts a field,
+		// otherwise the colon is part of the word
+		if r == ':' && isField(string(l.input[start:l.pos])) {
+			field := strings.ToLower(string(l.input[start:l.pos]))
+			l.pos++
+			return token{kind: tField, text: field, pos: start}, nil
+		}
+		l.pos++
+	}
+
+	word := string(l.input[start:l.pos])
+	switch word {
+	case "AND", "&&":
+		return token{kind: tAnd, text: word, pos: start}, nil
+	case "OR", "||":
+		return token{kind: tOr, text: word, pos: start}, nil
+	case "NOT":
+		return token{kind: tNot, text: word, pos: start}, nil
+	}
+	return token{kind: tWord, text: word, pos: start}, nil
+}
+
+func (l *lexer) phrase() (token, error) {
+	start := l.pos
+	l.pos++
+
+	var sb bytes.Buffer
+	for l.pos < len(l.input) {
+		r := l.input[l.pos]
+		l.pos++
+
+		switch {
+		case r == '\\' && l.pos < len(l.input):
+			sb.WriteRune(l.input[l.pos])
+			l.pos++
+		case r == '"':
+			return token{kind: tPhrase, text: sb.String(), pos: start}, nil
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return token{}, &SyntaxError{Pos: start, Msg: "unterminated phrase"}
+}
+
+func isField(s string) bool {
+	s = strings.ToLower(s)
+	if s == "tags" {
+		return true
+	}
+	for _, f := range Fields {
+		if f == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses a query string into its AST
+func Parse(s string) (Node, error) {
+	p := &parser{lex: &lexer{input: []rune(s)}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tEOF {
+		return nil, ErrEmptyQuery
+	}
+
+	n, err := p.parseOr("")
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tEOF {
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+	return n, nil
+}
+
+// MustParse is like Parse but panics if the query is invalid
+func MustParse(s string) Node {
+	n, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// parser is a recursive descent parser for
+//
+//	or     = and { "OR" and }
+//	and    = unary { ["AND"] unary }
+//	unary  = ("NOT" | "-") unary | [field ":"] ( "(" or ")" | value )
+//	value  = word | word"*" | phrase
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr(field string) (Node, error) {
+	n, err := p.parseAnd(field)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []Node{n}
+	for p.tok.kind == tOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseAnd(field)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return &Or{Nodes: nodes}, nil
+}
+
+func (p *parser) parseAnd(field string) (Node, error) {
+	n, err := p.parseUnary(field)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []Node{n}
+	for {
+		if p.tok.kind == tAnd {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		} else if p.tok.kind == tEOF || p.tok.kind == tOr || p.tok.kind == tRParen {
+			break
+		}
+
+		n, err := p.parseUnary(field)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return &And{Nodes: nodes}, nil
+}
+
+func (p *parser) parseUnary(field string) (Node, error) {
+	switch p.tok.kind {
+	case tNot:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseUnary(field)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Node: n}, nil
+	case tField:
+		if len(field) != 0 {
+			return nil, p.errorf("field %s inside of field %s", p.tok.text, field)
+		}
+		field = p.tok.text
+		if field == "tags" {
+			field = FieldTag
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tLParen && p.tok.kind != tWord && p.tok.kind != tPhrase {
+			return nil, p.errorf("expected a value for field %s", field)
+		}
+	}
+
+	switch p.tok.kind {
+	case tLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.parseOr(field)
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tRParen {
+			return nil, p.errorf("expected )")
+		}
+		return n, p.advance()
+	case tWord, tPhrase:
+		n, err := p.parseValue(field)
+		if err != nil {
+			return nil, err
+		}
+		return n, p.advance()
+	case tEOF:
+		return nil, p.errorf("unexpected end of query")
+	}
+	return nil, p.errorf("unexpected %q", p.tok.text)
+}
+
+func (p *parser) parseValue(field string) (Node, error) {
+	switch field {
+	case FieldCreated, FieldModified:
+		if p.tok.kind == tPhrase {
+			return nil, p.errorf("expected a date for field %s", field)
+		}
+		return p.parseDateRange(field)
+	case FieldID:
+		id, err := strconv.ParseInt(p.tok.text, 10, 64)
+		if err != nil || p.tok.kind == tPhrase {
+			return nil, p.errorf("invalid id %q", p.tok.text)
+		}
+		return &ID{ID: id}, nil
+	}
+
+	if p.tok.kind == tPhrase {
+		return &Phrase{Field: field, Value: p.tok.text}, nil
+	}
+
+	word := p.tok.text
+	if strings.HasSuffix(word, "*") && len(word) > 1 {
+		return &Term{Field: field, Value: strings.TrimSuffix(word, "*"), Prefix: true}, nil
+	}
+	return &Term{Field: field, Value: word}, nil
+}
+
+func (p *parser) parseDateRange(field string) (Node, error) {
+	value := p.tok.text
+
+	// Ranges include the whole of both dates
+	if parts := strings.SplitN(value, "..", 2); len(parts) == 2 {
+		start, _, err := p.parseDate(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		_, end, err := p.parseDate(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if !start.Before(end) {
+			return nil, p.errorf("date range %q ends before it starts", value)
+		}
+		return &DateRange{Field: field, Start: start, End: end}, nil
+	}
+
+	op := ""
+	for _, o := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, o) {
+			op = o
+			value = value[len(o):]
+			break
+		}
+	}
+
+	start, end, err := p.parseDate(value)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &DateRange{Field: field}
+	switch op {
+	case ">":
+		n.Start = end
+	case ">=":
+		n.Start = start
+	case "<":
+		n.End = start
+	case "<=":
+		n.End = end
+	default:
+		n.Start, n.End = start, end
+	}
+	return n, nil
+}
+
+// parseDate parses a date in DateLayout (local time) or RFC3339. It
+// returns the start and the end of the date, the next day for a date
+// or the next moment for a time.
+func (p *parser) parseDate(s string) (time.Time, time.Time, error) {
+	if t, err := time.ParseInLocation(DateLayout, s, time.Local); err == nil {
+		return t, t.AddDate(0, 0, 1), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, time.Time{}, p.errorf("invalid date %q, expected %s or RFC3339", s, DateLayout)
+	}
+	return t, t.Add(time.Nanosecond), nil
+}
@@ -0,0 +1,141 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package query
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseUnit(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"apples", "apples"},
+		{"app*", "app*"},
+		{`"apple pie"`, `"apple pie"`},
+		{"apples oranges", "apples AND oranges"},
+		{"apples AND oranges OR pears", "(apples AND oranges) OR pears"},
+		{"apples (oranges || pears)", "apples AND (oranges OR pears)"},
+		{"title:apples TAGS:food", "title:apples AND tag:food"},
+		{`book:"My Book" -tag:food`, `book:"My Book" AND NOT tag:food`},
+		{"tag:(food OR drinks) NOT body:pie*", "(tag:food OR tag:drinks) AND NOT body:pie*"},
+		{`"say \"hi\""`, `"say \"hi\""`},
+		{"10:30 well-known", `"10:30" AND well-known`},
+		{"id:603", "id:603"},
+	}
+
+	for _, test := range tests {
+		n, err := Parse(test.query)
+		if err != nil {
+			t.Fatalf("Parsing %q: %s", test.query, err)
+		}
+		if s := n.String(); s != test.expected {
+			t.Fatalf("Parsing %q, expected %q got %q", test.query, test.expected, s)
+		}
+
+		// The string of a node must parse to the same node
+		n2, err := Parse(n.String())
+		if err != nil {
+			t.Fatalf("Parsing %q: %s", n.String(), err)
+		}
+		if n2.String() != n.String() {
+			t.Fatalf("Expected %q got %q", n.String(), n2.String())
+		}
+	}
+}
+
+func TestParseDateRangeUnit(t *testing.T) {
+	day := time.Date(2017, 3, 25, 0, 0, 0, 0, time.Local)
+	next := day.AddDate(0, 0, 1)
+
+	tests := []struct {
+		query string
+		start time.Time
+		end   time.Time
+	}{
+		{"created:2017-03-25", day, next},
+		{"created:=2017-03-25", day, next},
+		{"created:>2017-03-25", next, time.Time{}},
+		{"created:>=2017-03-25", day, time.Time{}},
+		{"modified:<2017-03-25", time.Time{}, day},
+		{"modified:<=2017-03-25", time.Time{}, next},
+		{"created:2017-03-01..2017-03-25", day.AddDate(0, 0, -24), next},
+	}
+
+	for _, test := range tests {
+		n, err := Parse(test.query)
+		if err != nil {
+			t.Fatalf("Parsing %q: %s", test.query, err)
+		}
+
+		dr, ok := n.(*DateRange)
+		if !ok {
+			t.Fatalf("Parsing %q, expected a DateRange got %T", test.query, n)
+		}
+		if !dr.Start.Equal(test.start) || !dr.End.Equal(test.end) {
+			t.Fatalf("Parsing %q, expected %s - %s got %s - %s", test.query,
+				test.start, test.end, dr.Start, dr.End)
+		}
+
+		n2, err := Parse(n.String())
+		if err != nil {
+			t.Fatalf("Parsing %q: %s", n.String(), err)
+		}
+		if n2.String() != n.String() {
+			t.Fatalf("Expected %q got %q", n.String(), n2.String())
+		}
+	}
+
+	n, err := Parse("created:>2017-03-25T21:35:27-04:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2017, 3, 26, 1, 35, 27, 1, time.UTC)
+	if dr := n.(*DateRange); !dr.Start.Equal(expected) {
+		t.Fatalf("Expected %s got %s", expected, dr.Start)
+	}
+}
+
+func TestParseErrorsUnit(t *testing.T) {
+	if _, err := Parse("   "); err != ErrEmptyQuery {
+		t.Fatalf("Expected ErrEmptyQuery got %v", err)
+	}
+
+	queries := []string{
+		`"apple pie`,
+		"(apples",
+		"apples)",
+		"apples AND",
+		"NOT",
+		"title:",
+		"title:(body:apples)",
+		"created:yesterday",
+		`created:"2017-03-25"`,
+		"created:2017-03-25..2017-03-01",
+		"id:abc",
+	}
+	for _, q := range queries {
+		if _, err := Parse(q); err == nil {
+			t.Fatalf("Expected an error parsing %q", q)
+		} else if _, ok := err.(*SyntaxError); !ok {
+			t.Fatalf("Expected a SyntaxError parsing %q got %T", q, err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package query is qnote's search query language. Queries are parsed
+// into an AST that each index provider translates to its own queries,
+// so the same query works with every provider.
+//
+//	apples                      any of title, body or tags has the word
+//	app*                        prefix
+//	"apple pie"                 phrase
+//	title:apples tag:food       in the field
+//	tag:(food OR drinks)        field for a group
+//	mention:alice               notes mentioning @alice
+//	apples AND NOT tag:food     booleans, AND is implied between terms
+//	-tag:food                   same as NOT tag:food
+//	created:>2017-01-01         date ranges, >, >=, <, <= or a day
+//	created:2017-01-01..2017-01-31  both days included
+//	modified:<=2017-03-25T21:35:27-04:00
+//	id:603
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields that can be searched
+const (
+	FieldTitle    = "title"
+	FieldBody     = "body"
+	FieldTag      = "tag"
+	FieldMention  = "mention"
+	FieldBook     = "book"
+	FieldType     = "type"
+	FieldCreated  = "created"
+	FieldModified = "modified"
+	FieldID       = "id"
+)
+
+// Fields are all the fields that can be searched
+var Fields = []string{FieldTitle, FieldBody, FieldTag, FieldMention, FieldBook, FieldType, FieldCreated, FieldModified, FieldID}
+
+// DateLayout is the layout of dates in date ranges, dates are in local
+// time. RFC3339 can also be used for a date and time.
+const DateLayout = "2006-01-02"
+
+// Node is a node of a parsed query. String returns the node in
+// the query language, it can be parsed again.
+type Node interface {
+	String() string
+}
+
+// And matches notes that match all of Nodes
+type And struct {
+	Nodes []Node
+}
+
+func (n *And) String() string {
+	return joinNodes(n.Nodes, " AND ")
+}
+
+// Or matches notes that match any of Nodes
+type Or struct {
+	Nodes []Node
+}
+
+func (n *Or) String() string {
+	return joinNodes(n.Nodes, " OR ")
+}
+
+// Not matches notes that do not match Node
+type Not struct {
+	Node Node
+}
+
+func (n *Not) String() string {
+	return "NOT " + groupNode(n.Node)
+}
+
+// Term matches a word in Field, or title, body and tags when
+// Field is empty. Prefix matches words starting with Value.
+type Term struct {
+	Field  string
+	Value  string
+	Prefix bool
+}
+
+func (n *Term) String() string {
+	s := n.Value
+	if needsQuote(s) {
+		s = Quote(s)
+	}
+	if n.Prefix {
+		s += "*"
+	}
+	return fieldPrefix(n.Field) + s
+}
+
+// Phrase matches the words of Value in order in Field, or title,
+// body and tags when Field is empty
+type Phrase struct {
+	Field string
+	Value string
+}
+
+func (n *Phrase) String() string {
+	return fieldPrefix(n.Field) + Quote(n.Value)
+}
+
+// DateRange matches notes with Field (created or modified) from Start
+// up to but not including End. A zero Start or End is unbounded.
+type DateRange struct {
+	Field string
+	Start time.Time
+	End   time.Time
+}
+
+func (n *DateRange) String() string {
+	switch {
+	case n.Start.IsZero():
+		return fmt.Sprintf("%s:<%s", n.Field, n.End.Format(time.RFC3339Nano))
+	case n.End.IsZero():
+		return fmt.Sprintf("%s:>=%s", n.Field, n.Start.Format(time.RFC3339Nano))
+	default:
+		// Ranges include the end, so it is the moment before End
+		return fmt.Sprintf("%s:%s..%s", n.Field, n.Start.Format(time.RFC3339Nano),
+			n.End.Add(-time.Nanosecond).Format(time.RFC3339Nano))
+	}
+}
+
+// ID matches the note with the ID
+type ID struct {
+	ID int64
+}
+
+func (n *ID) String() string {
+	return fieldPrefix(FieldID) + strconv.FormatInt(n.ID, 10)
+}
+
+// Quote returns s as a quoted phrase
+func Quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+func fieldPrefix(field string) string {
+	if len(field) == 0 {
+		return ""
+	}
+	return field + ":"
+}
+
+func joinNodes(nodes []Node, sep string) string {
+	parts := make([]string, len(nodes))
+	for idx, n := range nodes {
+		parts[idx] = groupNode(n)
+	}
+	return strings.Join(parts, sep)
+}
+
+// groupNode puts the boolean nodes in parentheses
+func groupNode(n Node) string {
+	switch n.(type) {
+	case *And, *Or:
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
+
+// needsQuote returns true if s would not be read back as a single word
+func needsQuote(s string) bool {
+	if len(s) == 0 || s == "AND" || s == "OR" || s == "NOT" || s[0] == '-' {
+		return true
+	}
+	return strings.ContainsAny(s, " \t\r\n()\":*")
+}
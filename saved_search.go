@@ -0,0 +1,73 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package quicknote
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SavedSearchPrefix starts the name of a saved search where a Book
+// name is accepted, e.g. "-n @todo" for the saved search "todo"
+const SavedSearchPrefix = "@"
+
+// SavedSearch is a named query (see the query package). It can be
+// used as a virtual Book, its notes are the notes matching Query.
+type SavedSearch struct {
+	ID       int64
+	Created  time.Time
+	Modified time.Time
+
+	Name  string
+	Query string
+}
+
+// NewSavedSearch returns a new SavedSearch
+func NewSavedSearch() *SavedSearch {
+	return &SavedSearch{}
+}
+
+func (s *SavedSearch) String() string {
+	return fmt.Sprintf("<SavedSearch ID: %d Name: %s Query: %s>", s.ID, s.Name, s.Query)
+}
+
+type SavedSearches []*SavedSearch
+
+func (s SavedSearches) Len() int {
+	return len(s)
+}
+
+func (s SavedSearches) Less(i, j int) bool {
+	return s[i].Name < s[j].Name
+}
+
+func (s SavedSearches) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// IsSavedSearchName returns true if name refers to a
+// saved search instead of a Book
+func IsSavedSearchName(name string) bool {
+	return strings.HasPrefix(name, SavedSearchPrefix)
+}
+
+// SavedSearchName returns name without the SavedSearchPrefix
+func SavedSearchName(name string) string {
+	return strings.TrimPrefix(name, SavedSearchPrefix)
+}
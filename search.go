@@ -0,0 +1,166 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package quicknote
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Markers the index providers put around the
+// matched terms in SearchHit Fragments
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// SearchHit is a Note that matched a search query
+type SearchHit struct {
+	ID    int64
+	Score float64
+
+	// Fragments are the parts of each field (title, body, tags) that
+	// matched, with the matched terms between HighlightStart and HighlightEnd
+	Fragments map[string][]string
+
+	// Explanation is how the Score was computed, only set
+	// when SearchOptions.Explain is true
+	Explanation *Explanation
+}
+
+// Explanation is a part of the score of a SearchHit. Value is computed
+// from the Values of the Children as described by Message.
+type Explanation struct {
+	Value    float64
+	Message  string
+	Children []*Explanation
+}
+
+func (h *SearchHit) String() string {
+	return fmt.Sprintf("<SearchHit ID: %d Score: %f>", h.ID, h.Score)
+}
+
+// SearchResult is the result of a search query, Hits are in the
+// order the index provider returned them
+type SearchResult struct {
+	Hits  []*SearchHit
+	Total uint64
+
+	// Facets are in the order they were requested in SearchOptions
+	Facets []*Facet
+
+	// Next is the cursor of the next page, it is empty when the
+	// page has less hits than the limit
+	Next string
+}
+
+// IDs returns the Note IDs of the Hits in order
+func (r *SearchResult) IDs() []int64 {
+	ids := make([]int64, len(r.Hits))
+	for idx, h := range r.Hits {
+		ids[idx] = h.ID
+	}
+	return ids
+}
+
+// Hit returns the SearchHit for the Note id, nil if it is not in the results
+func (r *SearchResult) Hit(id int64) *SearchHit {
+	for _, h := range r.Hits {
+		if h.ID == id {
+			return h
+		}
+	}
+	return nil
+}
+
+// SortNotes returns notes in the same order as the Hits. Notes
+// that are not in the results are left out.
+func (r *SearchResult) SortNotes(notes Notes) Notes {
+	byID := make(map[int64]*Note, len(notes))
+	for _, n := range notes {
+		byID[n.ID] = n
+	}
+
+	sorted := make(Notes, 0, len(notes))
+	for _, h := range r.Hits {
+		if n, found := byID[h.ID]; found {
+			sorted = append(sorted, n)
+		}
+	}
+	return sorted
+}
+
+// Completion is a tag or note title that starts with the text
+// being typed. Count is the number of notes with a tag, ID is
+// the note of a title.
+type Completion struct {
+	Text  string `json:"text"`
+	Count int    `json:"count,omitempty"`
+	ID    int64  `json:"id,omitempty"`
+}
+
+// Fuzziness returns the number of typos (edits) a fuzzy search allows
+// in word. Like ElasticSearch's AUTO, short words must match exactly.
+func Fuzziness(word string) int {
+	switch l := utf8.RuneCountInString(word); {
+	case l < 3:
+		return 0
+	case l < 6:
+		return 1
+	}
+	return 2
+}
+
+// CombineSuggestions returns up to limit "did you mean" queries made by
+// replacing the words of a query with their corrections. corrections[i]
+// are the corrections of words[i] best first, words with no corrections
+// are kept. The first query uses the best correction of each word.
+func CombineSuggestions(words []string, corrections [][]string, limit int) []string {
+	most := 0
+	for _, c := range corrections {
+		if len(c) > most {
+			most = len(c)
+		}
+	}
+
+	original := strings.Join(words, " ")
+	seen := make(map[string]bool)
+	suggestions := make([]string, 0, limit)
+
+	for i := 0; i < most && len(suggestions) < limit; i++ {
+		parts := make([]string, len(words))
+		for idx, w := range words {
+			parts[idx] = w
+			if idx < len(corrections) && len(corrections[idx]) > 0 {
+				if i < len(corrections[idx]) {
+					parts[idx] = corrections[idx][i]
+				} else {
+					parts[idx] = corrections[idx][0]
+				}
+			}
+		}
+
+		s := strings.Join(parts, " ")
+		if s != original && !seen[s] {
+			seen[s] = true
+			suggestions = append(suggestions, s)
+		}
+	}
+	return suggestions
+}
@@ -0,0 +1,50 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package quicknote
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCombineSuggestionsUnit(t *testing.T) {
+	words := []string{"apels", "pie", "recipy"}
+	corrections := [][]string{{"apples", "apple"}, nil, {"recipe"}}
+
+	expected := []string{"apples pie recipe", "apple pie recipe"}
+	if s := CombineSuggestions(words, corrections, 5); fmt.Sprint(s) != fmt.Sprint(expected) {
+		t.Fatalf("Expected %v, got %v", expected, s)
+	}
+
+	if s := CombineSuggestions(words, corrections, 1); len(s) != 1 {
+		t.Fatalf("Expected 1 suggestion, got %d", len(s))
+	}
+
+	if s := CombineSuggestions(words, make([][]string, len(words)), 5); len(s) != 0 {
+		t.Fatalf("Expected no suggestions, got %v", s)
+	}
+}
+
+func TestFuzzinessUnit(t *testing.T) {
+	tests := map[string]int{"pi": 0, "pie": 1, "apple": 1, "apples": 2, "äpfel": 1}
+	for word, expected := range tests {
+		if f := Fuzziness(word); f != expected {
+			t.Fatalf("%s: expected %d, got %d", word, expected, f)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package quicknote
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Fields search results can be sorted by
+const (
+	SortRelevance = "relevance"
+	SortCreated   = "created"
+	SortModified  = "modified"
+	SortTitle     = "title"
+	SortID        = "id"
+)
+
+// SortFields are all the fields search results can be sorted by
+var SortFields = []string{SortRelevance, SortCreated, SortModified, SortTitle, SortID}
+
+// ErrInvalidCursor is returned when a cursor was not returned
+// by a search with the same sort order
+var ErrInvalidCursor = errors.New("invalid cursor, it must be from a search with the same sort order")
+
+// SortField orders search results by Field, ascending unless Desc
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+func (s *SortField) String() string {
+	if s.Desc {
+		return s.Field + ":desc"
+	}
+	return s.Field + ":asc"
+}
+
+// ParseSort parses comma separated sort fields, each field can end with
+// :asc or :desc, e.g. "created:desc,title". Fields without an order
+// use order, which must be "asc" or "desc".
+func ParseSort(s, order string) ([]*SortField, error) {
+	if order != "asc" && order != "desc" {
+		return nil, fmt.Errorf("invalid order %q, must be asc or desc", order)
+	}
+
+	sort := make([]*SortField, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if len(part) == 0 {
+			continue
+		}
+
+		field, fieldOrder := part, order
+		if i := strings.LastIndex(part, ":"); i >= 0 {
+			field, fieldOrder = part[:i], part[i+1:]
+			if fieldOrder != "asc" && fieldOrder != "desc" {
+				return nil, fmt.Errorf("invalid order %q for %s, must be asc or desc", fieldOrder, field)
+			}
+		}
+		if field == "score" {
+			field = SortRelevance
+		}
+
+		if !isSortField(field) {
+			return nil, fmt.Errorf("can not sort by %q, must be one of %s", field,
+				strings.Join(SortFields, ", "))
+		}
+		sort = append(sort, &SortField{Field: field, Desc: fieldOrder == "desc"})
+	}
+	return sort, nil
+}
+
+func isSortField(field string) bool {
+	for _, f := range SortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// SortWithTiebreaker returns sort ending with the note ID, so notes with
+// the same values are always in the same order. Without sort fields the
+// most relevant notes are first.
+func SortWithTiebreaker(sort []*SortField) []*SortField {
+	if len(sort) == 0 {
+		sort = []*SortField{{Field: SortRelevance, Desc: true}}
+	}
+	for _, s := range sort {
+		if s.Field == SortID {
+			return sort
+		}
+	}
+	return append(sort[:len(sort):len(sort)], &SortField{Field: SortID})
+}
+
+// EncodeCursor returns the cursor for the sort values of the
+// last hit of a page, see SearchOptions.After
+func EncodeCursor(values interface{}) (string, error) {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor reads the sort values of cursor into values
+func DecodeCursor(cursor string, values interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err = json.Unmarshal(b, values); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package quicknote
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseSortUnit(t *testing.T) {
+	tests := map[string]string{
+		"":                     "[]",
+		"created":              "[created:desc]",
+		"created:asc, Title":   "[created:asc title:desc]",
+		"score,modified:desc":  "[relevance:desc modified:desc]",
+		"relevance:asc,id:asc": "[relevance:asc id:asc]",
+	}
+	for s, expected := range tests {
+		sort, err := ParseSort(s, "desc")
+		if err != nil {
+			t.Fatalf("%s: %s", s, err)
+		} else if fmt.Sprint(sort) != expected {
+			t.Fatalf("%s: expected %s, got %v", s, expected, sort)
+		}
+	}
+
+	for _, s := range []string{"book", "created:up", "title:"} {
+		if _, err := ParseSort(s, "asc"); err == nil {
+			t.Fatalf("%s: expected an error", s)
+		}
+	}
+	if _, err := ParseSort("created", "up"); err == nil {
+		t.Fatal("Expected an error for the order up")
+	}
+}
+
+func TestSortWithTiebreakerUnit(t *testing.T) {
+	if s := SortWithTiebreaker(nil); fmt.Sprint(s) != "[relevance:desc id:asc]" {
+		t.Fatalf("Expected [relevance:desc id:asc], got %v", s)
+	}
+
+	sort := []*SortField{{Field: SortTitle}}
+	if s := SortWithTiebreaker(sort); fmt.Sprint(s) != "[title:asc id:asc]" {
+		t.Fatalf("Expected [title:asc id:asc], got %v", s)
+	} else if len(sort) != 1 {
+		t.Fatal("Expected sort to be unchanged")
+	}
+
+	sort = []*SortField{{Field: SortID, Desc: true}, {Field: SortTitle}}
+	if s := SortWithTiebreaker(sort); fmt.Sprint(s) != "[id:desc title:asc]" {
+		t.Fatalf("Expected [id:desc title:asc], got %v", s)
+	}
+}
+
+func TestCursorUnit(t *testing.T) {
+	cursor, err := EncodeCursor([]interface{}{1.5, "apples", 603})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values []interface{}
+	if err = DecodeCursor(cursor, &values); err != nil {
+		t.Fatal(err)
+	} else if fmt.Sprint(values) != "[1.5 apples 603]" {
+		t.Fatalf("Expected [1.5 apples 603], got %v", values)
+	}
+
+	if err = DecodeCursor("not a cursor!", &values); err != ErrInvalidCursor {
+		t.Fatalf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
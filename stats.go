@@ -0,0 +1,91 @@
+// Quicknote stores and searches tens of thousands of short notes.
+//
+// Copyright (C) 2017  Andrew Miller <amiller@amilx.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package quicknote
+
+import (
+	"fmt"
+	"time"
+)
+
+// CountStat is the number of Notes for the given name
+// (Book name, Tag name, Note type, ...)
+type CountStat struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+func (c *CountStat) String() string {
+	return fmt.Sprintf("<CountStat Name: %s Count: %d>", c.Name, c.Count)
+}
+
+// CountStats list of CountStat
+type CountStats []*CountStat
+
+// TagPairStat is the number of Notes tagged with both tags
+type TagPairStat struct {
+	Tag1  string `json:"tag1"`
+	Tag2  string `json:"tag2"`
+	Count int64  `json:"count"`
+}
+
+func (t *TagPairStat) String() string {
+	return fmt.Sprintf("<TagPairStat Tag1: %s Tag2: %s Count: %d>", t.Tag1, t.Tag2, t.Count)
+}
+
+// TagPairStats list of TagPairStat
+type TagPairStats []*TagPairStat
+
+// WeekStat is the number of Notes created and modified in the
+// week starting on Week (the Monday formatted as YYYY-MM-DD)
+type WeekStat struct {
+	Week     string `json:"week"`
+	Created  int64  `json:"created"`
+	Modified int64  `json:"modified"`
+}
+
+func (w *WeekStat) String() string {
+	return fmt.Sprintf("<WeekStat Week: %s Created: %d Modified: %d>", w.Week, w.Created, w.Modified)
+}
+
+// WeekStats list of WeekStat
+type WeekStats []*WeekStat
+
+// LengthStat is the average length in characters
+// of the Notes' Title and Body
+type LengthStat struct {
+	Notes    int64   `json:"notes"`
+	AvgTitle float64 `json:"avg_title_length"`
+	AvgBody  float64 `json:"avg_body_length"`
+}
+
+func (l *LengthStat) String() string {
+	return fmt.Sprintf("<LengthStat Notes: %d AvgTitle: %.1f AvgBody: %.1f>", l.Notes, l.AvgTitle, l.AvgBody)
+}
+
+// Stats is a summary of the Notes in one or all Books
+type Stats struct {
+	Book        string       `json:"book,omitempty"`
+	Books       CountStats   `json:"books"`
+	Types       CountStats   `json:"types"`
+	Tags        CountStats   `json:"top_tags"`
+	TagPairs    TagPairStats `json:"tag_pairs"`
+	Weeks       WeekStats    `json:"weeks"`
+	Length      *LengthStat  `json:"length"`
+	StaleBefore time.Time    `json:"stale_before"`
+	Stale       int64        `json:"stale"`
+}